@@ -1,7 +1,6 @@
 package main
 
 import (
-	"context"
 	"fmt"
 	"log"
 	"os"
@@ -9,7 +8,6 @@ import (
 	"github.com/qingbingwei/latex_ppt_by_claude/backend/internal/api"
 	"github.com/qingbingwei/latex_ppt_by_claude/backend/internal/config"
 	"github.com/qingbingwei/latex_ppt_by_claude/backend/internal/model"
-	"github.com/qingbingwei/latex_ppt_by_claude/backend/pkg/vectordb"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
@@ -31,17 +29,19 @@ func main() {
 		&model.Chunk{},
 		&model.PPTRecord{},
 		&model.PPTKnowledgeRef{},
+		&model.PPTRepairAttempt{},
+		&model.PPTRevision{},
+		&model.Team{},
+		&model.TeamMember{},
+		&model.Template{},
+		&model.TemplateVersion{},
 	); err != nil {
 		log.Fatalf("Failed to migrate database: %v", err)
 	}
-
-	// Setup Milvus collection
-	milvusClient, err := vectordb.NewMilvusClient(cfg.Milvus.Host, cfg.Milvus.Port)
-	if err != nil {
-		log.Printf("Warning: Failed to connect to Milvus: %v", err)
-	} else {
-		if err := milvusClient.CreateCollection(context.Background()); err != nil {
-			log.Printf("Warning: Failed to create Milvus collection: %v", err)
+	// 模板名称改为按所有者唯一，删除旧版本创建的全局唯一索引
+	if db.Migrator().HasIndex(&model.Template{}, "idx_templates_name") {
+		if err := db.Migrator().DropIndex(&model.Template{}, "idx_templates_name"); err != nil {
+			log.Fatalf("Failed to migrate database: %v", err)
 		}
 	}
 
@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"path/filepath"
@@ -11,6 +12,7 @@ import (
 	"github.com/qingbingwei/latex_ppt_by_claude/backend/internal/api/middleware"
 	"github.com/qingbingwei/latex_ppt_by_claude/backend/internal/model"
 	"github.com/qingbingwei/latex_ppt_by_claude/backend/internal/service"
+	"github.com/qingbingwei/latex_ppt_by_claude/backend/pkg/vectordb"
 )
 
 type KnowledgeHandler struct {
@@ -107,11 +109,21 @@ func (h *KnowledgeHandler) Get(c *gin.Context) {
 		return
 	}
 
-	doc, err := h.knowledgeService.GetDocument(uint(id))
-	if err != nil {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	doc, err := h.knowledgeService.GetDocument(userID, uint(id))
+	if errors.Is(err, service.ErrDocumentNotFound) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Document not found"})
 		return
 	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get document"})
+		return
+	}
 
 	c.JSON(http.StatusOK, doc)
 }
@@ -123,7 +135,22 @@ func (h *KnowledgeHandler) Delete(c *gin.Context) {
 		return
 	}
 
-	if err := h.knowledgeService.DeleteDocument(uint(id)); err != nil {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	err = h.knowledgeService.DeleteDocument(c.Request.Context(), userID, uint(id))
+	if errors.Is(err, service.ErrDocumentNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Document not found"})
+		return
+	}
+	if errors.Is(err, vectordb.ErrUnavailable) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Vector store is unavailable, please try again later"})
+		return
+	}
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete document"})
 		return
 	}
@@ -132,8 +159,9 @@ func (h *KnowledgeHandler) Delete(c *gin.Context) {
 }
 
 type SearchRequest struct {
-	Query string `json:"query" binding:"required"`
-	TopK  int    `json:"top_k"`
+	Query       string `json:"query" binding:"required"`
+	TopK        int    `json:"top_k"`
+	DocumentIDs []uint `json:"document_ids"` // 为空时检索当前用户的全部文档
 }
 
 func (h *KnowledgeHandler) Search(c *gin.Context) {
@@ -143,11 +171,17 @@ func (h *KnowledgeHandler) Search(c *gin.Context) {
 		return
 	}
 
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
 	if req.TopK == 0 {
 		req.TopK = 5
 	}
 
-	results, err := h.knowledgeService.SearchSimilarChunks(c.Request.Context(), req.Query, req.TopK)
+	results, err := h.knowledgeService.SearchSimilarChunks(c.Request.Context(), userID, req.Query, req.TopK, req.DocumentIDs)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Search failed"})
 		return
@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/qingbingwei/latex_ppt_by_claude/backend/internal/model"
+	"github.com/qingbingwei/latex_ppt_by_claude/backend/pkg/vectordb"
+)
+
+func TestDocumentOfAnotherUserIsNotFound(t *testing.T) {
+	env := newTestEnv(t)
+	path := "/knowledge/" + strconv.Itoa(int(env.doc.ID))
+
+	for _, method := range []string{http.MethodGet, http.MethodDelete} {
+		if w := env.do(intruderID, method, path, ""); w.Code != http.StatusNotFound {
+			t.Errorf("%s %s as another user: got %d %s, want 404", method, path, w.Code, w.Body)
+		}
+	}
+	if err := env.db.First(&model.Document{}, env.doc.ID).Error; err != nil {
+		t.Fatalf("document was deleted by another user: %v", err)
+	}
+
+	if w := env.do(ownerID, http.MethodDelete, path, ""); w.Code != http.StatusOK {
+		t.Fatalf("DELETE %s as owner: got %d %s, want 200", path, w.Code, w.Body)
+	}
+	if w := env.do(ownerID, http.MethodGet, path, ""); w.Code != http.StatusNotFound {
+		t.Errorf("GET %s after delete: got %d, want 404", path, w.Code)
+	}
+}
+
+func TestDocumentDeleteNeedsVectorStore(t *testing.T) {
+	env := newTestEnvWithStore(t, vectordb.Unavailable(errors.New("test")))
+	path := "/knowledge/" + strconv.Itoa(int(env.doc.ID))
+
+	if w := env.do(ownerID, http.MethodDelete, path, ""); w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("DELETE %s without a vector store: got %d %s, want 503", path, w.Code, w.Body)
+	}
+	// 向量没有删除时必须保留文档，之后可以重试
+	if err := env.db.First(&model.Document{}, env.doc.ID).Error; err != nil {
+		t.Fatalf("document was deleted although its vectors were kept: %v", err)
+	}
+}
+
+func TestDocumentLookupErrorIsServerError(t *testing.T) {
+	env := newTestEnv(t)
+	sqlDB, err := env.db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.Close()
+
+	path := "/knowledge/" + strconv.Itoa(int(env.doc.ID))
+	for _, method := range []string{http.MethodGet, http.MethodDelete} {
+		if w := env.do(ownerID, method, path, ""); w.Code != http.StatusInternalServerError {
+			t.Errorf("%s %s with a closed database: got %d %s, want 500", method, path, w.Code, w.Body)
+		}
+	}
+}
@@ -1,31 +1,82 @@
 package handler
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/qingbingwei/latex_ppt_by_claude/backend/internal/api/middleware"
+	"github.com/qingbingwei/latex_ppt_by_claude/backend/internal/model"
 	"github.com/qingbingwei/latex_ppt_by_claude/backend/internal/service"
+	"github.com/qingbingwei/latex_ppt_by_claude/backend/pkg/ai"
+	"github.com/qingbingwei/latex_ppt_by_claude/backend/pkg/latex"
 )
 
 type PPTHandler struct {
-	pptService *service.PPTService
+	pptService    *service.PPTService
+	jobService    *service.JobService
+	slideService  *service.SlideService
+	exportService *service.ExportService
 }
 
-func NewPPTHandler(pptService *service.PPTService) *PPTHandler {
+func NewPPTHandler(
+	pptService *service.PPTService,
+	jobService *service.JobService,
+	slideService *service.SlideService,
+	exportService *service.ExportService,
+) *PPTHandler {
 	return &PPTHandler{
-		pptService: pptService,
+		pptService:    pptService,
+		jobService:    jobService,
+		slideService:  slideService,
+		exportService: exportService,
 	}
 }
 
 type GenerateRequest struct {
-	Title       string `json:"title" binding:"required"`
-	Prompt      string `json:"prompt" binding:"required"`
-	Template    string `json:"template"`
+	Title    string `json:"title" binding:"required"`
+	Prompt   string `json:"prompt" binding:"required"`
+	Template string `json:"template"`
+	// Mode 为 "template" 时模型只生成 frame，标题等元信息填入模板
+	Mode        string `json:"mode"`
+	Subtitle    string `json:"subtitle"`
+	Author      string `json:"author"`
+	Institute   string `json:"institute"`
+	Engine      string `json:"engine"`
 	DocumentIDs []uint `json:"document_ids"`
-	UseOpenAI   bool   `json:"use_openai"`
+	Provider    string `json:"provider"`
+	Model       string `json:"model"`
+	// AutoFix 开启编译失败后的 AI 自动修复，MaxFixAttempts 为 0 时使用服务端上限
+	AutoFix        bool `json:"auto_fix"`
+	MaxFixAttempts int  `json:"max_fix_attempts"`
+	// Deprecated: 使用 Provider 指定 "openai"
+	UseOpenAI bool `json:"use_openai"`
+}
+
+func (r GenerateRequest) params() service.GenerateParams {
+	sel := service.ModelSelection{Provider: r.Provider, Model: r.Model}
+	if sel.Provider == "" && r.UseOpenAI {
+		sel.Provider = "openai"
+	}
+	return service.GenerateParams{
+		Title:       r.Title,
+		Prompt:      r.Prompt,
+		Template:    r.Template,
+		Mode:        r.Mode,
+		Subtitle:    r.Subtitle,
+		Author:      r.Author,
+		Institute:   r.Institute,
+		Engine:      r.Engine,
+		DocumentIDs: r.DocumentIDs,
+		Selection:   sel,
+
+		AutoFix:        r.AutoFix,
+		MaxFixAttempts: r.MaxFixAttempts,
+	}
 }
 
 func (h *PPTHandler) Generate(c *gin.Context) {
@@ -44,6 +95,21 @@ func (h *PPTHandler) Generate(c *gin.Context) {
 	if req.Template == "" {
 		req.Template = "default"
 	}
+	if req.Mode == "" {
+		req.Mode = service.GenerationModeFull
+	}
+	if req.Mode != service.GenerationModeFull && req.Mode != service.GenerationModeTemplate {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "mode must be \"full\" or \"template\""})
+		return
+	}
+	if err := h.pptService.ValidateEngine(req.Engine); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.pptService.ValidateTemplate(userID, req.Template); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
 	// Check if SSE stream is requested
 	if c.GetHeader("Accept") == "text/event-stream" {
@@ -51,29 +117,160 @@ func (h *PPTHandler) Generate(c *gin.Context) {
 		return
 	}
 
-	// Regular synchronous generation
-	ppt, err := h.pptService.GeneratePPT(
-		c.Request.Context(),
-		userID,
-		req.Title,
-		req.Prompt,
-		req.Template,
-		req.DocumentIDs,
-		req.UseOpenAI,
-	)
+	// Enqueue generation job, clients poll GET /ppt/:id for status
+	ppt, err := h.jobService.Submit(userID, req.params())
+	if errors.Is(err, service.ErrQueueFull) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, ppt)
+}
+
+// CreateOutline 生成大纲并创建待确认的 PPT 记录，请求体同 Generate，mode 被忽略
+func (h *PPTHandler) CreateOutline(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req GenerateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.Template == "" {
+		req.Template = "default"
+	}
+	if err := h.pptService.ValidateEngine(req.Engine); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.pptService.ValidateTemplate(userID, req.Template); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
+	ppt, err := h.pptService.CreateOutline(c.Request.Context(), userID, req.params())
+	if errors.Is(err, service.ErrInvalidOutline) {
+		c.JSON(http.StatusBadGateway, gin.H{"error": fmt.Sprintf("Model returned an invalid outline: %v", err)})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	c.JSON(http.StatusCreated, ppt)
+}
+
+// GetOutline 返回 PPT 的大纲
+func (h *PPTHandler) GetOutline(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid PPT ID"})
+		return
+	}
+
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	ppt, err := h.pptService.GetPPT(userID, uint(id))
+	if err != nil {
+		respondPPTError(c, err)
+		return
+	}
+	if ppt.Outline == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": service.ErrOutlineNotAvailable.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, ppt.Outline)
+}
+
+// UpdateOutline 替换 PPT 的大纲，之后通过 GenerateFromOutline 重新生成
+func (h *PPTHandler) UpdateOutline(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid PPT ID"})
+		return
+	}
+
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var outline model.Outline
+	if err := c.ShouldBindJSON(&outline); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ppt, err := h.pptService.UpdateOutline(userID, uint(id), &outline)
+	if err != nil {
+		respondOutlineError(c, err)
+		return
+	}
+
 	c.JSON(http.StatusOK, ppt)
 }
 
+// GenerateFromOutline 按大纲把生成任务入队，客户端通过 GET /ppt/:id 轮询状态
+func (h *PPTHandler) GenerateFromOutline(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid PPT ID"})
+		return
+	}
+
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	ppt, err := h.jobService.SubmitOutline(userID, uint(id))
+	if errors.Is(err, service.ErrQueueFull) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		respondOutlineError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, ppt)
+}
+
+func respondOutlineError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidOutline):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrPPTNotFound), errors.Is(err, service.ErrOutlineNotAvailable):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrOutlineLocked):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
 func (h *PPTHandler) generateStream(c *gin.Context, userID uint, req GenerateRequest) {
 	c.Header("Content-Type", "text/event-stream")
 	c.Header("Cache-Control", "no-cache")
 	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
 
 	flusher, ok := c.Writer.(http.Flusher)
 	if !ok {
@@ -81,74 +278,174 @@ func (h *PPTHandler) generateStream(c *gin.Context, userID uint, req GenerateReq
 		return
 	}
 
-	// Send initial message
-	fmt.Fprintf(c.Writer, "data: {\"status\":\"generating\"}\n\n")
-	flusher.Flush()
-
-	// Generate PPT (simplified for now, should use streaming AI service)
-	ppt, err := h.pptService.GeneratePPT(
-		c.Request.Context(),
-		userID,
-		req.Title,
-		req.Prompt,
-		req.Template,
-		req.DocumentIDs,
-		req.UseOpenAI,
-	)
+	events := make(chan service.StreamEvent)
+	go h.pptService.StreamGeneratePPT(c.Request.Context(), userID, req.params(), events)
 
-	if err != nil {
-		fmt.Fprintf(c.Writer, "data: {\"status\":\"error\",\"error\":\"%s\"}\n\n", err.Error())
-	} else {
-		fmt.Fprintf(c.Writer, "data: {\"status\":\"completed\",\"ppt_id\":%d}\n\n", ppt.ID)
+	// 客户端断开后继续消费事件，确保生成协程能够退出
+	for event := range events {
+		data, err := json.Marshal(event)
+		if err != nil {
+			continue
+		}
+		fmt.Fprintf(c.Writer, "event: %s\ndata: %s\n\n", event.Type, data)
+		flusher.Flush()
 	}
-	flusher.Flush()
 }
 
 func (h *PPTHandler) GetTemplates(c *gin.Context) {
-	templates := h.pptService.GetTemplates()
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	templates := h.pptService.GetTemplates(userID)
 	c.JSON(http.StatusOK, gin.H{"templates": templates})
 }
 
+func (h *PPTHandler) GetProviders(c *gin.Context) {
+	providers := h.pptService.GetProviders()
+	c.JSON(http.StatusOK, gin.H{"providers": providers})
+}
+
+func (h *PPTHandler) GetEngines(c *gin.Context) {
+	engines := h.pptService.GetEngines()
+	c.JSON(http.StatusOK, gin.H{"engines": engines})
+}
+
+// maxSourceBytes 限制提交 LaTeX/Markdown 源码的请求体大小。源码会保存为版本并参与 diff，
+// 过大的源码会让编译和 diff 占用过多资源
+const maxSourceBytes = 1 << 20
+
+// bindSource 解析提交源码的请求体，超过 maxSourceBytes 返回 413，失败时已写入响应
+func bindSource(c *gin.Context, req interface{}) bool {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSourceBytes)
+	err := c.ShouldBindJSON(req)
+	if err == nil {
+		return true
+	}
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("Request body exceeds %d bytes", maxSourceBytes)})
+		return false
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	return false
+}
+
 type CompileRequest struct {
 	LatexContent string `json:"latex_content" binding:"required"`
+	Engine       string `json:"engine"`
 }
 
+// Compile 重新编译用户编辑后的源码，成功后替换该 PPT 的内容并记录为新版本
 func (h *PPTHandler) Compile(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid PPT ID"})
+		return
+	}
+
 	var req CompileRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if !bindSource(c, &req) {
+		return
+	}
+
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	if err := h.pptService.ValidateEngine(req.Engine); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
+	ppt, err := h.pptService.CompileLaTeX(c.Request.Context(), uint(id), userID, req.LatexContent, req.Engine)
+	if err != nil {
+		respondCompileError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, ppt)
+}
+
+type ScratchCompileRequest struct {
+	LatexContent string `json:"latex_content" binding:"required"`
+	Engine       string `json:"engine"`
+	Template     string `json:"template"` // 可选，提供图片等资源文件和偏好引擎
+}
+
+// CompileScratch 编译任意 LaTeX 源码并直接返回 PDF，不创建 PPT 记录也不调用模型
+func (h *PPTHandler) CompileScratch(c *gin.Context) {
+	var req ScratchCompileRequest
+	if !bindSource(c, &req) {
+		return
+	}
+
 	userID, exists := middleware.GetUserID(c)
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
 	}
 
-	// For simplicity, create a temporary PPT record
-	ppt, err := h.pptService.GeneratePPT(
-		c.Request.Context(),
-		userID,
-		"Manual Compile",
-		"Manual compilation",
-		"default",
-		nil,
-		false,
-	)
+	if err := h.pptService.ValidateEngine(req.Engine); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Template != "" {
+		if err := h.pptService.ValidateTemplate(userID, req.Template); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
 
+	result, pdf, err := h.pptService.CompileScratch(c.Request.Context(), userID, req.LatexContent, req.Template, req.Engine)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondCompileError(c, err)
+		return
+	}
+
+	c.Header("X-LaTeX-Engine", result.Engine)
+	c.Data(http.StatusOK, "application/pdf", pdf)
+}
+
+// respondCompileError 对编译错误返回 422 和结构化诊断，引擎不可用返回 400，
+// PPT 不存在或不属于当前用户返回 404，仍在生成中返回 409，其他错误返回 500
+func respondCompileError(c *gin.Context, err error) {
+	if errors.Is(err, latex.ErrUnknownEngine) || errors.Is(err, latex.ErrEngineUnavailable) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, service.ErrPPTNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, service.ErrPPTNotReady) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Compile the provided LaTeX
-	if err := h.pptService.CompileLaTeX(ppt.ID, req.LatexContent); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Compilation failed: %v", err)})
+	var compileErr *latex.CompileError
+	if errors.As(err, &compileErr) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":       fmt.Sprintf("Compilation failed: %v", err),
+			"diagnostics": compileErr.Diagnostics,
+			"passes":      compileErr.Passes,
+		})
 		return
 	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Compilation failed: %v", err)})
+}
 
-	c.JSON(http.StatusOK, ppt)
+// respondPPTError 对不存在或不属于当前用户的 PPT 返回 404，查询出错返回 500
+func respondPPTError(c *gin.Context, err error) {
+	if errors.Is(err, service.ErrPPTNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "PPT not found"})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get PPT"})
 }
 
 func (h *PPTHandler) GetHistory(c *gin.Context) {
@@ -174,9 +471,15 @@ func (h *PPTHandler) Get(c *gin.Context) {
 		return
 	}
 
-	ppt, err := h.pptService.GetPPT(uint(id))
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	ppt, err := h.pptService.GetPPT(userID, uint(id))
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "PPT not found"})
+		respondPPTError(c, err)
 		return
 	}
 
@@ -190,9 +493,15 @@ func (h *PPTHandler) Download(c *gin.Context) {
 		return
 	}
 
-	ppt, err := h.pptService.GetPPT(uint(id))
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	ppt, err := h.pptService.GetPPT(userID, uint(id))
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "PPT not found"})
+		respondPPTError(c, err)
 		return
 	}
 
@@ -204,14 +513,606 @@ func (h *PPTHandler) Download(c *gin.Context) {
 	c.File(ppt.PDFPath)
 }
 
-func (h *PPTHandler) Delete(c *gin.Context) {
+// Export 把 PPT 转换为 format 指定的格式并作为附件下载
+func (h *PPTHandler) Export(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid PPT ID"})
 		return
 	}
 
-	if err := h.pptService.DeletePPT(uint(id)); err != nil {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	ppt, err := h.pptService.GetPPT(userID, uint(id))
+	if err != nil {
+		respondPPTError(c, err)
+		return
+	}
+
+	file, err := h.exportService.Export(c.Request.Context(), ppt, c.Query("format"))
+	switch {
+	case errors.Is(err, service.ErrUnsupportedFormat):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, service.ErrLaTeXNotAvailable):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export PPT"})
+		return
+	}
+
+	c.Header("Content-Disposition", file.ContentDisposition())
+	c.Data(http.StatusOK, file.ContentType, file.Data)
+}
+
+type MarkdownRequest struct {
+	Markdown  string `json:"markdown" binding:"required"`
+	Template  string `json:"template"`
+	Engine    string `json:"engine"`
+	Title     string `json:"title"`
+	Subtitle  string `json:"subtitle"`
+	Author    string `json:"author"`
+	Institute string `json:"institute"`
+}
+
+func (r MarkdownRequest) params() service.MarkdownParams {
+	if r.Template == "" {
+		r.Template = "default"
+	}
+	return service.MarkdownParams{
+		Markdown:  r.Markdown,
+		Template:  r.Template,
+		Engine:    r.Engine,
+		Title:     r.Title,
+		Subtitle:  r.Subtitle,
+		Author:    r.Author,
+		Institute: r.Institute,
+	}
+}
+
+// ImportMarkdown 把 Markdown 幻灯片套入模板并编译为 PPT，不经过模型
+func (h *PPTHandler) ImportMarkdown(c *gin.Context) {
+	var req MarkdownRequest
+	if !bindSource(c, &req) {
+		return
+	}
+
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	if err := h.pptService.ValidateEngine(req.Engine); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ppt, err := h.pptService.ImportMarkdown(c.Request.Context(), userID, req.params())
+	if errors.Is(err, service.ErrMarkdownTitleRequired) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if ppt == nil {
+		respondTemplateError(c, err)
+		return
+	}
+	if err != nil {
+		// 记录已保存，返回其 ID 以便在编辑器中修改
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":       ppt.ErrorMessage,
+			"ppt":         ppt,
+			"diagnostics": ppt.Diagnostics,
+			"passes":      ppt.Passes,
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, ppt)
+}
+
+// ConvertMarkdown 只把 Markdown 幻灯片转换为 LaTeX，不创建记录也不编译
+func (h *PPTHandler) ConvertMarkdown(c *gin.Context) {
+	var req MarkdownRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	latexContent, data, _, err := h.pptService.ConvertMarkdown(userID, req.params())
+	if err != nil {
+		respondTemplateError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"latex":     latexContent,
+		"title":     data.Title,
+		"subtitle":  data.Subtitle,
+		"author":    data.Author,
+		"institute": data.Institute,
+	})
+}
+
+// GetDeck 返回 PPT 的结构化文档（章节、frame 和内容块）
+func (h *PPTHandler) GetDeck(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid PPT ID"})
+		return
+	}
+
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	ppt, err := h.pptService.GetPPT(userID, uint(id))
+	if err != nil {
+		respondPPTError(c, err)
+		return
+	}
+
+	deck, err := h.pptService.GetDeck(ppt)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, deck)
+}
+
+type UpdateDeckRequest struct {
+	Deck   *latex.Deck `json:"deck" binding:"required"`
+	Engine string      `json:"engine"`
+}
+
+// UpdateDeck 由编辑后的结构化文档重新生成 LaTeX 并编译
+func (h *PPTHandler) UpdateDeck(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid PPT ID"})
+		return
+	}
+
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req UpdateDeckRequest
+	if !bindSource(c, &req) {
+		return
+	}
+
+	if err := h.pptService.ValidateEngine(req.Engine); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ppt, err := h.pptService.UpdateDeck(c.Request.Context(), uint(id), userID, req.Deck, req.Engine)
+	if err != nil {
+		respondCompileError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, ppt)
+}
+
+type SlideInfo struct {
+	Page         int    `json:"page"`
+	URL          string `json:"url"`
+	ThumbnailURL string `json:"thumbnail_url"`
+}
+
+// GetSlides 列出 PPT 的逐页 PNG，首次访问时渲染
+func (h *PPTHandler) GetSlides(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid PPT ID"})
+		return
+	}
+
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	ppt, err := h.pptService.GetPPT(userID, uint(id))
+	if err != nil {
+		respondPPTError(c, err)
+		return
+	}
+
+	pages, err := h.slideService.Slides(c.Request.Context(), ppt)
+	if err != nil {
+		respondSlideError(c, err)
+		return
+	}
+
+	base := strings.TrimSuffix(c.Request.URL.Path, "/")
+	slides := make([]SlideInfo, len(pages))
+	for i := range pages {
+		url := fmt.Sprintf("%s/%d.png", base, i+1)
+		slides[i] = SlideInfo{Page: i + 1, URL: url, ThumbnailURL: url + "?thumbnail=true"}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"count":  len(slides),
+		"slides": slides,
+	})
+}
+
+// GetSlide 返回第 n 页的 PNG，路径形如 /ppt/:id/slides/3.png，thumbnail=true 时返回缩略图
+func (h *PPTHandler) GetSlide(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid PPT ID"})
+		return
+	}
+	n, err := strconv.Atoi(strings.TrimSuffix(c.Param("page"), ".png"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid slide number"})
+		return
+	}
+
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	ppt, err := h.pptService.GetPPT(userID, uint(id))
+	if err != nil {
+		respondPPTError(c, err)
+		return
+	}
+
+	path, err := h.slideService.Slide(c.Request.Context(), ppt, n, c.Query("thumbnail") == "true")
+	if err != nil {
+		respondSlideError(c, err)
+		return
+	}
+
+	c.Header("Cache-Control", "private, max-age=3600")
+	c.File(path)
+}
+
+type RegenerateFrameRequest struct {
+	Instruction string `json:"instruction" binding:"required"`
+	Provider    string `json:"provider"`
+	Model       string `json:"model"`
+	Engine      string `json:"engine"`
+}
+
+// RegenerateFrame 按指令重写第 n 个 frame 并重新编译，其余 frame 不变
+func (h *PPTHandler) RegenerateFrame(c *gin.Context) {
+	id, n, ok := frameParams(c)
+	if !ok {
+		return
+	}
+
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req RegenerateFrameRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.pptService.ValidateEngine(req.Engine); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	sel := service.ModelSelection{Provider: req.Provider, Model: req.Model}
+	ppt, err := h.pptService.RegenerateFrame(c.Request.Context(), id, userID, n, req.Instruction, sel, req.Engine)
+	switch {
+	case errors.Is(err, service.ErrInvalidFrame):
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, ai.ErrProviderNotFound), errors.Is(err, ai.ErrNoProvider):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		respondFrameError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, ppt)
+}
+
+type UpdateFrameRequest struct {
+	Source string `json:"source" binding:"required"`
+	Engine string `json:"engine"`
+}
+
+// UpdateFrame 用提交的 frame 源码替换第 n 个 frame 并重新编译
+func (h *PPTHandler) UpdateFrame(c *gin.Context) {
+	id, n, ok := frameParams(c)
+	if !ok {
+		return
+	}
+
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req UpdateFrameRequest
+	if !bindSource(c, &req) {
+		return
+	}
+	if err := h.pptService.ValidateEngine(req.Engine); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ppt, err := h.pptService.UpdateFrame(c.Request.Context(), id, userID, n, req.Source, req.Engine)
+	if err != nil {
+		respondFrameError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, ppt)
+}
+
+// frameParams 解析 :id 和 :n，失败时已写入响应。
+// n 是 frame 序号，与 /slides 的 PDF 页码不同：使用叠加动画的 frame 会生成多页
+func frameParams(c *gin.Context) (uint, int, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid PPT ID"})
+		return 0, 0, false
+	}
+	n, err := strconv.Atoi(c.Param("n"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid frame number"})
+		return 0, 0, false
+	}
+	return uint(id), n, true
+}
+
+func respondFrameError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrSlideNotFound), errors.Is(err, service.ErrLaTeXNotAvailable):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrTitlePageFrame), errors.Is(err, service.ErrInvalidFrame):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		respondCompileError(c, err)
+	}
+}
+
+func respondSlideError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrPDFNotAvailable), errors.Is(err, service.ErrSlideNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, latex.ErrNoRasterizer):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to render slides"})
+	}
+}
+
+func (h *PPTHandler) GetRepairs(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid PPT ID"})
+		return
+	}
+
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	attempts, err := h.pptService.GetRepairAttempts(userID, uint(id))
+	if errors.Is(err, service.ErrPPTNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "PPT not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get repair attempts"})
+		return
+	}
+
+	c.JSON(http.StatusOK, attempts)
+}
+
+// GetRevisions 列出 PPT 的历史版本，不含源码
+func (h *PPTHandler) GetRevisions(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid PPT ID"})
+		return
+	}
+
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	ppt, err := h.pptService.GetPPT(userID, uint(id))
+	if err != nil {
+		respondPPTError(c, err)
+		return
+	}
+
+	revisions, err := h.pptService.GetRevisions(userID, ppt.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get revisions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"current": ppt.Revision, "revisions": revisions})
+}
+
+// GetRevision 返回一个历史版本，包含源码
+func (h *PPTHandler) GetRevision(c *gin.Context) {
+	id, number, ok := revisionParams(c)
+	if !ok {
+		return
+	}
+
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	rev, err := h.pptService.GetRevision(userID, id, number)
+	if err != nil {
+		respondRevisionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, rev)
+}
+
+// DiffRevisions 返回两个版本之间的 unified diff，省略 to 时与当前内容比较
+func (h *PPTHandler) DiffRevisions(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid PPT ID"})
+		return
+	}
+
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	from, err := strconv.Atoi(c.Query("from"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from revision"})
+		return
+	}
+	to := 0
+	if raw := c.Query("to"); raw != "" {
+		if to, err = strconv.Atoi(raw); err != nil || to <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to revision"})
+			return
+		}
+	}
+
+	ppt, err := h.pptService.GetPPT(userID, uint(id))
+	if err != nil {
+		respondPPTError(c, err)
+		return
+	}
+
+	diff, err := h.pptService.DiffRevisions(ppt, from, to)
+	if err != nil {
+		respondRevisionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"from": from, "to": to, "diff": diff})
+}
+
+type RestoreRevisionRequest struct {
+	Engine string `json:"engine"`
+}
+
+// RestoreRevision 重新编译历史版本并把它作为新版本恢复为当前内容
+func (h *PPTHandler) RestoreRevision(c *gin.Context) {
+	id, number, ok := revisionParams(c)
+	if !ok {
+		return
+	}
+
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	// 请求体可以为空
+	var req RestoreRevisionRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if err := h.pptService.ValidateEngine(req.Engine); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ppt, err := h.pptService.RestoreRevision(c.Request.Context(), id, userID, number, req.Engine)
+	if err != nil {
+		respondRevisionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, ppt)
+}
+
+// revisionParams 解析 :id 和 :rev，失败时已写入响应
+func revisionParams(c *gin.Context) (uint, int, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid PPT ID"})
+		return 0, 0, false
+	}
+	number, err := strconv.Atoi(c.Param("rev"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid revision number"})
+		return 0, 0, false
+	}
+	return uint(id), number, true
+}
+
+func respondRevisionError(c *gin.Context, err error) {
+	if errors.Is(err, service.ErrRevisionNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	respondCompileError(c, err)
+}
+
+func (h *PPTHandler) Delete(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid PPT ID"})
+		return
+	}
+
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	err = h.pptService.DeletePPT(userID, uint(id))
+	if errors.Is(err, service.ErrPPTNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "PPT not found"})
+		return
+	}
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete PPT"})
 		return
 	}
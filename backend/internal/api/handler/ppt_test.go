@@ -0,0 +1,260 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"github.com/qingbingwei/latex_ppt_by_claude/backend/internal/model"
+	"github.com/qingbingwei/latex_ppt_by_claude/backend/internal/repository"
+	"github.com/qingbingwei/latex_ppt_by_claude/backend/internal/service"
+	"github.com/qingbingwei/latex_ppt_by_claude/backend/pkg/ai"
+	"github.com/qingbingwei/latex_ppt_by_claude/backend/pkg/embedding"
+	"github.com/qingbingwei/latex_ppt_by_claude/backend/pkg/latex"
+	"github.com/qingbingwei/latex_ppt_by_claude/backend/pkg/vectordb"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+const (
+	ownerID    uint = 1
+	intruderID uint = 2
+)
+
+const testLatex = `\documentclass{beamer}
+\begin{document}
+\begin{frame}{First}
+Hello
+\end{frame}
+\end{document}
+`
+
+// testEnv 是基于 sqlite 内存数据库的路由，请求头 X-User-ID 代替 JWT 指定当前用户
+type testEnv struct {
+	db     *gorm.DB
+	router *gin.Engine
+	ppt    *model.PPTRecord
+	doc    *model.Document
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+	store := vectordb.NewLocalStore(filepath.Join(t.TempDir(), "vectors.jsonl"))
+	if err := store.Init(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(store.Close)
+	return newTestEnvWithStore(t, store)
+}
+
+// newTestEnvWithStore 与 newTestEnv 相同，但使用指定的向量库
+func newTestEnvWithStore(t *testing.T, store vectordb.VectorStore) *testEnv {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	dir := t.TempDir()
+
+	db, err := gorm.Open(sqlite.Open(filepath.Join(dir, "test.db")), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.AutoMigrate(
+		&model.User{},
+		&model.Document{},
+		&model.Chunk{},
+		&model.PPTRecord{},
+		&model.PPTKnowledgeRef{},
+		&model.PPTRepairAttempt{},
+		&model.PPTRevision{},
+		&model.Team{},
+		&model.TeamMember{},
+		&model.Template{},
+		&model.TemplateVersion{},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	userRepo := repository.NewUserRepository(db)
+	docRepo := repository.NewDocumentRepository(db)
+	pptRepo := repository.NewPPTRepository(db)
+	templateRepo := repository.NewTemplateRepository(db)
+	teamService := service.NewTeamService(repository.NewTeamRepository(db), userRepo)
+
+	outputDir := filepath.Join(dir, "outputs")
+	compiler := latex.NewCompiler(outputDir, latex.Limits{})
+	rasterizer := latex.NewRasterizer(latex.Limits{})
+	knowledgeService := service.NewKnowledgeService(docRepo, embedding.NewOpenAIEmbedding("", ""), store, filepath.Join(dir, "uploads"))
+	templateService := service.NewTemplateService(templateRepo, teamService, compiler, rasterizer, filepath.Join(dir, "templates"), filepath.Join(outputDir, "previews"))
+	pptService := service.NewPPTService(pptRepo, knowledgeService, service.NewAIService(ai.NewRegistry()), templateService, compiler, outputDir, 3)
+	jobService := service.NewJobService(pptService, pptRepo, 1, 1, 1, 0, "")
+	pptHandler := NewPPTHandler(pptService, jobService, service.NewSlideService(rasterizer, outputDir), service.NewExportService(templateService, compiler, rasterizer, filepath.Join(dir, "katex")))
+	knowledgeHandler := NewKnowledgeHandler(knowledgeService)
+
+	router := gin.New()
+	api := router.Group("", func(c *gin.Context) {
+		if id, err := strconv.ParseUint(c.GetHeader("X-User-ID"), 10, 32); err == nil {
+			c.Set("user_id", uint(id))
+		}
+	})
+	ppt := api.Group("/ppt")
+	ppt.GET("/:id", pptHandler.Get)
+	ppt.GET("/:id/download", pptHandler.Download)
+	ppt.POST("/:id/compile", pptHandler.Compile)
+	ppt.GET("/:id/deck", pptHandler.GetDeck)
+	ppt.PUT("/:id/deck", pptHandler.UpdateDeck)
+	ppt.GET("/:id/export", pptHandler.Export)
+	ppt.GET("/:id/repairs", pptHandler.GetRepairs)
+	ppt.GET("/:id/revisions", pptHandler.GetRevisions)
+	ppt.GET("/:id/revisions/diff", pptHandler.DiffRevisions)
+	ppt.GET("/:id/revisions/:rev", pptHandler.GetRevision)
+	ppt.POST("/:id/revisions/:rev/restore", pptHandler.RestoreRevision)
+	ppt.GET("/:id/slides", pptHandler.GetSlides)
+	ppt.GET("/:id/slides/:page", pptHandler.GetSlide)
+	ppt.PUT("/:id/frames/:n", pptHandler.UpdateFrame)
+	ppt.POST("/:id/frames/:n/regenerate", pptHandler.RegenerateFrame)
+	ppt.DELETE("/:id", pptHandler.Delete)
+	knowledge := api.Group("/knowledge")
+	knowledge.GET("/:id", knowledgeHandler.Get)
+	knowledge.DELETE("/:id", knowledgeHandler.Delete)
+
+	for _, id := range []uint{ownerID, intruderID} {
+		name := "user" + strconv.Itoa(int(id))
+		user := &model.User{ID: id, Username: name, Email: name + "@example.com", PasswordHash: "x"}
+		if err := userRepo.Create(user); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	pdfPath := filepath.Join(outputDir, "owner.pdf")
+	if err := os.WriteFile(pdfPath, []byte("%PDF-1.5"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	record := &model.PPTRecord{
+		UserID:       ownerID,
+		Title:        "Owner deck",
+		Prompt:       "prompt",
+		Template:     "default",
+		LatexContent: testLatex,
+		PDFPath:      pdfPath,
+		Status:       "completed",
+	}
+	if err := pptRepo.Create(record); err != nil {
+		t.Fatal(err)
+	}
+	rev := &model.PPTRevision{PPTID: record.ID, AuthorID: ownerID, Origin: service.RevisionOriginGenerated, Source: testLatex, PDFPath: pdfPath}
+	if err := pptRepo.CreateRevision(rev); err != nil {
+		t.Fatal(err)
+	}
+
+	doc := &model.Document{UserID: ownerID, Filename: "notes.txt", FileType: "txt", Status: "completed"}
+	if err := docRepo.Create(doc); err != nil {
+		t.Fatal(err)
+	}
+
+	return &testEnv{db: db, router: router, ppt: record, doc: doc}
+}
+
+func (e *testEnv) do(userID uint, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-User-ID", strconv.Itoa(int(userID)))
+	w := httptest.NewRecorder()
+	e.router.ServeHTTP(w, req)
+	return w
+}
+
+func TestPPTOfAnotherUserIsNotFound(t *testing.T) {
+	env := newTestEnv(t)
+	base := "/ppt/" + strconv.Itoa(int(env.ppt.ID))
+
+	frame := `{"source": "\\begin{frame}{Changed}\nHi\n\\end{frame}"}`
+	requests := []struct {
+		name, method, path, body string
+	}{
+		{"get", http.MethodGet, base, ""},
+		{"download", http.MethodGet, base + "/download", ""},
+		{"compile", http.MethodPost, base + "/compile", `{"latex_content": "\\documentclass{beamer}"}`},
+		{"export", http.MethodGet, base + "/export?format=pptx", ""},
+		{"slides", http.MethodGet, base + "/slides", ""},
+		{"slide", http.MethodGet, base + "/slides/1.png", ""},
+		{"get deck", http.MethodGet, base + "/deck", ""},
+		{"update deck", http.MethodPut, base + "/deck", `{"deck": {"title": "Stolen"}}`},
+		{"update frame", http.MethodPut, base + "/frames/1", frame},
+		{"regenerate frame", http.MethodPost, base + "/frames/1/regenerate", `{"instruction": "shorter"}`},
+		{"revisions", http.MethodGet, base + "/revisions", ""},
+		{"revision", http.MethodGet, base + "/revisions/1", ""},
+		{"diff", http.MethodGet, base + "/revisions/diff?from=1", ""},
+		{"restore", http.MethodPost, base + "/revisions/1/restore", ""},
+		{"repairs", http.MethodGet, base + "/repairs", ""},
+		{"delete", http.MethodDelete, base, ""},
+	}
+	for _, r := range requests {
+		t.Run(r.name, func(t *testing.T) {
+			w := env.do(intruderID, r.method, r.path, r.body)
+			if w.Code != http.StatusNotFound {
+				t.Fatalf("%s %s as another user: got %d %s, want 404", r.method, r.path, w.Code, w.Body)
+			}
+		})
+	}
+
+	// 其他用户的请求不能修改或删除记录
+	var stored model.PPTRecord
+	if err := env.db.First(&stored, env.ppt.ID).Error; err != nil {
+		t.Fatalf("PPT was deleted by another user: %v", err)
+	}
+	if stored.LatexContent != testLatex {
+		t.Fatalf("PPT content was changed by another user")
+	}
+	if _, err := os.Stat(env.ppt.PDFPath); err != nil {
+		t.Fatalf("PDF was removed by another user: %v", err)
+	}
+}
+
+func TestPPTOwnerCanAccess(t *testing.T) {
+	env := newTestEnv(t)
+	base := "/ppt/" + strconv.Itoa(int(env.ppt.ID))
+
+	for _, path := range []string{base, base + "/download", base + "/deck", base + "/revisions", base + "/revisions/1", base + "/repairs"} {
+		if w := env.do(ownerID, http.MethodGet, path, ""); w.Code != http.StatusOK {
+			t.Errorf("GET %s as owner: got %d %s, want 200", path, w.Code, w.Body)
+		}
+	}
+
+	if w := env.do(ownerID, http.MethodDelete, base, ""); w.Code != http.StatusOK {
+		t.Fatalf("DELETE %s as owner: got %d %s, want 200", base, w.Code, w.Body)
+	}
+	var count int64
+	env.db.Model(&model.PPTRevision{}).Where("ppt_id = ?", env.ppt.ID).Count(&count)
+	if count != 0 {
+		t.Errorf("%d revisions left after delete", count)
+	}
+	if _, err := os.Stat(env.ppt.PDFPath); !os.IsNotExist(err) {
+		t.Errorf("PDF still exists after delete: %v", err)
+	}
+}
+
+func TestPPTLookupErrorIsServerError(t *testing.T) {
+	env := newTestEnv(t)
+	sqlDB, err := env.db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.Close()
+
+	base := "/ppt/" + strconv.Itoa(int(env.ppt.ID))
+	for _, path := range []string{base, base + "/download", base + "/deck", base + "/revisions"} {
+		if w := env.do(ownerID, http.MethodGet, path, ""); w.Code != http.StatusInternalServerError {
+			t.Errorf("GET %s with a closed database: got %d %s, want 500", path, w.Code, w.Body)
+		}
+	}
+}
@@ -0,0 +1,265 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/qingbingwei/latex_ppt_by_claude/backend/internal/api/middleware"
+	"github.com/qingbingwei/latex_ppt_by_claude/backend/internal/service"
+	"github.com/qingbingwei/latex_ppt_by_claude/backend/pkg/latex"
+)
+
+type TemplateHandler struct {
+	templateService *service.TemplateService
+}
+
+func NewTemplateHandler(templateService *service.TemplateService) *TemplateHandler {
+	return &TemplateHandler{
+		templateService: templateService,
+	}
+}
+
+// Create 上传新模板 (multipart/form-data)：name、description、engine、team_id、
+// source（文本字段或文件）以及任意个 assets 文件
+func (h *TemplateHandler) Create(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	input, err := readTemplateInput(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tmpl, err := h.templateService.Create(c.Request.Context(), userID, input)
+	if err != nil {
+		respondTemplateError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, tmpl)
+}
+
+// AddVersion 上传模板的新版本，字段与 Create 相同（name、team_id 被忽略）
+func (h *TemplateHandler) AddVersion(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid template ID"})
+		return
+	}
+
+	input, err := readTemplateInput(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	version, err := h.templateService.AddVersion(c.Request.Context(), userID, uint(id), input)
+	if err != nil {
+		respondTemplateError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, version)
+}
+
+func (h *TemplateHandler) List(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	templates, err := h.templateService.List(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get templates"})
+		return
+	}
+
+	c.JSON(http.StatusOK, templates)
+}
+
+func (h *TemplateHandler) Get(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid template ID"})
+		return
+	}
+
+	detail, err := h.templateService.Get(userID, uint(id))
+	if err != nil {
+		respondTemplateError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, detail)
+}
+
+type ShareTemplateRequest struct {
+	// TeamID 为 null 时取消共享
+	TeamID *uint `json:"team_id"`
+}
+
+func (h *TemplateHandler) Share(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid template ID"})
+		return
+	}
+
+	var req ShareTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tmpl, err := h.templateService.Share(userID, uint(id), req.TeamID)
+	if err != nil {
+		respondTemplateError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tmpl)
+}
+
+func (h *TemplateHandler) Delete(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid template ID"})
+		return
+	}
+
+	if err := h.templateService.Delete(userID, uint(id)); err != nil {
+		respondTemplateError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Template deleted successfully"})
+}
+
+// Preview 返回模板预览 PNG（内置模板或可见的自定义模板）
+func (h *TemplateHandler) Preview(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	path, err := h.templateService.Preview(c.Request.Context(), userID, c.Param("name"))
+	if errors.Is(err, latex.ErrNoRasterizer) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		respondTemplateError(c, err)
+		return
+	}
+
+	c.Header("Cache-Control", "private, max-age=3600")
+	c.File(path)
+}
+
+func readTemplateInput(c *gin.Context) (service.TemplateInput, error) {
+	input := service.TemplateInput{
+		Name:        c.PostForm("name"),
+		Description: c.PostForm("description"),
+		Engine:      c.PostForm("engine"),
+		Source:      c.PostForm("source"),
+	}
+
+	if teamID := c.PostForm("team_id"); teamID != "" {
+		id, err := strconv.ParseUint(teamID, 10, 32)
+		if err != nil {
+			return input, fmt.Errorf("invalid team_id")
+		}
+		tid := uint(id)
+		input.TeamID = &tid
+	}
+
+	if input.Source == "" {
+		if file, err := c.FormFile("source"); err == nil {
+			data, err := readFormFile(file)
+			if err != nil {
+				return input, err
+			}
+			input.Source = string(data)
+		}
+	}
+	if input.Source == "" {
+		return input, fmt.Errorf("source is required")
+	}
+
+	form, err := c.MultipartForm()
+	if err != nil {
+		return input, nil
+	}
+	for _, file := range form.File["assets"] {
+		data, err := readFormFile(file)
+		if err != nil {
+			return input, err
+		}
+		if input.Assets == nil {
+			input.Assets = make(map[string][]byte)
+		}
+		input.Assets[filepath.Base(file.Filename)] = data
+	}
+	return input, nil
+}
+
+func readFormFile(file *multipart.FileHeader) ([]byte, error) {
+	f, err := file.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s", file.Filename)
+	}
+	defer f.Close()
+	return io.ReadAll(f)
+}
+
+// respondTemplateError 把模板错误映射为状态码，编译校验失败时返回 422 和诊断信息
+func respondTemplateError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrTemplateNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrTemplateExists), errors.Is(err, service.ErrTemplateInUse):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrNotTemplateOwner), errors.Is(err, service.ErrNotTeamMember):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrInvalidTemplate):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		respondCompileError(c, err)
+	}
+}
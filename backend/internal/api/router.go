@@ -1,6 +1,11 @@
 package api
 
 import (
+	"context"
+	"fmt"
+	"log"
+	"path/filepath"
+
 	"github.com/gin-gonic/gin"
 	"github.com/qingbingwei/latex_ppt_by_claude/backend/internal/api/handler"
 	"github.com/qingbingwei/latex_ppt_by_claude/backend/internal/api/middleware"
@@ -26,41 +31,109 @@ func SetupRouter(db *gorm.DB, cfg *config.Config) *gin.Engine {
 	router.Use(middleware.CORS())
 	router.Use(middleware.Logger())
 
-	// Initialize clients - 使用 copilot-api 代理时直接使用 OpenAI 客户端
-	var openaiClient *ai.OpenAIClient
+	// Initialize AI providers - 使用 copilot-api 代理时直接使用 OpenAI 客户端
+	// 注册顺序即默认优先级：Copilot > OpenAI > Claude
+	aiRegistry := ai.NewRegistry()
+	if cfg.AI.GitHubToken != "" {
+		aiRegistry.Register(ai.NewCopilotClient(cfg.AI.GitHubToken))
+	}
 	if cfg.AI.OpenAIAPIKey != "" {
-		openaiClient = ai.NewOpenAIClient(cfg.AI.OpenAIAPIKey, cfg.AI.OpenAIBaseURL)
+		aiRegistry.Register(ai.NewOpenAIClient(cfg.AI.OpenAIAPIKey, cfg.AI.OpenAIBaseURL))
 	}
-
-	var claudeClient *ai.ClaudeClient
 	if cfg.AI.ClaudeAPIKey != "" {
-		claudeClient = ai.NewClaudeClient(cfg.AI.ClaudeAPIKey)
+		aiRegistry.Register(ai.NewClaudeClient(cfg.AI.ClaudeAPIKey))
+	}
+	if cfg.AI.DefaultProvider != "" {
+		if err := aiRegistry.SetDefault(cfg.AI.DefaultProvider); err != nil {
+			log.Printf("Warning: %v", err)
+		}
 	}
 
 	embeddingClient := embedding.NewOpenAIEmbedding(cfg.AI.OpenAIAPIKey, cfg.AI.OpenAIBaseURL)
 
-	milvusClient, err := vectordb.NewMilvusClient(cfg.Milvus.Host, cfg.Milvus.Port)
-	if err != nil {
-		panic("Failed to connect to Milvus: " + err.Error())
+	vectorStore := newVectorStore(cfg)
+
+	latexCompiler := latex.NewCompiler(cfg.Storage.OutputDir, latex.Limits{
+		Timeout:        cfg.Latex.Timeout,
+		CPUSeconds:     cfg.Latex.CPUSeconds,
+		MemoryMB:       cfg.Latex.MemoryMB,
+		MaxFileMB:      cfg.Latex.MaxFileMB,
+		MaxOutputBytes: cfg.Latex.MaxOutputBytes,
+	})
+	if !cfg.Latex.CacheEnabled {
+		latexCompiler.DisableCache()
+	}
+	if err := latexCompiler.SetDefaultEngine(cfg.Latex.Engine); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+	if engines := latexCompiler.DetectEngines(); len(engines) > 0 {
+		log.Printf("Detected TeX engines: %v", engines)
+	} else {
+		log.Printf("Warning: no TeX engine found in PATH")
 	}
 
-	latexCompiler := latex.NewCompiler(cfg.Storage.OutputDir)
+	rasterizer := latex.NewRasterizer(latex.Limits{
+		Timeout:        cfg.Latex.Timeout,
+		CPUSeconds:     cfg.Latex.CPUSeconds,
+		MemoryMB:       cfg.Latex.MemoryMB,
+		MaxFileMB:      cfg.Latex.MaxFileMB,
+		MaxOutputBytes: cfg.Latex.MaxOutputBytes,
+	})
+	if rasterizer.Tool() == "" {
+		log.Printf("Warning: %v, template and slide previews are disabled", latex.ErrNoRasterizer)
+	}
 
 	// Initialize repositories
 	userRepo := repository.NewUserRepository(db)
 	docRepo := repository.NewDocumentRepository(db)
 	pptRepo := repository.NewPPTRepository(db)
+	teamRepo := repository.NewTeamRepository(db)
+	templateRepo := repository.NewTemplateRepository(db)
 
 	// Initialize services
-	knowledgeService := service.NewKnowledgeService(docRepo, embeddingClient, milvusClient, cfg.Storage.UploadDir)
-	aiService := service.NewAIService(openaiClient, claudeClient, nil)
-	pptService := service.NewPPTService(pptRepo, knowledgeService, aiService, latexCompiler, cfg.Storage.OutputDir)
+	knowledgeService := service.NewKnowledgeService(docRepo, embeddingClient, vectorStore, cfg.Storage.UploadDir)
+	aiService := service.NewAIService(aiRegistry)
+	teamService := service.NewTeamService(teamRepo, userRepo)
+	templateService := service.NewTemplateService(
+		templateRepo,
+		teamService,
+		latexCompiler,
+		rasterizer,
+		filepath.Join(cfg.Storage.UploadDir, "templates"),
+		filepath.Join(cfg.Storage.OutputDir, "previews"),
+	)
+	pptService := service.NewPPTService(pptRepo, knowledgeService, aiService, templateService, latexCompiler, cfg.Storage.OutputDir, cfg.AI.AutoFixMaxAttempts)
+	jobService := service.NewJobService(
+		pptService,
+		pptRepo,
+		cfg.Job.Workers,
+		cfg.Job.QueueSize,
+		cfg.Job.MaxAttempts,
+		cfg.Job.Timeout,
+		cfg.Job.Recovery,
+	)
+	jobService.Start(context.Background())
+
+	slideService := service.NewSlideService(rasterizer, cfg.Storage.OutputDir)
+	exportService := service.NewExportService(templateService, latexCompiler, rasterizer, cfg.Storage.KaTeXDir)
+
+	janitorService := service.NewJanitorService(
+		pptRepo,
+		latexCompiler,
+		cfg.Storage.OutputDir,
+		cfg.Latex.JanitorInterval,
+		cfg.Latex.TempTTL,
+		cfg.Latex.CacheTTL,
+	)
+	janitorService.Start(context.Background())
 
 	// Initialize handlers
 	healthHandler := handler.NewHealthHandler()
 	authHandler := handler.NewAuthHandler(userRepo, cfg.JWT.Secret, cfg.JWT.ExpireHours)
 	knowledgeHandler := handler.NewKnowledgeHandler(knowledgeService)
-	pptHandler := handler.NewPPTHandler(pptService)
+	pptHandler := handler.NewPPTHandler(pptService, jobService, slideService, exportService)
+	teamHandler := handler.NewTeamHandler(teamService)
+	templateHandler := handler.NewTemplateHandler(templateService)
 
 	// Public routes
 	v1 := router.Group("/api/v1")
@@ -96,14 +169,87 @@ func SetupRouter(db *gorm.DB, cfg *config.Config) *gin.Engine {
 		ppt := protected.Group("/ppt")
 		{
 			ppt.POST("/generate", pptHandler.Generate)
+			ppt.POST("/outline", pptHandler.CreateOutline)
 			ppt.GET("/templates", pptHandler.GetTemplates)
-			ppt.POST("/compile", pptHandler.Compile)
+			ppt.GET("/templates/:name/preview", templateHandler.Preview)
+			ppt.GET("/providers", pptHandler.GetProviders)
+			ppt.GET("/engines", pptHandler.GetEngines)
+			ppt.POST("/markdown", pptHandler.ImportMarkdown)
+			ppt.POST("/markdown/convert", pptHandler.ConvertMarkdown)
 			ppt.GET("/history", pptHandler.GetHistory)
 			ppt.GET("/:id", pptHandler.Get)
 			ppt.GET("/:id/download", pptHandler.Download)
+			ppt.POST("/:id/compile", pptHandler.Compile)
+			ppt.GET("/:id/outline", pptHandler.GetOutline)
+			ppt.PUT("/:id/outline", pptHandler.UpdateOutline)
+			ppt.POST("/:id/outline/generate", pptHandler.GenerateFromOutline)
+			ppt.GET("/:id/deck", pptHandler.GetDeck)
+			ppt.PUT("/:id/deck", pptHandler.UpdateDeck)
+			ppt.GET("/:id/export", pptHandler.Export)
+			ppt.GET("/:id/repairs", pptHandler.GetRepairs)
+			ppt.GET("/:id/revisions", pptHandler.GetRevisions)
+			ppt.GET("/:id/revisions/diff", pptHandler.DiffRevisions)
+			ppt.GET("/:id/revisions/:rev", pptHandler.GetRevision)
+			ppt.POST("/:id/revisions/:rev/restore", pptHandler.RestoreRevision)
+			ppt.GET("/:id/slides", pptHandler.GetSlides)
+			ppt.GET("/:id/slides/:page", pptHandler.GetSlide)
+			ppt.PUT("/:id/frames/:n", pptHandler.UpdateFrame)
+			ppt.POST("/:id/frames/:n/regenerate", pptHandler.RegenerateFrame)
 			ppt.DELETE("/:id", pptHandler.Delete)
 		}
+
+		// LaTeX scratch compile
+		tex := protected.Group("/latex")
+		{
+			tex.POST("/compile", pptHandler.CompileScratch)
+		}
+
+		// Teams
+		teams := protected.Group("/teams")
+		{
+			teams.POST("", teamHandler.Create)
+			teams.GET("", teamHandler.List)
+			teams.GET("/:id/members", teamHandler.GetMembers)
+			teams.POST("/:id/members", teamHandler.AddMember)
+			teams.DELETE("/:id/members/:user_id", teamHandler.RemoveMember)
+		}
+
+		// Custom templates
+		templates := protected.Group("/templates")
+		{
+			templates.POST("", templateHandler.Create)
+			templates.GET("", templateHandler.List)
+			templates.GET("/:id", templateHandler.Get)
+			templates.POST("/:id/versions", templateHandler.AddVersion)
+			templates.PUT("/:id/share", templateHandler.Share)
+			templates.DELETE("/:id", templateHandler.Delete)
+		}
 	}
 
 	return router
 }
+
+// newVectorStore 按配置创建并初始化向量存储。
+// Milvus 无法连接时服务照常启动，知识库的向量写入和检索不可用
+func newVectorStore(cfg *config.Config) vectordb.VectorStore {
+	var store vectordb.VectorStore
+	switch cfg.Vector.Backend {
+	case "local":
+		store = vectordb.NewLocalStore(cfg.Vector.LocalPath)
+	case "milvus":
+		client, err := vectordb.NewMilvusClient(cfg.Milvus.Host, cfg.Milvus.Port)
+		if err != nil {
+			log.Printf("Warning: Failed to connect to Milvus: %v, knowledge base search is disabled", err)
+			return vectordb.Unavailable(err)
+		}
+		store = client
+	default:
+		log.Printf("Warning: unknown vector store %q, knowledge base search is disabled", cfg.Vector.Backend)
+		return vectordb.Unavailable(fmt.Errorf("unknown backend %q", cfg.Vector.Backend))
+	}
+
+	if err := store.Init(context.Background()); err != nil {
+		log.Printf("Warning: Failed to initialize %s vector store: %v", cfg.Vector.Backend, err)
+	}
+	return store
+}
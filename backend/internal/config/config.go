@@ -4,6 +4,7 @@ import (
 	"log"
 	"os"
 	"strconv"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -12,9 +13,12 @@ type Config struct {
 	Server   ServerConfig
 	Database DatabaseConfig
 	Milvus   MilvusConfig
+	Vector   VectorConfig
 	AI       AIConfig
 	JWT      JWTConfig
 	Storage  StorageConfig
+	Job      JobConfig
+	Latex    LatexConfig
 }
 
 type ServerConfig struct {
@@ -35,10 +39,20 @@ type MilvusConfig struct {
 	Port string
 }
 
+type VectorConfig struct {
+	// Backend 为 milvus 或 local（进程内检索，向量保存在 LocalPath 文件中）
+	Backend   string
+	LocalPath string
+}
+
 type AIConfig struct {
-	OpenAIAPIKey  string
-	OpenAIBaseURL string
-	ClaudeAPIKey  string
+	OpenAIAPIKey    string
+	OpenAIBaseURL   string
+	ClaudeAPIKey    string
+	GitHubToken     string
+	DefaultProvider string
+	// AutoFixMaxAttempts 是自动修复编译错误的次数上限
+	AutoFixMaxAttempts int
 }
 
 type JWTConfig struct {
@@ -49,6 +63,32 @@ type JWTConfig struct {
 type StorageConfig struct {
 	UploadDir string
 	OutputDir string
+	// KaTeXDir 是 HTML 导出时打包的 KaTeX 发行包目录
+	KaTeXDir string
+}
+
+type LatexConfig struct {
+	// Engine 是未指定引擎时使用的 TeX 引擎
+	Engine         string
+	Timeout        time.Duration
+	CPUSeconds     int
+	MemoryMB       int
+	MaxFileMB      int
+	MaxOutputBytes int
+	// 编译缓存和临时文件清理
+	CacheEnabled    bool
+	CacheTTL        time.Duration
+	TempTTL         time.Duration
+	JanitorInterval time.Duration
+}
+
+type JobConfig struct {
+	Workers     int
+	QueueSize   int
+	MaxAttempts int
+	Timeout     time.Duration
+	// 启动时对中断任务的处理方式：resume 重新入队，fail 标记为失败
+	Recovery string
 }
 
 func Load() *Config {
@@ -80,10 +120,17 @@ func Load() *Config {
 			Host: getEnv("MILVUS_HOST", "localhost"),
 			Port: getEnv("MILVUS_PORT", "19530"),
 		},
+		Vector: VectorConfig{
+			Backend:   getEnv("VECTOR_STORE", "milvus"),
+			LocalPath: getEnv("VECTOR_STORE_PATH", "./data/vectors.jsonl"),
+		},
 		AI: AIConfig{
-			OpenAIAPIKey:  getEnv("OPENAI_API_KEY", ""),
-			OpenAIBaseURL: getEnv("OPENAI_BASE_URL", "https://api.githubcopilot.com"),
-			ClaudeAPIKey:  getEnv("CLAUDE_API_KEY", ""),
+			OpenAIAPIKey:       getEnv("OPENAI_API_KEY", ""),
+			OpenAIBaseURL:      getEnv("OPENAI_BASE_URL", "https://api.githubcopilot.com"),
+			ClaudeAPIKey:       getEnv("CLAUDE_API_KEY", ""),
+			GitHubToken:        getEnv("GITHUB_TOKEN", ""),
+			DefaultProvider:    getEnv("AI_DEFAULT_PROVIDER", ""),
+			AutoFixMaxAttempts: getEnvInt("AI_AUTOFIX_MAX_ATTEMPTS", 3),
 		},
 		JWT: JWTConfig{
 			Secret:      getEnv("JWT_SECRET", "your-secret-key-change-this"),
@@ -92,6 +139,27 @@ func Load() *Config {
 		Storage: StorageConfig{
 			UploadDir: getEnv("UPLOAD_DIR", "./uploads"),
 			OutputDir: getEnv("OUTPUT_DIR", "./outputs"),
+			KaTeXDir:  getEnv("KATEX_DIR", "./assets/katex"),
+		},
+		Job: JobConfig{
+			Workers:     getEnvInt("JOB_WORKERS", 2),
+			QueueSize:   getEnvInt("JOB_QUEUE_SIZE", 100),
+			MaxAttempts: getEnvInt("JOB_MAX_ATTEMPTS", 3),
+			Timeout:     time.Duration(getEnvInt("JOB_TIMEOUT_SECONDS", 600)) * time.Second,
+			Recovery:    getEnv("JOB_RECOVERY", "resume"),
+		},
+		Latex: LatexConfig{
+			Engine:         getEnv("LATEX_ENGINE", "xelatex"),
+			Timeout:        time.Duration(getEnvInt("LATEX_TIMEOUT_SECONDS", 120)) * time.Second,
+			CPUSeconds:     getEnvInt("LATEX_CPU_SECONDS", 120),
+			MemoryMB:       getEnvInt("LATEX_MEMORY_MB", 2048),
+			MaxFileMB:      getEnvInt("LATEX_MAX_FILE_MB", 100),
+			MaxOutputBytes: getEnvInt("LATEX_MAX_OUTPUT_KB", 256) * 1024,
+
+			CacheEnabled:    getEnvBool("LATEX_CACHE_ENABLED", true),
+			CacheTTL:        time.Duration(getEnvInt("LATEX_CACHE_TTL_HOURS", 168)) * time.Hour,
+			TempTTL:         time.Duration(getEnvInt("LATEX_TEMP_TTL_MINUTES", 60)) * time.Minute,
+			JanitorInterval: time.Duration(getEnvInt("JANITOR_INTERVAL_MINUTES", 30)) * time.Minute,
 		},
 	}
 }
@@ -102,3 +170,21 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+func getEnvInt(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if i, err := strconv.Atoi(value); err == nil {
+			return i
+		}
+	}
+	return defaultValue
+}
+
+func getEnvBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	}
+	return defaultValue
+}
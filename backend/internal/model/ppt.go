@@ -1,27 +1,173 @@
 package model
 
 import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
 	"time"
+
+	"github.com/qingbingwei/latex_ppt_by_claude/backend/pkg/latex"
 )
 
 type PPTRecord struct {
-	ID           uint      `gorm:"primaryKey" json:"id"`
-	UserID       uint      `gorm:"index;not null" json:"user_id"`
-	Title        string    `gorm:"size:255" json:"title"`
-	Prompt       string    `gorm:"type:text;not null" json:"prompt"`
-	LatexContent string    `gorm:"type:text" json:"latex_content"`
-	PDFPath      string    `gorm:"size:500" json:"pdf_path"`
-	Template     string    `gorm:"size:50;default:'default'" json:"template"`
-	Status       string    `gorm:"size:20;default:'pending'" json:"status"` // pending, generating, completed, failed
-	ErrorMessage string    `gorm:"type:text" json:"error_message,omitempty"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
+	ID              uint          `gorm:"primaryKey" json:"id"`
+	UserID          uint          `gorm:"index;not null" json:"user_id"`
+	Title           string        `gorm:"size:255" json:"title"`
+	Prompt          string        `gorm:"type:text;not null" json:"prompt"`
+	LatexContent    string        `gorm:"type:text" json:"latex_content"`
+	Deck            *Deck         `gorm:"type:text" json:"-"` // 由 LatexContent 解析出的结构化文档，通过 /ppt/:id/deck 读写
+	PDFPath         string        `gorm:"size:500" json:"pdf_path"`
+	Revision        int           `gorm:"default:0" json:"revision,omitempty"` // 当前内容对应的版本号，0 表示还没有版本
+	Template        string        `gorm:"size:64;default:'default'" json:"template"`
+	TemplateVersion int           `gorm:"default:0" json:"template_version,omitempty"` // 自定义模板的版本号，内置模板为 0
+	Engine          string        `gorm:"size:20" json:"engine,omitempty"`             // 实际使用的 TeX 引擎
+	Mode            string        `gorm:"size:20" json:"mode,omitempty"`               // full: 模型输出完整文档；template: 模型只输出 frame；outline: 按大纲分章节生成；markdown: 由 Markdown 导入
+	Subtitle        string        `gorm:"size:255" json:"subtitle,omitempty"`
+	Author          string        `gorm:"size:255" json:"author,omitempty"`
+	Institute       string        `gorm:"size:255" json:"institute,omitempty"`
+	Status          string        `gorm:"size:20;default:'pending';index" json:"status"` // outline (大纲待确认), pending, generating, completed, failed
+	ErrorMessage    string        `gorm:"type:text" json:"error_message,omitempty"`
+	Diagnostics     Diagnostics   `gorm:"type:text" json:"diagnostics,omitempty"`
+	Passes          CompilePasses `gorm:"type:text" json:"passes,omitempty"`
+	Outline         *Outline      `gorm:"type:text" json:"outline,omitempty"` // 两阶段生成的大纲，可在生成 frame 前修改
+	Provider        string        `gorm:"size:50" json:"provider,omitempty"`
+	Model           string        `gorm:"size:100" json:"model,omitempty"`
+	DocumentIDs     string        `gorm:"type:text" json:"-"` // JSON string of document IDs
+	Attempts        int           `gorm:"default:0" json:"attempts"`
+	MaxFixAttempts  int           `gorm:"default:0" json:"max_fix_attempts"` // 编译失败后 AI 自动修复的最大次数，0 表示不修复
+	StartedAt       *time.Time    `json:"started_at,omitempty"`
+	FinishedAt      *time.Time    `json:"finished_at,omitempty"`
+	CreatedAt       time.Time     `json:"created_at"`
+	UpdatedAt       time.Time     `json:"updated_at"`
 }
 
 func (PPTRecord) TableName() string {
 	return "ppt_records"
 }
 
+// PPTRepairAttempt 记录一次由 AI 自动修复编译错误的尝试
+type PPTRepairAttempt struct {
+	ID           uint        `gorm:"primaryKey" json:"id"`
+	PPTID        uint        `gorm:"index;not null" json:"ppt_id"`
+	Attempt      int         `json:"attempt"`
+	Provider     string      `gorm:"size:50" json:"provider"`
+	Model        string      `gorm:"size:100" json:"model"`
+	Errors       Diagnostics `gorm:"type:text" json:"errors"` // 提交给模型的编译错误
+	Source       string      `gorm:"type:text" json:"source"` // 模型返回的修复后源码
+	Success      bool        `json:"success"`                 // 修复后的源码是否编译成功
+	ErrorMessage string      `gorm:"type:text" json:"error_message,omitempty"`
+	CreatedAt    time.Time   `json:"created_at"`
+}
+
+func (PPTRepairAttempt) TableName() string {
+	return "ppt_repair_attempts"
+}
+
+// PPTRevision 记录 PPT 源码的一个历史版本
+type PPTRevision struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	PPTID        uint      `gorm:"uniqueIndex:idx_ppt_revision;not null" json:"ppt_id"`
+	Number       int       `gorm:"uniqueIndex:idx_ppt_revision;not null" json:"number"` // 在同一个 PPT 内从 1 递增
+	Source       string    `gorm:"type:text" json:"source,omitempty"`                   // 列表接口不返回源码
+	PDFPath      string    `gorm:"size:500" json:"pdf_path,omitempty"`                  // 编译失败的版本没有 PDF
+	Engine       string    `gorm:"size:20" json:"engine,omitempty"`
+	AuthorID     uint      `gorm:"index" json:"author_id"`
+	Origin       string    `gorm:"size:20" json:"origin"`                    // generated, manual, auto_fix, restore
+	RestoredFrom int       `gorm:"default:0" json:"restored_from,omitempty"` // origin 为 restore 时恢复的版本号
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+func (PPTRevision) TableName() string {
+	return "ppt_revisions"
+}
+
+// Diagnostics 是最近一次编译的诊断信息，以 JSON 形式存储
+type Diagnostics []latex.Diagnostic
+
+func (d Diagnostics) Value() (driver.Value, error) {
+	if len(d) == 0 {
+		return "", nil
+	}
+	return jsonValue(d)
+}
+
+func (d *Diagnostics) Scan(value interface{}) error {
+	return scanJSON(value, d)
+}
+
+// CompilePasses 是最近一次编译实际执行的步骤，以 JSON 形式存储
+type CompilePasses []latex.Pass
+
+func (p CompilePasses) Value() (driver.Value, error) {
+	if len(p) == 0 {
+		return "", nil
+	}
+	return jsonValue(p)
+}
+
+func (p *CompilePasses) Scan(value interface{}) error {
+	return scanJSON(value, p)
+}
+
+// Outline 是两阶段生成中第一阶段得到的大纲，以 JSON 形式存储
+type Outline struct {
+	Sections []OutlineSection `json:"sections"`
+}
+
+type OutlineSection struct {
+	Title  string         `json:"title"`
+	Frames []OutlineFrame `json:"frames"`
+}
+
+// OutlineFrame 是一页幻灯片的标题和要点
+type OutlineFrame struct {
+	Title  string   `json:"title"`
+	Points []string `json:"points,omitempty"`
+}
+
+func (o Outline) Value() (driver.Value, error) {
+	return jsonValue(o)
+}
+
+func (o *Outline) Scan(value interface{}) error {
+	return scanJSON(value, o)
+}
+
+// Deck 是 PPT 的结构化文档，以 JSON 形式存储
+type Deck latex.Deck
+
+func (d Deck) Value() (driver.Value, error) {
+	return jsonValue(d)
+}
+
+func (d *Deck) Scan(value interface{}) error {
+	return scanJSON(value, d)
+}
+
+func jsonValue(v interface{}) (driver.Value, error) {
+	data, err := json.Marshal(v)
+	return string(data), err
+}
+
+// scanJSON 把 text 列中的 JSON 解码到 dest，空值保持零值
+func scanJSON(value interface{}, dest interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	default:
+		return fmt.Errorf("unsupported JSON column type %T", value)
+	}
+	if len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, dest)
+}
+
 type PPTKnowledgeRef struct {
 	ID         uint      `gorm:"primaryKey" json:"id"`
 	PPTID      uint      `gorm:"index;not null" json:"ppt_id"`
@@ -0,0 +1,30 @@
+package model
+
+import (
+	"time"
+)
+
+// Team 是共享自定义模板的用户组
+type Team struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Name      string    `gorm:"size:100;not null" json:"name"`
+	OwnerID   uint      `gorm:"index;not null" json:"owner_id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (Team) TableName() string {
+	return "teams"
+}
+
+type TeamMember struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	TeamID    uint      `gorm:"uniqueIndex:idx_team_user;not null" json:"team_id"`
+	UserID    uint      `gorm:"uniqueIndex:idx_team_user;index;not null" json:"user_id"`
+	Role      string    `gorm:"size:20;default:'member'" json:"role"` // owner, member
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (TeamMember) TableName() string {
+	return "team_members"
+}
@@ -0,0 +1,39 @@
+package model
+
+import (
+	"time"
+)
+
+// Template 是用户上传的 Beamer 模板，内容按版本保存在 TemplateVersion 中
+type Template struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	Name        string    `gorm:"size:50;uniqueIndex:idx_template_owner_name;not null" json:"name"` // GenerateRequest.Template 中使用的名称，同一所有者内唯一
+	Description string    `gorm:"type:text" json:"description"`
+	OwnerID     uint      `gorm:"uniqueIndex:idx_template_owner_name;index;not null" json:"owner_id"`
+	TeamID      *uint     `gorm:"index" json:"team_id,omitempty"`  // 非空时团队成员可见
+	Engine      string    `gorm:"size:20" json:"engine,omitempty"` // 偏好的 TeX 引擎
+	Version     int       `gorm:"default:0" json:"version"`        // 最新版本号
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+func (Template) TableName() string {
+	return "templates"
+}
+
+// TemplateVersion 是模板的一个不可变版本，只有通过编译校验的版本才会被保存
+type TemplateVersion struct {
+	ID          uint        `gorm:"primaryKey" json:"id"`
+	TemplateID  uint        `gorm:"uniqueIndex:idx_template_version;not null" json:"template_id"`
+	Version     int         `gorm:"uniqueIndex:idx_template_version;not null" json:"version"`
+	Source      string      `gorm:"type:text;not null" json:"source"` // 含 {{TITLE}}、{{CONTENT}} 等占位符
+	AssetDir    string      `gorm:"size:500" json:"-"`
+	Assets      string      `gorm:"type:text" json:"-"` // JSON string of asset names
+	Diagnostics Diagnostics `gorm:"type:text" json:"diagnostics,omitempty"`
+	CreatedBy   uint        `json:"created_by"`
+	CreatedAt   time.Time   `json:"created_at"`
+}
+
+func (TemplateVersion) TableName() string {
+	return "template_versions"
+}
@@ -23,12 +23,30 @@ func (r *DocumentRepository) FindByID(id uint) (*model.Document, error) {
 	return &doc, err
 }
 
+// FindByIDAndUserID 只查找属于 userID 的文档，其他用户的文档同样返回 gorm.ErrRecordNotFound
+func (r *DocumentRepository) FindByIDAndUserID(id, userID uint) (*model.Document, error) {
+	var doc model.Document
+	err := r.db.Where("user_id = ?", userID).First(&doc, id).Error
+	return &doc, err
+}
+
 func (r *DocumentRepository) FindByUserID(userID uint) ([]model.Document, error) {
 	var docs []model.Document
 	err := r.db.Where("user_id = ?", userID).Order("created_at DESC").Find(&docs).Error
 	return docs, err
 }
 
+// FindIDsByUserID 返回 userID 的文档 ID，ids 非空时只返回其中属于该用户的
+func (r *DocumentRepository) FindIDsByUserID(userID uint, ids []uint) ([]uint, error) {
+	query := r.db.Model(&model.Document{}).Where("user_id = ?", userID)
+	if len(ids) > 0 {
+		query = query.Where("id IN ?", ids)
+	}
+	var found []uint
+	err := query.Pluck("id", &found).Error
+	return found, err
+}
+
 func (r *DocumentRepository) Update(doc *model.Document) error {
 	return r.db.Save(doc).Error
 }
@@ -3,6 +3,7 @@ package repository
 import (
 	"github.com/qingbingwei/latex_ppt_by_claude/backend/internal/model"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type PPTRepository struct {
@@ -23,18 +24,55 @@ func (r *PPTRepository) FindByID(id uint) (*model.PPTRecord, error) {
 	return &ppt, err
 }
 
+// FindByIDAndUserID 只查找属于 userID 的记录，其他用户的记录同样返回 gorm.ErrRecordNotFound
+func (r *PPTRepository) FindByIDAndUserID(id, userID uint) (*model.PPTRecord, error) {
+	var ppt model.PPTRecord
+	err := r.db.Where("user_id = ?", userID).First(&ppt, id).Error
+	return &ppt, err
+}
+
 func (r *PPTRepository) FindByUserID(userID uint) ([]model.PPTRecord, error) {
 	var ppts []model.PPTRecord
 	err := r.db.Where("user_id = ?", userID).Order("created_at DESC").Find(&ppts).Error
 	return ppts, err
 }
 
+func (r *PPTRepository) FindByStatus(statuses ...string) ([]model.PPTRecord, error) {
+	var ppts []model.PPTRecord
+	err := r.db.Where("status IN ?", statuses).Order("created_at").Find(&ppts).Error
+	return ppts, err
+}
+
+// TransitionStatus 仅当记录处于 from 状态时将其更新为 to，返回是否更新成功
+func (r *PPTRepository) TransitionStatus(id uint, from, to string) (bool, error) {
+	result := r.db.Model(&model.PPTRecord{}).
+		Where("id = ? AND status = ?", id, from).
+		Update("status", to)
+	return result.RowsAffected == 1, result.Error
+}
+
+// UpdateDiagnostics 仅当记录处于 status 状态时更新诊断信息和编译步骤，不改动其他列
+func (r *PPTRepository) UpdateDiagnostics(id uint, status string, diags model.Diagnostics, passes model.CompilePasses) (bool, error) {
+	result := r.db.Model(&model.PPTRecord{}).
+		Where("id = ? AND status = ?", id, status).
+		UpdateColumns(map[string]interface{}{"diagnostics": diags, "passes": passes})
+	return result.RowsAffected == 1, result.Error
+}
+
 func (r *PPTRepository) Update(ppt *model.PPTRecord) error {
 	return r.db.Save(ppt).Error
 }
 
+// Delete 删除记录及其历史版本、修复记录和知识库引用
 func (r *PPTRepository) Delete(id uint) error {
-	return r.db.Delete(&model.PPTRecord{}, id).Error
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		for _, dependent := range []interface{}{&model.PPTRevision{}, &model.PPTRepairAttempt{}, &model.PPTKnowledgeRef{}} {
+			if err := tx.Where("ppt_id = ?", id).Delete(dependent).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Delete(&model.PPTRecord{}, id).Error
+	})
 }
 
 func (r *PPTRepository) CreateKnowledgeRef(ref *model.PPTKnowledgeRef) error {
@@ -46,3 +84,59 @@ func (r *PPTRepository) FindKnowledgeRefsByPPTID(pptID uint) ([]model.PPTKnowled
 	err := r.db.Where("ppt_id = ?", pptID).Find(&refs).Error
 	return refs, err
 }
+
+func (r *PPTRepository) CreateRepairAttempt(attempt *model.PPTRepairAttempt) error {
+	return r.db.Create(attempt).Error
+}
+
+func (r *PPTRepository) FindRepairAttemptsByPPTID(pptID uint) ([]model.PPTRepairAttempt, error) {
+	var attempts []model.PPTRepairAttempt
+	err := r.db.Where("ppt_id = ?", pptID).Order("id").Find(&attempts).Error
+	return attempts, err
+}
+
+// CreateRevision 保存新版本，版本号为该 PPT 已有的最大版本号加一。
+// 事务内先锁住 PPT 记录，同一 PPT 的并发保存按顺序分配版本号
+func (r *PPTRepository) CreateRevision(rev *model.PPTRevision) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var ppt model.PPTRecord
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&ppt, rev.PPTID).Error
+		if err != nil {
+			return err
+		}
+
+		var last int
+		err = tx.Model(&model.PPTRevision{}).
+			Where("ppt_id = ?", rev.PPTID).
+			Select("COALESCE(MAX(number), 0)").
+			Scan(&last).Error
+		if err != nil {
+			return err
+		}
+		rev.Number = last + 1
+		return tx.Create(rev).Error
+	})
+}
+
+// FindRevisionsByPPTID 按版本号倒序返回版本列表，不含源码
+func (r *PPTRepository) FindRevisionsByPPTID(pptID uint) ([]model.PPTRevision, error) {
+	var revs []model.PPTRevision
+	err := r.db.Omit("source").Where("ppt_id = ?", pptID).Order("number DESC").Find(&revs).Error
+	return revs, err
+}
+
+func (r *PPTRepository) FindRevision(pptID uint, number int) (*model.PPTRevision, error) {
+	var rev model.PPTRevision
+	err := r.db.Where("ppt_id = ? AND number = ?", pptID, number).First(&rev).Error
+	return &rev, err
+}
+
+// FindPDFPaths 返回所有记录及其历史版本引用的 PDF 路径
+func (r *PPTRepository) FindPDFPaths() ([]string, error) {
+	var paths, revisionPaths []string
+	if err := r.db.Model(&model.PPTRecord{}).Where("pdf_path <> ''").Pluck("pdf_path", &paths).Error; err != nil {
+		return nil, err
+	}
+	err := r.db.Model(&model.PPTRevision{}).Where("pdf_path <> ''").Pluck("pdf_path", &revisionPaths).Error
+	return append(paths, revisionPaths...), err
+}
@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"github.com/qingbingwei/latex_ppt_by_claude/backend/internal/model"
+	"gorm.io/gorm"
+)
+
+type TeamRepository struct {
+	db *gorm.DB
+}
+
+func NewTeamRepository(db *gorm.DB) *TeamRepository {
+	return &TeamRepository{db: db}
+}
+
+// Create 创建团队并把创建者加入为 owner
+func (r *TeamRepository) Create(team *model.Team) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(team).Error; err != nil {
+			return err
+		}
+		return tx.Create(&model.TeamMember{TeamID: team.ID, UserID: team.OwnerID, Role: "owner"}).Error
+	})
+}
+
+func (r *TeamRepository) FindByID(id uint) (*model.Team, error) {
+	var team model.Team
+	err := r.db.First(&team, id).Error
+	return &team, err
+}
+
+// FindByUserID 返回用户所在的团队
+func (r *TeamRepository) FindByUserID(userID uint) ([]model.Team, error) {
+	var teams []model.Team
+	err := r.db.Where("id IN (?)", r.db.Model(&model.TeamMember{}).Select("team_id").Where("user_id = ?", userID)).
+		Order("created_at").Find(&teams).Error
+	return teams, err
+}
+
+func (r *TeamRepository) FindMembers(teamID uint) ([]model.TeamMember, error) {
+	var members []model.TeamMember
+	err := r.db.Where("team_id = ?", teamID).Order("id").Find(&members).Error
+	return members, err
+}
+
+func (r *TeamRepository) IsMember(teamID, userID uint) (bool, error) {
+	var count int64
+	err := r.db.Model(&model.TeamMember{}).Where("team_id = ? AND user_id = ?", teamID, userID).Count(&count).Error
+	return count > 0, err
+}
+
+func (r *TeamRepository) AddMember(member *model.TeamMember) error {
+	return r.db.Create(member).Error
+}
+
+func (r *TeamRepository) RemoveMember(teamID, userID uint) error {
+	return r.db.Where("team_id = ? AND user_id = ?", teamID, userID).Delete(&model.TeamMember{}).Error
+}
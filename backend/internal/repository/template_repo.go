@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"strings"
+
+	"github.com/qingbingwei/latex_ppt_by_claude/backend/internal/model"
+	"gorm.io/gorm"
+)
+
+type TemplateRepository struct {
+	db *gorm.DB
+}
+
+func NewTemplateRepository(db *gorm.DB) *TemplateRepository {
+	return &TemplateRepository{db: db}
+}
+
+// Create 创建模板及其第一个版本
+func (r *TemplateRepository) Create(tmpl *model.Template, version *model.TemplateVersion) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(tmpl).Error; err != nil {
+			return err
+		}
+		version.TemplateID = tmpl.ID
+		return tx.Create(version).Error
+	})
+}
+
+// AddVersion 保存新版本并更新模板的最新版本号
+func (r *TemplateRepository) AddVersion(tmpl *model.Template, version *model.TemplateVersion) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(version).Error; err != nil {
+			return err
+		}
+		tmpl.Version = version.Version
+		return tx.Save(tmpl).Error
+	})
+}
+
+func (r *TemplateRepository) FindByID(id uint) (*model.Template, error) {
+	var tmpl model.Template
+	err := r.db.First(&tmpl, id).Error
+	return &tmpl, err
+}
+
+func (r *TemplateRepository) FindByOwnerAndName(ownerID uint, name string) (*model.Template, error) {
+	var tmpl model.Template
+	err := r.db.Where("owner_id = ? AND name = ?", ownerID, name).First(&tmpl).Error
+	return &tmpl, err
+}
+
+// FindVisible 返回用户自己的模板以及共享给其所在团队的模板
+func (r *TemplateRepository) FindVisible(userID uint) ([]model.Template, error) {
+	var templates []model.Template
+	err := r.visible(userID).Order("name").Order("id").Find(&templates).Error
+	return templates, err
+}
+
+// FindVisibleByName 返回用户可见的同名模板，不同所有者的模板可以同名
+func (r *TemplateRepository) FindVisibleByName(userID uint, name string) ([]model.Template, error) {
+	var templates []model.Template
+	err := r.visible(userID).Where("name = ?", name).Order("id").Find(&templates).Error
+	return templates, err
+}
+
+func (r *TemplateRepository) visible(userID uint) *gorm.DB {
+	teamIDs := r.db.Model(&model.TeamMember{}).Select("team_id").Where("user_id = ?", userID)
+	return r.db.Where("owner_id = ? OR team_id IN (?)", userID, teamIDs)
+}
+
+func (r *TemplateRepository) Update(tmpl *model.Template) error {
+	return r.db.Save(tmpl).Error
+}
+
+func (r *TemplateRepository) FindVersion(templateID uint, version int) (*model.TemplateVersion, error) {
+	var v model.TemplateVersion
+	err := r.db.Where("template_id = ? AND version = ?", templateID, version).First(&v).Error
+	return &v, err
+}
+
+func (r *TemplateRepository) FindVersions(templateID uint) ([]model.TemplateVersion, error) {
+	var versions []model.TemplateVersion
+	err := r.db.Where("template_id = ?", templateID).Order("version DESC").Find(&versions).Error
+	return versions, err
+}
+
+// CountReferences 统计可能使用该模板的 PPT 数：所有者和共享团队成员的记录中，
+// 模板名为 name 或 "name@版本号" 的记录
+func (r *TemplateRepository) CountReferences(tmpl *model.Template) (int64, error) {
+	// 名称中的 _ 在 LIKE 中是通配符，需要转义
+	query := r.db.Model(&model.PPTRecord{}).
+		Where("template = ? OR template LIKE ?", tmpl.Name, strings.ReplaceAll(tmpl.Name, "_", `\_`)+"@%")
+	if tmpl.TeamID != nil {
+		members := r.db.Model(&model.TeamMember{}).Select("user_id").Where("team_id = ?", *tmpl.TeamID)
+		query = query.Where("user_id = ? OR user_id IN (?)", tmpl.OwnerID, members)
+	} else {
+		query = query.Where("user_id = ?", tmpl.OwnerID)
+	}
+	var count int64
+	err := query.Count(&count).Error
+	return count, err
+}
+
+// Delete 删除模板及其全部版本
+func (r *TemplateRepository) Delete(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("template_id = ?", id).Delete(&model.TemplateVersion{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&model.Template{}, id).Error
+	})
+}
@@ -5,83 +5,384 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/qingbingwei/latex_ppt_by_claude/backend/internal/model"
 	"github.com/qingbingwei/latex_ppt_by_claude/backend/pkg/ai"
+	"github.com/qingbingwei/latex_ppt_by_claude/backend/pkg/latex"
+)
+
+// ModelSelection 指定本次生成使用的 Provider 与模型，为空时使用默认值
+type ModelSelection struct {
+	Provider string `json:"provider"`
+	Model    string `json:"model"`
+}
+
+// 生成模式：full 由模型输出完整文档；template 模型只输出 frame，由服务端套用模板；
+// outline 先生成大纲，确认后按章节并行生成 frame 再套用模板；
+// markdown 不经过模型，由用户提供的 Markdown 幻灯片转换而来
+const (
+	GenerationModeFull     = "full"
+	GenerationModeTemplate = "template"
+	GenerationModeOutline  = "outline"
+	GenerationModeMarkdown = "markdown"
 )
 
 type AIService struct {
-	openaiClient  *ai.OpenAIClient
-	claudeClient  *ai.ClaudeClient
-	copilotClient *ai.CopilotClient
+	registry *ai.Registry
 }
 
-func NewAIService(openaiClient *ai.OpenAIClient, claudeClient *ai.ClaudeClient, copilotClient *ai.CopilotClient) *AIService {
+func NewAIService(registry *ai.Registry) *AIService {
 	return &AIService{
-		openaiClient:  openaiClient,
-		claudeClient:  claudeClient,
-		copilotClient: copilotClient,
+		registry: registry,
 	}
 }
 
-func (s *AIService) GenerateLaTeXPPT(ctx context.Context, prompt string, contextChunks []string, useOpenAI bool) (string, error) {
+func (s *AIService) GenerateLaTeXPPT(ctx context.Context, prompt string, contextChunks []string, sel ModelSelection, mode string) (string, error) {
+	provider, err := s.registry.Get(sel.Provider)
+	if err != nil {
+		return "", err
+	}
+
 	// Build enhanced prompt with RAG context
-	enhancedPrompt := s.buildPrompt(prompt, contextChunks)
+	enhancedPrompt := s.buildPrompt(prompt, contextChunks, mode)
+
+	return provider.Generate(ctx, ai.Request{
+		Model:  sel.Model,
+		Prompt: enhancedPrompt,
+	})
+}
 
-	// 优先使用 Copilot
-	if s.copilotClient != nil {
-		return s.copilotClient.GenerateLaTeX(ctx, enhancedPrompt)
+func (s *AIService) StreamGenerateLaTeXPPT(ctx context.Context, prompt string, contextChunks []string, sel ModelSelection, mode string, streamCh chan<- string) error {
+	provider, err := s.registry.Get(sel.Provider)
+	if err != nil {
+		close(streamCh)
+		return err
 	}
 
-	if useOpenAI && s.openaiClient != nil {
-		return s.openaiClient.GenerateLaTeX(ctx, enhancedPrompt)
-	} else if s.claudeClient != nil {
-		return s.claudeClient.GenerateLaTeX(ctx, enhancedPrompt)
+	// Build enhanced prompt with RAG context
+	enhancedPrompt := s.buildPrompt(prompt, contextChunks, mode)
+
+	return provider.Stream(ctx, ai.Request{
+		Model:  sel.Model,
+		Prompt: enhancedPrompt,
+	}, streamCh)
+}
+
+// GenerateOutline 生成 JSON 格式的大纲，由调用方解析
+func (s *AIService) GenerateOutline(ctx context.Context, prompt string, contextChunks []string, sel ModelSelection) (string, error) {
+	provider, err := s.registry.Get(sel.Provider)
+	if err != nil {
+		return "", err
 	}
 
-	return "", fmt.Errorf("no AI client available")
+	return provider.Generate(ctx, ai.Request{
+		Model:        sel.Model,
+		SystemPrompt: "You are an expert in planning presentations. You answer with JSON only.",
+		Prompt:       s.buildOutlinePrompt(prompt, contextChunks),
+		Temperature:  0.5,
+	})
 }
 
-func (s *AIService) StreamGenerateLaTeXPPT(ctx context.Context, prompt string, contextChunks []string, streamCh chan<- string) error {
-	// Build enhanced prompt with RAG context
-	enhancedPrompt := s.buildPrompt(prompt, contextChunks)
+// GenerateSectionFrames 按大纲生成第 index 个章节的 frame，不包含 \section 命令
+func (s *AIService) GenerateSectionFrames(ctx context.Context, prompt string, outline *model.Outline, index int, contextChunks []string, sel ModelSelection) (string, error) {
+	provider, err := s.registry.Get(sel.Provider)
+	if err != nil {
+		return "", err
+	}
 
-	if s.openaiClient != nil {
-		return s.openaiClient.StreamGenerateLaTeX(ctx, enhancedPrompt, streamCh)
+	return provider.Generate(ctx, ai.Request{
+		Model:  sel.Model,
+		Prompt: s.buildSectionPrompt(prompt, outline, index, contextChunks),
+	})
+}
+
+// FrameContext 是重新生成单个 frame 时提供给模型的上下文
+type FrameContext struct {
+	DeckTitle string
+	Section   string
+	Packages  []string // 导言区中的 \usepackage 等命令
+	Previous  string
+	Frame     string
+	Next      string
+}
+
+// RegenerateFrame 按用户指令重写一个 frame，返回模型输出
+func (s *AIService) RegenerateFrame(ctx context.Context, frame FrameContext, instruction string, sel ModelSelection) (string, error) {
+	provider, err := s.registry.Get(sel.Provider)
+	if err != nil {
+		return "", err
 	}
 
-	return fmt.Errorf("streaming only supported with OpenAI")
+	return provider.Generate(ctx, ai.Request{
+		Model:        sel.Model,
+		SystemPrompt: "You are an expert in LaTeX and the Beamer class. You rewrite single slides of existing presentations.",
+		Prompt:       s.buildFramePrompt(frame, instruction),
+	})
 }
 
-func (s *AIService) buildPrompt(userPrompt string, contextChunks []string) string {
-	var prompt strings.Builder
+func (s *AIService) ListProviders() []ai.ProviderInfo {
+	return s.registry.List()
+}
 
-	prompt.WriteString("You are an expert in creating LaTeX Beamer presentations. ")
-	prompt.WriteString("Create a complete, compilable LaTeX Beamer presentation based on the following requirements.\n\n")
+// Resolve 用注册表中的默认值补全 Provider 与模型名称
+func (s *AIService) Resolve(sel ModelSelection) ModelSelection {
+	provider, err := s.registry.Get(sel.Provider)
+	if err != nil {
+		return sel
+	}
+	sel.Provider = provider.Name()
+	if sel.Model == "" {
+		if models := provider.Models(); len(models) > 0 {
+			sel.Model = models[0].Name
+		}
+	}
+	return sel
+}
 
-	if len(contextChunks) > 0 {
-		prompt.WriteString("=== Reference Materials (from knowledge base) ===\n")
-		for i, chunk := range contextChunks {
-			prompt.WriteString(fmt.Sprintf("\n[Context %d]:\n%s\n", i+1, chunk))
+// RepairLaTeX 把编译错误和出错的源码交给模型，返回修复后的完整文档。engine 是报错时使用的 TeX 引擎
+func (s *AIService) RepairLaTeX(ctx context.Context, source, engine string, diags []latex.Diagnostic, sel ModelSelection) (string, error) {
+	provider, err := s.registry.Get(sel.Provider)
+	if err != nil {
+		return "", err
+	}
+
+	return provider.Generate(ctx, ai.Request{
+		Model:        sel.Model,
+		SystemPrompt: "You are an expert in LaTeX and the Beamer class. You fix compilation errors in LaTeX documents with minimal changes.",
+		Prompt:       s.buildRepairPrompt(source, engine, diags),
+		Temperature:  0.2,
+	})
+}
+
+func (s *AIService) buildRepairPrompt(source, engine string, diags []latex.Diagnostic) string {
+	var prompt strings.Builder
+	lines := strings.Split(source, "\n")
+
+	if engine == "" {
+		engine = latex.DefaultEngine
+	}
+	prompt.WriteString(fmt.Sprintf("The following LaTeX Beamer document fails to compile with %s.\n\n", engine))
+	prompt.WriteString("=== Compilation Errors ===\n")
+	for i, d := range diags {
+		if d.Line > 0 {
+			prompt.WriteString(fmt.Sprintf("\n[Error %d] line %d: %s\n", i+1, d.Line, d.Message))
+		} else {
+			prompt.WriteString(fmt.Sprintf("\n[Error %d] %s\n", i+1, d.Message))
+		}
+		if d.Snippet != "" {
+			prompt.WriteString(fmt.Sprintf("Near: %s\n", d.Snippet))
+		}
+		// 附上出错行附近的源码，带行号
+		if d.Line > 0 && d.Line <= len(lines) {
+			start := max(d.Line-3, 1)
+			end := min(d.Line+2, len(lines))
+			for n := start; n <= end; n++ {
+				marker := "  "
+				if n == d.Line {
+					marker = "> "
+				}
+				prompt.WriteString(fmt.Sprintf("%s%4d | %s\n", marker, n, lines[n-1]))
+			}
 		}
-		prompt.WriteString("\n=== End of Reference Materials ===\n\n")
 	}
+	prompt.WriteString("\n=== End of Compilation Errors ===\n\n")
+
+	prompt.WriteString("=== Full Source ===\n")
+	prompt.WriteString(source)
+	prompt.WriteString("\n=== End of Full Source ===\n\n")
+
+	prompt.WriteString("Guidelines:\n")
+	prompt.WriteString("1. Fix every error listed above\n")
+	prompt.WriteString("2. Keep the content, structure and theme unchanged apart from the fixes\n")
+	prompt.WriteString("3. Do not use \\write18, \\input of absolute paths or external files that may not exist\n")
+	prompt.WriteString("4. Return the complete corrected document, not a diff\n")
+	prompt.WriteString("5. Wrap the LaTeX code in ```latex code blocks\n")
+
+	return prompt.String()
+}
+
+func (s *AIService) buildOutlinePrompt(userPrompt string, contextChunks []string) string {
+	var prompt strings.Builder
+
+	prompt.WriteString("Plan the outline of a LaTeX Beamer presentation based on the following requirements.\n\n")
+	writeReferences(&prompt, contextChunks)
 
 	prompt.WriteString("Requirements:\n")
 	prompt.WriteString(userPrompt)
 	prompt.WriteString("\n\n")
 
+	prompt.WriteString("Respond with a single JSON object in exactly this format:\n")
+	prompt.WriteString(`{"sections": [{"title": "Section title", "frames": [{"title": "Frame title", "points": ["Key point", "Key point"]}]}]}`)
+	prompt.WriteString("\n\n")
+
+	guidelines := []string{
+		"Use 3-6 sections with 2-5 frames each",
+		"Give each frame 3-5 short key points",
+		"Do not include a title page, table of contents or closing \"thank you\" frame",
+		"Write titles and points in the language of the requirements, as plain text without LaTeX commands",
+		"Output only the JSON object, without explanations or code blocks",
+	}
+	if len(contextChunks) > 0 {
+		guidelines = append(guidelines, "Base the key points on the reference materials provided")
+	}
 	prompt.WriteString("Guidelines:\n")
-	prompt.WriteString("1. Use \\documentclass[aspectratio=169,11pt]{beamer}\n")
-	prompt.WriteString("2. Include Chinese support with \\usepackage[UTF8]{ctex}\n")
-	prompt.WriteString("3. Use appropriate beamer theme (e.g., Madrid)\n")
-	prompt.WriteString("4. Include title page and table of contents\n")
-	prompt.WriteString("5. Organize content into sections and frames\n")
-	prompt.WriteString("6. Use itemize/enumerate for lists\n")
-	prompt.WriteString("7. Keep each frame concise (3-6 bullet points)\n")
-	prompt.WriteString("8. The output must be complete and compilable LaTeX code\n")
-	prompt.WriteString("9. Wrap the LaTeX code in ```latex code blocks\n")
+	for i, g := range guidelines {
+		prompt.WriteString(fmt.Sprintf("%d. %s\n", i+1, g))
+	}
+
+	return prompt.String()
+}
+
+func (s *AIService) buildSectionPrompt(userPrompt string, outline *model.Outline, index int, contextChunks []string) string {
+	var prompt strings.Builder
+	section := outline.Sections[index]
+
+	prompt.WriteString("You are an expert in creating LaTeX Beamer presentations. ")
+	prompt.WriteString("Write the frames of one section of a presentation; the other sections are written separately ")
+	prompt.WriteString("and the preamble, title page and table of contents are provided by a fixed template.\n\n")
+	writeReferences(&prompt, contextChunks)
 
+	prompt.WriteString("Presentation requirements:\n")
+	prompt.WriteString(userPrompt)
+	prompt.WriteString("\n\n")
+
+	prompt.WriteString("Outline of the whole presentation:\n")
+	for i, sec := range outline.Sections {
+		prompt.WriteString(fmt.Sprintf("%d. %s\n", i+1, sec.Title))
+		for _, frame := range sec.Frames {
+			prompt.WriteString(fmt.Sprintf("   - %s\n", frame.Title))
+		}
+	}
+
+	prompt.WriteString(fmt.Sprintf("\nWrite section %d \"%s\" with the following frames:\n", index+1, section.Title))
+	for i, frame := range section.Frames {
+		prompt.WriteString(fmt.Sprintf("\nFrame %d: %s\n", i+1, frame.Title))
+		for _, point := range frame.Points {
+			prompt.WriteString(fmt.Sprintf("- %s\n", point))
+		}
+	}
+	prompt.WriteString("\n")
+
+	guidelines := []string{
+		"Output only frame environments, one for each frame above and in the same order; the \\section command is added automatically",
+		"Do not write \\documentclass, \\usepackage, \\section, \\begin{document}, a title page or a table of contents",
+		"Expand the key points into concise content (3-6 bullet points), using formulas or tables where helpful",
+		"Escape LaTeX special characters such as %, &, # and _ in text",
+		"Only use commands from amsmath, amssymb, graphicx, hyperref and booktabs; Chinese text is supported",
+		"Wrap the LaTeX code in ```latex code blocks",
+	}
+	if len(contextChunks) > 0 {
+		guidelines = append(guidelines, "Incorporate relevant information from the reference materials provided")
+	}
+	prompt.WriteString("Guidelines:\n")
+	for i, g := range guidelines {
+		prompt.WriteString(fmt.Sprintf("%d. %s\n", i+1, g))
+	}
+
+	return prompt.String()
+}
+
+func (s *AIService) buildFramePrompt(frame FrameContext, instruction string) string {
+	var prompt strings.Builder
+
+	prompt.WriteString("Rewrite one frame of an existing LaTeX Beamer presentation according to the instruction below.\n\n")
+	if frame.DeckTitle != "" {
+		prompt.WriteString(fmt.Sprintf("Presentation: %s\n", frame.DeckTitle))
+	}
+	if frame.Section != "" {
+		prompt.WriteString(fmt.Sprintf("Section: %s\n", frame.Section))
+	}
+	if len(frame.Packages) > 0 {
+		prompt.WriteString("Preamble:\n" + strings.Join(frame.Packages, "\n") + "\n")
+	}
+	prompt.WriteString("\n")
+
+	if frame.Previous != "" {
+		prompt.WriteString("=== Previous Frame ===\n" + frame.Previous + "\n=== End of Previous Frame ===\n\n")
+	}
+	prompt.WriteString("=== Frame to Rewrite ===\n" + frame.Frame + "\n=== End of Frame to Rewrite ===\n\n")
+	if frame.Next != "" {
+		prompt.WriteString("=== Next Frame ===\n" + frame.Next + "\n=== End of Next Frame ===\n\n")
+	}
+
+	prompt.WriteString("Instruction:\n")
+	prompt.WriteString(instruction)
+	prompt.WriteString("\n\n")
+
+	guidelines := []string{
+		"Return exactly one frame environment that replaces the frame to rewrite; do not repeat the surrounding frames",
+		"Keep the language, style and level of detail consistent with the surrounding frames",
+		"Only use commands from the packages loaded in the preamble",
+		"Add the [fragile] option if the frame contains verbatim or lstlisting",
+		"Wrap the LaTeX code in ```latex code blocks",
+	}
+	prompt.WriteString("Guidelines:\n")
+	for i, g := range guidelines {
+		prompt.WriteString(fmt.Sprintf("%d. %s\n", i+1, g))
+	}
+
+	return prompt.String()
+}
+
+// writeReferences 写入知识库检索到的参考资料
+func writeReferences(prompt *strings.Builder, contextChunks []string) {
+	if len(contextChunks) == 0 {
+		return
+	}
+	prompt.WriteString("=== Reference Materials (from knowledge base) ===\n")
+	for i, chunk := range contextChunks {
+		prompt.WriteString(fmt.Sprintf("\n[Context %d]:\n%s\n", i+1, chunk))
+	}
+	prompt.WriteString("\n=== End of Reference Materials ===\n\n")
+}
+
+func (s *AIService) buildPrompt(userPrompt string, contextChunks []string, mode string) string {
+	var prompt strings.Builder
+
+	prompt.WriteString("You are an expert in creating LaTeX Beamer presentations. ")
+	if mode == GenerationModeTemplate {
+		prompt.WriteString("Write the slide content of a LaTeX Beamer presentation based on the following requirements. ")
+		prompt.WriteString("The preamble, title page and table of contents are provided by a fixed template.\n\n")
+	} else {
+		prompt.WriteString("Create a complete, compilable LaTeX Beamer presentation based on the following requirements.\n\n")
+	}
+
+	writeReferences(&prompt, contextChunks)
+
+	prompt.WriteString("Requirements:\n")
+	prompt.WriteString(userPrompt)
+	prompt.WriteString("\n\n")
+
+	var guidelines []string
+	if mode == GenerationModeTemplate {
+		guidelines = []string{
+			"Output only \\section commands and frame environments, they are inserted between \\begin{document} and \\end{document}",
+			"Do not write \\documentclass, \\usepackage, theme settings, \\title, \\author, \\begin{document} or \\end{document}",
+			"Do not create a title page or table of contents frame",
+			"Only use commands from amsmath, amssymb, graphicx, hyperref and booktabs; Chinese text is supported",
+			"Use itemize/enumerate for lists",
+			"Keep each frame concise (3-6 bullet points)",
+			"Wrap the LaTeX code in ```latex code blocks",
+		}
+	} else {
+		guidelines = []string{
+			"Use \\documentclass[aspectratio=169,11pt]{beamer}",
+			"Include Chinese support with \\usepackage[UTF8]{ctex}",
+			"Use appropriate beamer theme (e.g., Madrid)",
+			"Include title page and table of contents",
+			"Organize content into sections and frames",
+			"Use itemize/enumerate for lists",
+			"Keep each frame concise (3-6 bullet points)",
+			"The output must be complete and compilable LaTeX code",
+			"Wrap the LaTeX code in ```latex code blocks",
+		}
+	}
 	if len(contextChunks) > 0 {
-		prompt.WriteString("10. Incorporate relevant information from the reference materials provided\n")
+		guidelines = append(guidelines, "Incorporate relevant information from the reference materials provided")
+	}
+
+	prompt.WriteString("Guidelines:\n")
+	for i, g := range guidelines {
+		prompt.WriteString(fmt.Sprintf("%d. %s\n", i+1, g))
 	}
 
 	return prompt.String()
@@ -0,0 +1,166 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"mime"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/qingbingwei/latex_ppt_by_claude/backend/internal/model"
+	"github.com/qingbingwei/latex_ppt_by_claude/backend/pkg/export"
+	"github.com/qingbingwei/latex_ppt_by_claude/backend/pkg/latex"
+)
+
+const (
+	ExportFormatPPTX     = "pptx"
+	ExportFormatHTML     = "html"
+	ExportFormatMarkdown = "markdown"
+)
+
+var (
+	ErrUnsupportedFormat  = errors.New("unsupported export format")
+	ErrLaTeXNotAvailable  = errors.New("LaTeX content not available")
+	exportFilenamePattern = regexp.MustCompile(`[\\/:*?"<>|\x00-\x1f]+`)
+)
+
+// 无法转换的 frame 渲染为图片时的分辨率
+const exportDPI = 160
+
+// ExportFile 是导出结果
+type ExportFile struct {
+	Filename    string
+	ContentType string
+	Data        []byte
+}
+
+// ExportService 把 PPT 的 LaTeX 源码转换为其他格式
+type ExportService struct {
+	templateService *TemplateService
+	compiler        *latex.Compiler
+	rasterizer      *latex.Rasterizer
+	// katex 是 HTML 导出打包的 KaTeX，目录不存在时为 nil
+	katex fs.FS
+}
+
+func NewExportService(templateService *TemplateService, compiler *latex.Compiler, rasterizer *latex.Rasterizer, katexDir string) *ExportService {
+	s := &ExportService{
+		templateService: templateService,
+		compiler:        compiler,
+		rasterizer:      rasterizer,
+	}
+	if _, err := os.Stat(filepath.Join(katexDir, "katex.min.js")); err == nil {
+		s.katex = os.DirFS(katexDir)
+	} else {
+		log.Printf("Warning: KaTeX not found in %s, formulas in HTML exports will not be rendered", katexDir)
+	}
+	return s
+}
+
+// Export 按 format 导出 PPT
+func (s *ExportService) Export(ctx context.Context, ppt *model.PPTRecord, format string) (*ExportFile, error) {
+	if strings.TrimSpace(ppt.LatexContent) == "" {
+		return nil, ErrLaTeXNotAvailable
+	}
+
+	deck := deckOf(ppt)
+	if format == ExportFormatMarkdown {
+		return &ExportFile{
+			Filename:    exportFilename(ppt, "md"),
+			ContentType: "text/markdown; charset=utf-8",
+			Data:        []byte(latex.ToMarkdown(deck)),
+		}, nil
+	}
+
+	tmpl := s.template(ppt)
+	render := func(index int, frame latex.Frame) ([]byte, error) {
+		png, err := s.renderFrame(ctx, ppt, deck, tmpl, index, frame)
+		if err != nil {
+			log.Printf("Export: PPT %d frame %d cannot be rendered, converting it natively: %v", ppt.ID, index+1, err)
+		}
+		return png, err
+	}
+
+	switch format {
+	case ExportFormatPPTX:
+		data, err := export.PPTX(deck, export.PPTXOptions{Assets: tmpl.Assets, Render: render})
+		if err != nil {
+			return nil, err
+		}
+		return &ExportFile{
+			Filename:    exportFilename(ppt, "pptx"),
+			ContentType: "application/vnd.openxmlformats-officedocument.presentationml.presentation",
+			Data:        data,
+		}, nil
+	case ExportFormatHTML:
+		data, err := export.HTML(deck, export.HTMLOptions{Assets: tmpl.Assets, Render: render, KaTeX: s.katex})
+		if err != nil {
+			return nil, err
+		}
+		return &ExportFile{
+			Filename:    exportFilename(ppt, "zip"),
+			ContentType: "application/zip",
+			Data:        data,
+		}, nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedFormat, format)
+	}
+}
+
+// template 返回生成时使用的模板版本，模板已删除时只缺少资源文件，不影响导出
+func (s *ExportService) template(ppt *model.PPTRecord) *ResolvedTemplate {
+	tmpl, err := s.templateService.ResolveFor(ppt)
+	if err != nil {
+		log.Printf("Export: template %q version %d of PPT %d unavailable: %v", ppt.Template, ppt.TemplateVersion, ppt.ID, err)
+		return &ResolvedTemplate{Name: ppt.Template}
+	}
+	return tmpl
+}
+
+// renderFrame 用文档导言区单独编译一个 frame，返回最后一页（叠加动画的最终状态）的 PNG
+func (s *ExportService) renderFrame(ctx context.Context, ppt *model.PPTRecord, deck *latex.Deck, tmpl *ResolvedTemplate, index int, frame latex.Frame) ([]byte, error) {
+	source := deck.Preamble + "\\begin{document}\n" + frame.Source + "\n\\end{document}\n"
+	// 同一 PPT 可能同时有多个导出任务，文件名带上时间戳避免互相覆盖或删除
+	filename := fmt.Sprintf("export_%d_%d_%d.pdf", ppt.ID, index+1, time.Now().UnixNano())
+	result, err := s.compiler.CompileWithOptions(ctx, source, filename, latex.Options{
+		Engine:          ppt.Engine,
+		PreferredEngine: tmpl.Engine,
+		Assets:          tmpl.Assets,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(result.PDFPath)
+
+	dir, err := os.MkdirTemp("", "export-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	pages, err := s.rasterizer.RenderPages(ctx, result.PDFPath, dir, exportDPI)
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadFile(pages[len(pages)-1])
+}
+
+// exportFilename 用标题生成下载文件名
+func exportFilename(ppt *model.PPTRecord, ext string) string {
+	name := strings.TrimSpace(exportFilenamePattern.ReplaceAllString(ppt.Title, "_"))
+	if name == "" {
+		name = fmt.Sprintf("ppt_%d", ppt.ID)
+	}
+	return name + "." + ext
+}
+
+// ContentDisposition 返回附件下载头，非 ASCII 文件名按 RFC 2231 编码
+func (f *ExportFile) ContentDisposition() string {
+	return mime.FormatMediaType("attachment", map[string]string{"filename": f.Filename})
+}
@@ -0,0 +1,32 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/qingbingwei/latex_ppt_by_claude/backend/internal/model"
+)
+
+func TestExportFilename(t *testing.T) {
+	tests := []struct {
+		name        string
+		title       string
+		want        string
+		disposition string
+	}{
+		{"plain", "Quarterly Review", "Quarterly Review.pptx", `attachment; filename="Quarterly Review.pptx"`},
+		{"unsafe characters", `a/b:c*?"d`, "a_b_c_d.pptx", `attachment; filename=a_b_c_d.pptx`},
+		{"empty title", "  ", "ppt_7.pptx", `attachment; filename=ppt_7.pptx`},
+		{"non-ascii", "演示", "演示.pptx", `attachment; filename*=utf-8''%E6%BC%94%E7%A4%BA.pptx`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := exportFilename(&model.PPTRecord{ID: 7, Title: tt.title}, "pptx")
+			if got != tt.want {
+				t.Errorf("exportFilename() = %q, want %q", got, tt.want)
+			}
+			if d := (&ExportFile{Filename: got}).ContentDisposition(); d != tt.disposition {
+				t.Errorf("ContentDisposition() = %q, want %q", d, tt.disposition)
+			}
+		})
+	}
+}
@@ -0,0 +1,185 @@
+package service
+
+import (
+	"context"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/qingbingwei/latex_ppt_by_claude/backend/internal/repository"
+	"github.com/qingbingwei/latex_ppt_by_claude/backend/pkg/latex"
+)
+
+// JanitorService 定期清理 OutputDir：遗留的编译临时目录、过期的编译缓存，
+// 以及没有任何 PPT 记录引用的 PDF 和逐页渲染结果
+type JanitorService struct {
+	pptRepo   *repository.PPTRepository
+	compiler  *latex.Compiler
+	outputDir string
+	interval  time.Duration
+	tempTTL   time.Duration
+	cacheTTL  time.Duration
+}
+
+func NewJanitorService(
+	pptRepo *repository.PPTRepository,
+	compiler *latex.Compiler,
+	outputDir string,
+	interval time.Duration,
+	tempTTL time.Duration,
+	cacheTTL time.Duration,
+) *JanitorService {
+	return &JanitorService{
+		pptRepo:   pptRepo,
+		compiler:  compiler,
+		outputDir: outputDir,
+		interval:  interval,
+		tempTTL:   tempTTL,
+		cacheTTL:  cacheTTL,
+	}
+}
+
+// Start 立即执行一次清理，之后每隔 interval 执行，interval 为 0 时不启动
+func (s *JanitorService) Start(ctx context.Context) {
+	if s.interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		for {
+			s.Sweep()
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Sweep 执行一次清理
+func (s *JanitorService) Sweep() {
+	stats, err := s.compiler.Sweep(s.tempTTL, s.cacheTTL)
+	if err != nil {
+		log.Printf("Janitor: failed to sweep compile dirs: %v", err)
+	}
+
+	orphans, err := s.removeOrphanPDFs()
+	if err != nil {
+		log.Printf("Janitor: failed to remove orphaned PDFs: %v", err)
+	}
+
+	slides, err := s.removeOrphanSlides()
+	if err != nil {
+		log.Printf("Janitor: failed to remove orphaned slides: %v", err)
+	}
+
+	if stats.TempDirs > 0 || stats.CacheEntries > 0 || orphans > 0 || slides > 0 {
+		log.Printf("Janitor: removed %d temp dirs, %d cache entries, %d orphaned PDFs, %d slide dirs",
+			stats.TempDirs, stats.CacheEntries, orphans, slides)
+	}
+}
+
+// removeOrphanPDFs 删除 OutputDir 下未被引用的 PDF。
+// 刚编译完成的 PDF 会在写回记录前短暂处于未引用状态，因此只删除超过 tempTTL 的文件
+func (s *JanitorService) removeOrphanPDFs() (int, error) {
+	if s.tempTTL <= 0 {
+		return 0, nil
+	}
+
+	entries, err := os.ReadDir(s.outputDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	referenced, err := s.referencedPDFs()
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	now := time.Now()
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.EqualFold(filepath.Ext(entry.Name()), ".pdf") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || now.Sub(info.ModTime()) <= s.tempTTL {
+			continue
+		}
+
+		path, err := filepath.Abs(filepath.Join(s.outputDir, entry.Name()))
+		if err != nil || referenced[path] {
+			continue
+		}
+		if err := os.Remove(path); err == nil {
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+// removeOrphanSlides 删除对应 PDF 已不存在或不再被引用的逐页渲染目录，以及遗留的渲染临时目录
+func (s *JanitorService) removeOrphanSlides() (int, error) {
+	if s.tempTTL <= 0 {
+		return 0, nil
+	}
+
+	dir := slidesDir(s.outputDir)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	referenced, err := s.referencedPDFs()
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	now := time.Now()
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || now.Sub(info.ModTime()) <= s.tempTTL {
+			continue
+		}
+
+		pdf, err := filepath.Abs(filepath.Join(s.outputDir, entry.Name()+".pdf"))
+		if err != nil || referenced[pdf] {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(dir, entry.Name())); err == nil {
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+// referencedPDFs 返回 PPT 记录引用的 PDF 绝对路径集合
+func (s *JanitorService) referencedPDFs() (map[string]bool, error) {
+	paths, err := s.pptRepo.FindPDFPaths()
+	if err != nil {
+		return nil, err
+	}
+	referenced := make(map[string]bool, len(paths))
+	for _, p := range paths {
+		if abs, err := filepath.Abs(p); err == nil {
+			referenced[abs] = true
+		}
+	}
+	return referenced, nil
+}
@@ -0,0 +1,182 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/qingbingwei/latex_ppt_by_claude/backend/internal/model"
+	"github.com/qingbingwei/latex_ppt_by_claude/backend/internal/repository"
+)
+
+var ErrQueueFull = errors.New("generation queue is full")
+
+// JobService 以有界并发的 worker 池异步执行 PPT 生成任务。
+// 任务状态持久化在 PPTRecord 上，内存队列只保存记录 ID。
+type JobService struct {
+	pptService  *PPTService
+	pptRepo     *repository.PPTRepository
+	queue       chan uint
+	workers     int
+	maxAttempts int
+	timeout     time.Duration
+	recovery    string
+	wg          sync.WaitGroup
+}
+
+func NewJobService(
+	pptService *PPTService,
+	pptRepo *repository.PPTRepository,
+	workers int,
+	queueSize int,
+	maxAttempts int,
+	timeout time.Duration,
+	recovery string,
+) *JobService {
+	if workers < 1 {
+		workers = 1
+	}
+	if queueSize < 1 {
+		queueSize = 1
+	}
+	return &JobService{
+		pptService:  pptService,
+		pptRepo:     pptRepo,
+		queue:       make(chan uint, queueSize),
+		workers:     workers,
+		maxAttempts: maxAttempts,
+		timeout:     timeout,
+		recovery:    recovery,
+	}
+}
+
+// Start 启动 worker 并恢复上次退出时未完成的任务，ctx 取消后 worker 退出
+func (s *JobService) Start(ctx context.Context) {
+	for i := 0; i < s.workers; i++ {
+		s.wg.Add(1)
+		go s.worker(ctx, i)
+	}
+
+	// 在开始接收新请求前查询残留任务，入队在后台进行以免阻塞启动
+	pending, err := s.recoverable()
+	if err != nil {
+		log.Printf("Failed to recover generation jobs: %v", err)
+	}
+	go func() {
+		for _, id := range pending {
+			select {
+			case s.queue <- id:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	log.Printf("Generation job service started with %d workers", s.workers)
+}
+
+// Wait 等待所有 worker 退出
+func (s *JobService) Wait() {
+	s.wg.Wait()
+}
+
+// Submit 创建 PPT 记录并将生成任务入队
+func (s *JobService) Submit(userID uint, params GenerateParams) (*model.PPTRecord, error) {
+	ppt, err := s.pptService.CreatePending(userID, params)
+	if err != nil {
+		return nil, err
+	}
+
+	select {
+	case s.queue <- ppt.ID:
+		return ppt, nil
+	default:
+		s.pptService.markFailed(ppt, ErrQueueFull.Error())
+		return ppt, ErrQueueFull
+	}
+}
+
+// SubmitOutline 把 userID 按已确认大纲生成的任务入队
+func (s *JobService) SubmitOutline(userID, pptID uint) (*model.PPTRecord, error) {
+	ppt, err := s.pptService.QueueOutline(userID, pptID)
+	if err != nil {
+		return nil, err
+	}
+
+	select {
+	case s.queue <- ppt.ID:
+		return ppt, nil
+	default:
+		s.pptService.markFailed(ppt, ErrQueueFull.Error())
+		return ppt, ErrQueueFull
+	}
+}
+
+func (s *JobService) worker(ctx context.Context, n int) {
+	defer s.wg.Done()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case id := <-s.queue:
+			s.run(ctx, n, id)
+		}
+	}
+}
+
+func (s *JobService) run(ctx context.Context, n int, id uint) {
+	jobCtx := ctx
+	if s.timeout > 0 {
+		var cancel context.CancelFunc
+		jobCtx, cancel = context.WithTimeout(ctx, s.timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	log.Printf("Worker %d: starting generation job %d", n, id)
+	if err := s.pptService.RunJob(jobCtx, id); err != nil {
+		log.Printf("Worker %d: generation job %d failed after %v: %v", n, id, time.Since(start), err)
+		return
+	}
+	log.Printf("Worker %d: generation job %d finished in %v", n, id, time.Since(start))
+}
+
+// recoverable 处理进程退出时残留的任务，返回需要重新入队的记录 ID：
+// pending 直接重新入队，generating 按配置重试或标记失败
+func (s *JobService) recoverable() ([]uint, error) {
+	ppts, err := s.pptRepo.FindByStatus("pending", "generating")
+	if err != nil {
+		return nil, err
+	}
+	if len(ppts) == 0 {
+		return nil, nil
+	}
+
+	log.Printf("Recovering %d unfinished generation jobs", len(ppts))
+	var ids []uint
+	for i := range ppts {
+		ppt := &ppts[i]
+
+		if ppt.Status == "generating" {
+			if s.recovery != "resume" {
+				s.pptService.markFailed(ppt, "Generation interrupted by server restart")
+				continue
+			}
+			if s.maxAttempts > 0 && ppt.Attempts >= s.maxAttempts {
+				s.pptService.markFailed(ppt, "Generation interrupted too many times")
+				continue
+			}
+			ppt.Status = "pending"
+			if err := s.pptRepo.Update(ppt); err != nil {
+				log.Printf("Failed to reset job %d: %v", ppt.ID, err)
+				continue
+			}
+		}
+
+		ids = append(ids, ppt.ID)
+	}
+	return ids, nil
+}
@@ -2,6 +2,8 @@ package service
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
 	"os"
 	"path/filepath"
@@ -11,19 +13,22 @@ import (
 	"github.com/qingbingwei/latex_ppt_by_claude/backend/pkg/embedding"
 	"github.com/qingbingwei/latex_ppt_by_claude/backend/pkg/parser"
 	"github.com/qingbingwei/latex_ppt_by_claude/backend/pkg/vectordb"
+	"gorm.io/gorm"
 )
 
+var ErrDocumentNotFound = errors.New("document not found")
+
 type KnowledgeService struct {
 	docRepo         *repository.DocumentRepository
 	embeddingClient *embedding.OpenAIEmbedding
-	vectorDB        *vectordb.MilvusClient
+	vectorDB        vectordb.VectorStore
 	uploadDir       string
 }
 
 func NewKnowledgeService(
 	docRepo *repository.DocumentRepository,
 	embeddingClient *embedding.OpenAIEmbedding,
-	vectorDB *vectordb.MilvusClient,
+	vectorDB vectordb.VectorStore,
 	uploadDir string,
 ) *KnowledgeService {
 	return &KnowledgeService{
@@ -91,10 +96,10 @@ func (s *KnowledgeService) ProcessDocument(ctx context.Context, doc *model.Docum
 
 		log.Printf("Generated embedding for chunk %d, vector length: %d", i, len(emb))
 
-		// Store in Milvus
-		vectorID, err := s.vectorDB.Insert(context.Background(), int64(chunk.ID), int64(doc.ID), chunkText, emb)
+		// Store in vector store
+		vectorID, err := s.vectorDB.Insert(context.Background(), int64(chunk.ID), int64(doc.ID), int64(doc.UserID), chunkText, emb)
 		if err != nil {
-			log.Printf("Failed to insert chunk %d into Milvus: %v", i, err)
+			log.Printf("Failed to insert chunk %d into vector store: %v", i, err)
 			continue
 		}
 
@@ -119,31 +124,64 @@ func (s *KnowledgeService) SaveUploadedFile(file []byte, filename string) (strin
 	return filePath, os.WriteFile(filePath, file, 0644)
 }
 
-func (s *KnowledgeService) SearchSimilarChunks(ctx context.Context, query string, topK int) ([]vectordb.SearchResult, error) {
+// SearchSimilarChunks 在 userID 的文档中检索与 query 相近的分块。
+// documentIDs 非空时只检索其中属于该用户的文档，为空时检索该用户的全部文档
+func (s *KnowledgeService) SearchSimilarChunks(ctx context.Context, userID uint, query string, topK int, documentIDs []uint) ([]vectordb.SearchResult, error) {
+	owned, err := s.docRepo.FindIDsByUserID(userID, documentIDs)
+	if err != nil {
+		return nil, err
+	}
+	if len(owned) == 0 {
+		return nil, nil
+	}
+
 	// Generate query embedding
 	emb, err := s.embeddingClient.GenerateEmbedding(ctx, query)
 	if err != nil {
 		return nil, err
 	}
 
-	// Search in Milvus
-	return s.vectorDB.Search(ctx, emb, topK)
+	// Search in vector store
+	filter := vectordb.Filter{UserID: int64(userID)}
+	for _, id := range owned {
+		filter.DocumentIDs = append(filter.DocumentIDs, int64(id))
+	}
+	return s.vectorDB.Search(ctx, emb, topK, filter)
+}
+
+// OwnedDocumentIDs 返回 ids 中属于 userID 的文档 ID，ids 为空时返回空
+func (s *KnowledgeService) OwnedDocumentIDs(userID uint, ids []uint) ([]uint, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	return s.docRepo.FindIDsByUserID(userID, ids)
 }
 
 func (s *KnowledgeService) GetDocumentsByUser(userID uint) ([]model.Document, error) {
 	return s.docRepo.FindByUserID(userID)
 }
 
-func (s *KnowledgeService) GetDocument(id uint) (*model.Document, error) {
-	return s.docRepo.FindByID(id)
+// GetDocument 返回属于 userID 的文档，文档不存在或属于其他用户时都返回 ErrDocumentNotFound
+func (s *KnowledgeService) GetDocument(userID, id uint) (*model.Document, error) {
+	doc, err := s.docRepo.FindByIDAndUserID(id, userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrDocumentNotFound
+	}
+	return doc, err
 }
 
-func (s *KnowledgeService) DeleteDocument(id uint) error {
-	doc, err := s.docRepo.FindByID(id)
+// DeleteDocument 删除文档的向量、记录和上传的文件。向量删除失败（包括向量库不可用）时
+// 保留记录并返回错误，避免向量残留在库中无法再被清理
+func (s *KnowledgeService) DeleteDocument(ctx context.Context, userID, id uint) error {
+	doc, err := s.GetDocument(userID, id)
 	if err != nil {
 		return err
 	}
 
+	if err := s.vectorDB.Delete(ctx, int64(doc.ID)); err != nil {
+		return fmt.Errorf("delete vectors of document %d: %w", doc.ID, err)
+	}
+
 	// Delete file
 	if doc.FilePath != "" {
 		os.Remove(doc.FilePath)
@@ -0,0 +1,85 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/qingbingwei/latex_ppt_by_claude/backend/internal/model"
+	"github.com/qingbingwei/latex_ppt_by_claude/backend/pkg/vectordb"
+)
+
+func TestDeleteDocument(t *testing.T) {
+	tests := []struct {
+		name    string
+		store   func(t *testing.T) vectordb.VectorStore
+		wantErr error
+	}{
+		{
+			name: "vectors deleted",
+			store: func(t *testing.T) vectordb.VectorStore {
+				store := vectordb.NewLocalStore(filepath.Join(t.TempDir(), "vectors.jsonl"))
+				if err := store.Init(context.Background()); err != nil {
+					t.Fatal(err)
+				}
+				t.Cleanup(store.Close)
+				return store
+			},
+		},
+		{
+			name: "vector store unavailable",
+			store: func(t *testing.T) vectordb.VectorStore {
+				return vectordb.Unavailable(errors.New("test"))
+			},
+			wantErr: vectordb.ErrUnavailable,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := tt.store(t)
+			s := newTestServices(t, store)
+			ctx := context.Background()
+
+			path := filepath.Join(t.TempDir(), "notes.txt")
+			if err := os.WriteFile(path, []byte("notes"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			doc := &model.Document{UserID: testUserID, Filename: "notes.txt", FileType: "txt", FilePath: path, Status: "completed"}
+			if err := s.knowledge.CreateDocument(doc); err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantErr == nil {
+				if _, err := store.Insert(ctx, 1, int64(doc.ID), int64(testUserID), "chunk", []float32{1}); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err := s.knowledge.DeleteDocument(ctx, testUserID, doc.ID)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("DeleteDocument() error = %v, want %v", err, tt.wantErr)
+			}
+
+			_, getErr := s.knowledge.GetDocument(testUserID, doc.ID)
+			_, statErr := os.Stat(path)
+			if tt.wantErr != nil {
+				// 向量没有删除时文档和文件都要保留，之后可以重试
+				if getErr != nil || statErr != nil {
+					t.Errorf("document or file removed after a failed delete: %v, %v", getErr, statErr)
+				}
+				return
+			}
+			if !errors.Is(getErr, ErrDocumentNotFound) || !os.IsNotExist(statErr) {
+				t.Errorf("document or file kept after delete: %v, %v", getErr, statErr)
+			}
+			results, err := store.Search(ctx, []float32{1}, 10, vectordb.Filter{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(results) != 0 {
+				t.Errorf("vectors left behind: %+v", results)
+			}
+		})
+	}
+}
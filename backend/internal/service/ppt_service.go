@@ -2,95 +2,520 @@ package service
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
+	"os"
 	"regexp"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/qingbingwei/latex_ppt_by_claude/backend/internal/model"
 	"github.com/qingbingwei/latex_ppt_by_claude/backend/internal/repository"
+	"github.com/qingbingwei/latex_ppt_by_claude/backend/pkg/ai"
+	"github.com/qingbingwei/latex_ppt_by_claude/backend/pkg/diff"
 	"github.com/qingbingwei/latex_ppt_by_claude/backend/pkg/latex"
+	"gorm.io/gorm"
 )
 
+var (
+	ErrMarkdownTitleRequired = errors.New("title is required in front matter or request")
+	ErrInvalidOutline        = errors.New("invalid outline")
+	ErrOutlineNotAvailable   = errors.New("PPT was not generated from an outline")
+	ErrOutlineLocked         = errors.New("PPT is being generated")
+	ErrTitlePageFrame        = errors.New("the title page is generated from the template and cannot be edited")
+	ErrInvalidFrame          = errors.New("content is not a single frame environment")
+	ErrRevisionNotFound      = errors.New("revision not found")
+	ErrPPTNotReady           = errors.New("PPT has not finished generating")
+	ErrPPTNotFound           = errors.New("PPT not found")
+)
+
+// 版本来源
+const (
+	RevisionOriginGenerated = "generated" // 模型生成，包括单页重新生成
+	RevisionOriginManual    = "manual"    // 用户编辑后编译或由 Markdown 导入
+	RevisionOriginAutoFix   = "auto_fix"  // AI 修复编译错误后的源码
+	RevisionOriginRestore   = "restore"   // 由历史版本恢复
+)
+
+// 按大纲生成时同时请求模型的章节数
+const outlineConcurrency = 4
+
 type PPTService struct {
-	pptRepo         *repository.PPTRepository
+	pptRepo          *repository.PPTRepository
 	knowledgeService *KnowledgeService
-	aiService       *AIService
-	compiler        *latex.Compiler
-	outputDir       string
+	aiService        *AIService
+	templateService  *TemplateService
+	compiler         *latex.Compiler
+	outputDir        string
+	maxFixAttempts   int
 }
 
 func NewPPTService(
 	pptRepo *repository.PPTRepository,
 	knowledgeService *KnowledgeService,
 	aiService *AIService,
+	templateService *TemplateService,
 	compiler *latex.Compiler,
 	outputDir string,
+	maxFixAttempts int,
 ) *PPTService {
 	return &PPTService{
-		pptRepo:         pptRepo,
+		pptRepo:          pptRepo,
 		knowledgeService: knowledgeService,
-		aiService:       aiService,
-		compiler:        compiler,
-		outputDir:       outputDir,
+		aiService:        aiService,
+		templateService:  templateService,
+		compiler:         compiler,
+		outputDir:        outputDir,
+		maxFixAttempts:   maxFixAttempts,
+	}
+}
+
+// StreamEvent 是流式生成过程中推送给调用方的事件
+type StreamEvent struct {
+	Type        string `json:"type"` // started, delta, compiling, repairing, completed, failed
+	PPTID       uint   `json:"ppt_id,omitempty"`
+	Content     string `json:"content,omitempty"`
+	Pass        int    `json:"pass,omitempty"`
+	TotalPasses int    `json:"total_passes,omitempty"`
+	Attempt     int    `json:"attempt,omitempty"`
+	PDFPath     string `json:"pdf_path,omitempty"`
+	Error       string `json:"error,omitempty"`
+	// Diagnostics 随 completed / failed 事件返回编译诊断
+	Diagnostics []latex.Diagnostic `json:"diagnostics,omitempty"`
+}
+
+// GenerateParams 描述一次 PPT 生成请求
+type GenerateParams struct {
+	Title    string
+	Prompt   string
+	Template string
+	// Mode 为 GenerationModeTemplate 时模型只生成 frame，由服务端套用模板
+	Mode      string
+	Subtitle  string
+	Author    string
+	Institute string
+	// Engine 指定 TeX 引擎，为空时按模板偏好或默认引擎选择
+	Engine      string
+	DocumentIDs []uint
+	Selection   ModelSelection
+	// AutoFix 为 true 时编译失败会交给 AI 修复后重试，最多 MaxFixAttempts 次
+	AutoFix        bool
+	MaxFixAttempts int
+}
+
+// GeneratePPT 在当前请求中同步完成生成与编译
+func (s *PPTService) GeneratePPT(ctx context.Context, userID uint, params GenerateParams) (*model.PPTRecord, error) {
+	ppt, err := s.CreatePending(userID, params)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.runGeneration(ctx, ppt); err != nil {
+		return nil, err
 	}
+
+	// Return ppt with latex content even if compilation fails
+	return ppt, nil
 }
 
-func (s *PPTService) GeneratePPT(ctx context.Context, userID uint, title, prompt, template string, documentIDs []uint, useOpenAI bool) (*model.PPTRecord, error) {
-	// Create PPT record
+// CreatePending 创建待处理的 PPT 记录，生成参数随记录持久化以便任务恢复
+func (s *PPTService) CreatePending(userID uint, params GenerateParams) (*model.PPTRecord, error) {
+	ppt, err := s.newRecord(userID, params)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.pptRepo.Create(ppt); err != nil {
+		return nil, err
+	}
+	return ppt, nil
+}
+
+// newRecord 由生成参数构造 PPT 记录，不写入数据库
+func (s *PPTService) newRecord(userID uint, params GenerateParams) (*model.PPTRecord, error) {
+	documentIDs, err := json.Marshal(params.DocumentIDs)
+	if err != nil {
+		return nil, err
+	}
+
 	ppt := &model.PPTRecord{
-		UserID:   userID,
-		Title:    title,
-		Prompt:   prompt,
-		Template: template,
-		Status:   "generating",
+		UserID:      userID,
+		Title:       params.Title,
+		Prompt:      params.Prompt,
+		Template:    params.Template,
+		Mode:        params.Mode,
+		Subtitle:    params.Subtitle,
+		Author:      params.Author,
+		Institute:   params.Institute,
+		Engine:      params.Engine,
+		Status:      "pending",
+		Provider:    params.Selection.Provider,
+		Model:       params.Selection.Model,
+		DocumentIDs: string(documentIDs),
 	}
+	if params.AutoFix {
+		ppt.MaxFixAttempts = s.fixAttempts(params.MaxFixAttempts)
+	}
+	return ppt, nil
+}
 
+// RunJob 执行一个已入队的生成任务，非 pending 状态的记录会被跳过
+func (s *PPTService) RunJob(ctx context.Context, pptID uint) error {
+	claimed, err := s.pptRepo.TransitionStatus(pptID, "pending", "generating")
+	if err != nil || !claimed {
+		return err
+	}
+
+	ppt, err := s.pptRepo.FindByID(pptID)
+	if err != nil {
+		return err
+	}
+
+	return s.runGeneration(ctx, ppt)
+}
+
+func (s *PPTService) runGeneration(ctx context.Context, ppt *model.PPTRecord) error {
+	s.markGenerating(ppt)
+	if ppt.Mode == GenerationModeOutline {
+		return s.generateFromOutline(ctx, ppt)
+	}
+
+	documentIDs := decodeDocumentIDs(ppt.DocumentIDs)
+	contextChunks := s.retrieveContext(ctx, ppt.UserID, ppt.Prompt, documentIDs)
+
+	// Generate LaTeX content using AI
+	latexContent, err := s.aiService.GenerateLaTeXPPT(ctx, ppt.Prompt, contextChunks, selectionOf(ppt), ppt.Mode)
+	if err != nil {
+		s.markFailed(ppt, err.Error())
+		return err
+	}
+
+	s.compileGenerated(ctx, ppt, latexContent, documentIDs, nil)
+	return nil
+}
+
+// StreamGeneratePPT 与 GeneratePPT 相同，但会把模型增量输出和编译进度写入 events。
+// 结束时关闭 events。
+func (s *PPTService) StreamGeneratePPT(ctx context.Context, userID uint, params GenerateParams, events chan<- StreamEvent) {
+	defer close(events)
+
+	ppt, err := s.CreatePending(userID, params)
+	if err != nil {
+		events <- StreamEvent{Type: "failed", Error: err.Error()}
+		return
+	}
+	s.markGenerating(ppt)
+	events <- StreamEvent{Type: "started", PPTID: ppt.ID}
+
+	contextChunks := s.retrieveContext(ctx, userID, params.Prompt, params.DocumentIDs)
+
+	deltaCh := make(chan string)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- s.aiService.StreamGenerateLaTeXPPT(ctx, params.Prompt, contextChunks, params.Selection, params.Mode, deltaCh)
+	}()
+
+	var output strings.Builder
+	for delta := range deltaCh {
+		output.WriteString(delta)
+		events <- StreamEvent{Type: "delta", PPTID: ppt.ID, Content: delta}
+	}
+
+	err = <-errCh
+	if errors.Is(err, ai.ErrStreamingNotSupported) {
+		// Provider 不支持流式输出时退化为一次性生成
+		var content string
+		content, err = s.aiService.GenerateLaTeXPPT(ctx, params.Prompt, contextChunks, params.Selection, params.Mode)
+		if err == nil {
+			output.WriteString(content)
+			events <- StreamEvent{Type: "delta", PPTID: ppt.ID, Content: content}
+		}
+	}
+	if err != nil {
+		s.markFailed(ppt, err.Error())
+		events <- StreamEvent{Type: "failed", PPTID: ppt.ID, Error: err.Error()}
+		return
+	}
+
+	ppt = s.compileGenerated(ctx, ppt, output.String(), params.DocumentIDs, func(event StreamEvent) {
+		events <- event
+	})
+
+	if ppt.Status != "completed" {
+		events <- StreamEvent{Type: "failed", PPTID: ppt.ID, Error: ppt.ErrorMessage, Diagnostics: ppt.Diagnostics}
+		return
+	}
+	events <- StreamEvent{Type: "completed", PPTID: ppt.ID, PDFPath: ppt.PDFPath, Diagnostics: ppt.Diagnostics}
+}
+
+// CreateOutline 生成大纲并创建状态为 outline 的 PPT 记录，用户确认或修改大纲后
+// 通过 QueueOutline 开始按章节生成
+func (s *PPTService) CreateOutline(ctx context.Context, userID uint, params GenerateParams) (*model.PPTRecord, error) {
+	params.Mode = GenerationModeOutline
+	contextChunks := s.retrieveContext(ctx, userID, params.Prompt, params.DocumentIDs)
+
+	raw, err := s.aiService.GenerateOutline(ctx, params.Prompt, contextChunks, params.Selection)
+	if err != nil {
+		return nil, err
+	}
+	outline, err := parseOutline(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	ppt, err := s.newRecord(userID, params)
+	if err != nil {
+		return nil, err
+	}
+	// 记录以 outline 状态创建，任务恢复不会处理未确认的大纲
+	ppt.Status = "outline"
+	ppt.Outline = outline
 	if err := s.pptRepo.Create(ppt); err != nil {
 		return nil, err
 	}
+	return ppt, nil
+}
 
+// UpdateOutline 替换 PPT 的大纲，生成进行中时返回 ErrOutlineLocked
+func (s *PPTService) UpdateOutline(userID, pptID uint, outline *model.Outline) (*model.PPTRecord, error) {
+	if err := validateOutline(outline); err != nil {
+		return nil, err
+	}
+	ppt, err := s.GetPPT(userID, pptID)
+	if err != nil {
+		return nil, err
+	}
+	if ppt.Mode != GenerationModeOutline {
+		return nil, ErrOutlineNotAvailable
+	}
+	if ppt.Status == "pending" || ppt.Status == "generating" {
+		return nil, ErrOutlineLocked
+	}
+
+	ppt.Outline = outline
+	return ppt, s.pptRepo.Update(ppt)
+}
+
+// QueueOutline 把按大纲生成的记录置为 pending，由调用方入队。
+// 大纲待确认、生成失败或已完成的记录都可以重新生成
+func (s *PPTService) QueueOutline(userID, pptID uint) (*model.PPTRecord, error) {
+	ppt, err := s.GetPPT(userID, pptID)
+	if err != nil {
+		return nil, err
+	}
+	if ppt.Mode != GenerationModeOutline || ppt.Outline == nil {
+		return nil, ErrOutlineNotAvailable
+	}
+
+	for _, from := range []string{"outline", "failed", "completed"} {
+		claimed, err := s.pptRepo.TransitionStatus(pptID, from, "pending")
+		if err != nil {
+			return nil, err
+		}
+		if claimed {
+			ppt.Status = "pending"
+			return ppt, nil
+		}
+	}
+	return nil, ErrOutlineLocked
+}
+
+// generateFromOutline 按大纲并行生成各章节的 frame，拼接后套用模板并编译。
+// 任一章节失败时整个任务失败
+func (s *PPTService) generateFromOutline(ctx context.Context, ppt *model.PPTRecord) error {
+	outline := ppt.Outline
+	if err := validateOutline(outline); err != nil {
+		s.markFailed(ppt, err.Error())
+		return err
+	}
+
+	documentIDs := decodeDocumentIDs(ppt.DocumentIDs)
+	sections := make([]string, len(outline.Sections))
+	errs := make([]error, len(outline.Sections))
+	sem := make(chan struct{}, outlineConcurrency)
+	var wg sync.WaitGroup
+	for i, section := range outline.Sections {
+		wg.Add(1)
+		go func(i int, section model.OutlineSection) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			// 每个章节按自己的标题检索参考资料
+			contextChunks := s.retrieveContext(ctx, ppt.UserID, section.Title+"\n"+ppt.Prompt, documentIDs)
+			output, err := s.aiService.GenerateSectionFrames(ctx, ppt.Prompt, outline, i, contextChunks, selectionOf(ppt))
+			if err != nil {
+				errs[i] = fmt.Errorf("section %d (%s): %w", i+1, section.Title, err)
+				return
+			}
+			sections[i] = sectionContent(section.Title, output)
+		}(i, section)
+	}
+	wg.Wait()
+
+	if err := errors.Join(errs...); err != nil {
+		s.markFailed(ppt, err.Error())
+		return err
+	}
+
+	s.compileGenerated(ctx, ppt, strings.Join(sections, "\n\n"), documentIDs, nil)
+	return nil
+}
+
+var sectionCommandPattern = regexp.MustCompile(`(?m)^[ \t]*\\section\*?\{.*\}[ \t]*$`)
+
+// sectionContent 去掉模型输出中多余的 \section，加上大纲中的章节标题
+func sectionContent(title, output string) string {
+	frames := latex.FrameContent(extractLatexCode(output))
+	frames = strings.TrimSpace(sectionCommandPattern.ReplaceAllString(frames, ""))
+	return fmt.Sprintf("\\section{%s}\n\n%s", latex.EscapeLaTeX(title), frames)
+}
+
+// parseOutline 从模型输出中解析大纲，容忍代码块和前后的说明文字
+func parseOutline(raw string) (*model.Outline, error) {
+	start, end := strings.Index(raw, "{"), strings.LastIndex(raw, "}")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("%w: no JSON object in model output", ErrInvalidOutline)
+	}
+
+	var outline model.Outline
+	if err := json.Unmarshal([]byte(raw[start:end+1]), &outline); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidOutline, err)
+	}
+	if err := validateOutline(&outline); err != nil {
+		return nil, err
+	}
+	return &outline, nil
+}
+
+// validateOutline 要求大纲至少有一个章节，且每个章节和 frame 都有标题
+func validateOutline(outline *model.Outline) error {
+	if outline == nil || len(outline.Sections) == 0 {
+		return fmt.Errorf("%w: no sections", ErrInvalidOutline)
+	}
+	for i, section := range outline.Sections {
+		if strings.TrimSpace(section.Title) == "" {
+			return fmt.Errorf("%w: section %d has no title", ErrInvalidOutline, i+1)
+		}
+		if len(section.Frames) == 0 {
+			return fmt.Errorf("%w: section %d has no frames", ErrInvalidOutline, i+1)
+		}
+		for j, frame := range section.Frames {
+			if strings.TrimSpace(frame.Title) == "" {
+				return fmt.Errorf("%w: frame %d of section %d has no title", ErrInvalidOutline, j+1, i+1)
+			}
+		}
+	}
+	return nil
+}
+
+// retrieveContext 从 userID 选定的文档中检索与 prompt 相关的内容，不属于该用户的文档会被忽略
+func (s *PPTService) retrieveContext(ctx context.Context, userID uint, prompt string, documentIDs []uint) []string {
 	// Get context from knowledge base if document IDs provided
 	var contextChunks []string
 	if len(documentIDs) > 0 {
-		results, err := s.knowledgeService.SearchSimilarChunks(ctx, prompt, 5)
+		results, err := s.knowledgeService.SearchSimilarChunks(ctx, userID, prompt, 5, documentIDs)
 		if err == nil {
 			for _, result := range results {
 				contextChunks = append(contextChunks, result.Content)
 			}
 		}
 	}
+	return contextChunks
+}
 
-	// Generate LaTeX content using AI
-	latexContent, err := s.aiService.GenerateLaTeXPPT(ctx, prompt, contextChunks, useOpenAI)
+// compileGenerated 提取模型输出中的 LaTeX 代码并编译，结果写回 ppt。
+// notify 非空时会收到编译进度和修复事件
+func (s *PPTService) compileGenerated(ctx context.Context, ppt *model.PPTRecord, rawOutput string, documentIDs []uint, notify func(StreamEvent)) *model.PPTRecord {
+	tmpl, err := s.templateService.Resolve(ppt.UserID, ppt.Template)
 	if err != nil {
-		ppt.Status = "failed"
-		ppt.ErrorMessage = err.Error()
-		s.pptRepo.Update(ppt)
-		return nil, err
+		s.markFailed(ppt, fmt.Sprintf("Template %q: %v", ppt.Template, err))
+		return ppt
 	}
+	ppt.TemplateVersion = tmpl.Version
 
 	// Extract LaTeX code from markdown code blocks if present
-	latexContent = extractLatexCode(latexContent)
+	latexContent := extractLatexCode(rawOutput)
+	if ppt.Mode == GenerationModeTemplate || ppt.Mode == GenerationModeOutline {
+		latexContent = renderTemplate(ppt, tmpl.Source, latexContent)
+	}
 
-	ppt.LatexContent = latexContent
+	setLatexContent(ppt, latexContent)
+	generated := model.PPTRevision{AuthorID: ppt.UserID, Origin: RevisionOriginGenerated, Source: latexContent}
+
+	var progress latex.ProgressFunc
+	if notify != nil {
+		progress = func(pass, total int) {
+			notify(StreamEvent{Type: "compiling", PPTID: ppt.ID, Pass: pass, TotalPasses: total})
+		}
+	}
 
 	// Compile LaTeX to PDF
-	filename := fmt.Sprintf("ppt_%d_%d.pdf", ppt.ID, time.Now().Unix())
-	pdfPath, err := s.compiler.Compile(latexContent, filename)
+	filename := fmt.Sprintf("ppt_%d_%d.pdf", ppt.ID, time.Now().UnixNano())
+	opts := latex.Options{
+		Engine:          ppt.Engine,
+		PreferredEngine: tmpl.Engine,
+		Assets:          tmpl.Assets,
+		Progress:        progress,
+	}
+	result, err := s.compiler.CompileWithOptions(ctx, latexContent, filename, opts)
+
+	var compileErr *latex.CompileError
+	if errors.As(err, &compileErr) && ppt.MaxFixAttempts > 0 {
+		result, err = s.repair(ctx, ppt, compileErr, filename, notify, opts)
+	}
+
 	if err != nil {
-		ppt.Status = "failed"
-		ppt.ErrorMessage = fmt.Sprintf("Compilation failed: %v", err)
-		s.pptRepo.Update(ppt)
-		return ppt, nil // Return ppt with latex content even if compilation fails
+		ppt.Diagnostics = diagnosticsOf(err)
+		ppt.Passes = passesOf(err)
+		if errors.As(err, &compileErr) {
+			ppt.Engine = compileErr.Engine
+		}
+		generated.Engine = ppt.Engine
+		message := fmt.Sprintf("Compilation failed: %v", err)
+		if err := s.addRevision(ppt, generated); err != nil {
+			message = err.Error()
+		}
+		s.markFailed(ppt, message)
+		return ppt
 	}
 
-	ppt.PDFPath = pdfPath
-	ppt.Status = "completed"
-	s.pptRepo.Update(ppt)
+	ppt.PDFPath = result.PDFPath
+	ppt.Engine = result.Engine
+	ppt.Diagnostics = result.Diagnostics
+	ppt.Passes = result.Passes
+	if ppt.LatexContent != latexContent {
+		// 生成的源码没有编译通过，保留它再记录修复后的版本
+		err = s.addRevision(ppt, generated)
+		if err == nil {
+			err = s.addRevision(ppt, model.PPTRevision{
+				AuthorID: ppt.UserID,
+				Origin:   RevisionOriginAutoFix,
+				Source:   ppt.LatexContent,
+				PDFPath:  result.PDFPath,
+				Engine:   result.Engine,
+			})
+		}
+	} else {
+		generated.PDFPath = result.PDFPath
+		generated.Engine = result.Engine
+		err = s.addRevision(ppt, generated)
+	}
+	if err != nil {
+		s.markFailed(ppt, err.Error())
+		return ppt
+	}
+	s.markCompleted(ppt)
 
-	// Create knowledge references
-	for _, docID := range documentIDs {
+	// 只为属于该用户的文档创建知识库引用
+	owned, err := s.knowledgeService.OwnedDocumentIDs(ppt.UserID, documentIDs)
+	if err != nil {
+		log.Printf("Failed to check documents of PPT %d: %v", ppt.ID, err)
+	}
+	for _, docID := range owned {
 		ref := &model.PPTKnowledgeRef{
 			PPTID:      ppt.ID,
 			DocumentID: docID,
@@ -98,48 +523,609 @@ func (s *PPTService) GeneratePPT(ctx context.Context, userID uint, title, prompt
 		s.pptRepo.CreateKnowledgeRef(ref)
 	}
 
-	return ppt, nil
+	return ppt
 }
 
-func (s *PPTService) CompileLaTeX(pptID uint, latexContent string) error {
-	ppt, err := s.pptRepo.FindByID(pptID)
+// repair 把编译错误交给 AI 修复并重新编译，直到成功或达到 ppt.MaxFixAttempts。
+// 每次尝试都会被记录；修复成功时 ppt.LatexContent 更新为修复后的源码
+func (s *PPTService) repair(ctx context.Context, ppt *model.PPTRecord, compileErr *latex.CompileError, filename string, notify func(StreamEvent), opts latex.Options) (*latex.Result, error) {
+	sel := s.aiService.Resolve(selectionOf(ppt))
+	source := ppt.LatexContent
+	var lastErr error = compileErr
+
+	for attempt := 1; attempt <= ppt.MaxFixAttempts; attempt++ {
+		if notify != nil {
+			notify(StreamEvent{Type: "repairing", PPTID: ppt.ID, Attempt: attempt})
+		}
+
+		errs := compileErr.Errors()
+		if len(errs) == 0 {
+			errs = compileErr.Diagnostics
+		}
+		record := &model.PPTRepairAttempt{
+			PPTID:    ppt.ID,
+			Attempt:  attempt,
+			Provider: sel.Provider,
+			Model:    sel.Model,
+			Errors:   errs,
+		}
+
+		fixed, err := s.aiService.RepairLaTeX(ctx, source, compileErr.Engine, errs, sel)
+		if err != nil {
+			record.ErrorMessage = err.Error()
+			s.pptRepo.CreateRepairAttempt(record)
+			return nil, lastErr
+		}
+
+		source = extractLatexCode(fixed)
+		record.Source = source
+
+		result, err := s.compiler.CompileWithOptions(ctx, source, filename, opts)
+		if err == nil {
+			record.Success = true
+			s.pptRepo.CreateRepairAttempt(record)
+			setLatexContent(ppt, source)
+			return result, nil
+		}
+
+		record.ErrorMessage = err.Error()
+		s.pptRepo.CreateRepairAttempt(record)
+		lastErr = err
+
+		if !errors.As(err, &compileErr) {
+			break
+		}
+	}
+
+	return nil, lastErr
+}
+
+// fixAttempts 把请求的修复次数限制在配置的上限内
+func (s *PPTService) fixAttempts(requested int) int {
+	if requested <= 0 || requested > s.maxFixAttempts {
+		return s.maxFixAttempts
+	}
+	return requested
+}
+
+func (s *PPTService) GetRepairAttempts(userID, pptID uint) ([]model.PPTRepairAttempt, error) {
+	if _, err := s.GetPPT(userID, pptID); err != nil {
+		return nil, err
+	}
+	return s.pptRepo.FindRepairAttemptsByPPTID(pptID)
+}
+
+func (s *PPTService) markGenerating(ppt *model.PPTRecord) {
+	now := time.Now()
+	ppt.Status = "generating"
+	ppt.Attempts++
+	ppt.StartedAt = &now
+	ppt.FinishedAt = nil
+	ppt.ErrorMessage = ""
+	s.pptRepo.Update(ppt)
+}
+
+func (s *PPTService) markCompleted(ppt *model.PPTRecord) {
+	now := time.Now()
+	ppt.Status = "completed"
+	ppt.ErrorMessage = ""
+	ppt.FinishedAt = &now
+	s.pptRepo.Update(ppt)
+}
+
+func (s *PPTService) markFailed(ppt *model.PPTRecord, message string) {
+	now := time.Now()
+	ppt.Status = "failed"
+	ppt.ErrorMessage = message
+	ppt.FinishedAt = &now
+	s.pptRepo.Update(ppt)
+}
+
+// generationFinished 判断生成是否已结束。大纲待确认或生成中的记录不能编辑，否则会被生成结果覆盖
+func generationFinished(status string) bool {
+	return status == "completed" || status == "failed"
+}
+
+// addRevision 为 ppt 保存一个新版本并更新 ppt.Revision，由调用方负责保存 ppt
+func (s *PPTService) addRevision(ppt *model.PPTRecord, rev model.PPTRevision) error {
+	rev.PPTID = ppt.ID
+	if err := s.pptRepo.CreateRevision(&rev); err != nil {
+		return fmt.Errorf("record revision of PPT %d: %w", ppt.ID, err)
+	}
+	ppt.Revision = rev.Number
+	return nil
+}
+
+// diagnosticsOf 从编译错误中提取诊断信息
+func diagnosticsOf(err error) model.Diagnostics {
+	var compileErr *latex.CompileError
+	if errors.As(err, &compileErr) {
+		return compileErr.Diagnostics
+	}
+	return nil
+}
+
+// setLatexContent 更新 LaTeX 源码，并同步更新解析出的结构化文档
+func setLatexContent(ppt *model.PPTRecord, source string) {
+	ppt.LatexContent = source
+	ppt.Deck = (*model.Deck)(latex.ParseDeck(source))
+}
+
+// deckOf 返回 PPT 的结构化文档，旧记录没有存储时从 LaTeX 源码解析
+func deckOf(ppt *model.PPTRecord) *latex.Deck {
+	if ppt.Deck != nil {
+		return (*latex.Deck)(ppt.Deck)
+	}
+	return latex.ParseDeck(ppt.LatexContent)
+}
+
+// renderTemplate 把模型生成的 frame 套入模板源码
+func renderTemplate(ppt *model.PPTRecord, source string, content string) string {
+	return latex.RenderTemplate(source, latex.TemplateData{
+		Title:     ppt.Title,
+		Subtitle:  ppt.Subtitle,
+		Author:    ppt.Author,
+		Institute: ppt.Institute,
+		Content:   latex.FrameContent(content),
+	})
+}
+
+// passesOf 从编译错误中提取已执行的编译步骤
+func passesOf(err error) model.CompilePasses {
+	var compileErr *latex.CompileError
+	if errors.As(err, &compileErr) {
+		return compileErr.Passes
+	}
+	return nil
+}
+
+func selectionOf(ppt *model.PPTRecord) ModelSelection {
+	return ModelSelection{Provider: ppt.Provider, Model: ppt.Model}
+}
+
+func decodeDocumentIDs(raw string) []uint {
+	var ids []uint
+	if raw != "" {
+		json.Unmarshal([]byte(raw), &ids)
+	}
+	return ids
+}
+
+// CompileLaTeX 编译用户提交的 LaTeX，engine 为空时沿用记录上次使用的引擎。
+// 编译失败时只更新诊断信息，保留原有内容和 PDF；成功时记录为 userID 的 manual 版本
+func (s *PPTService) CompileLaTeX(ctx context.Context, pptID uint, userID uint, latexContent string, engine string) (*model.PPTRecord, error) {
+	return s.compileRevision(ctx, pptID, latexContent, engine, model.PPTRevision{AuthorID: userID, Origin: RevisionOriginManual})
+}
+
+// compileRevision 编译 latexContent，成功后替换当前内容并保存为新版本，rev 提供作者和来源。
+// 版本作者即调用方，PPT 不属于作者时返回 ErrPPTNotFound，仍在大纲确认或生成中时返回 ErrPPTNotReady
+func (s *PPTService) compileRevision(ctx context.Context, pptID uint, latexContent string, engine string, rev model.PPTRevision) (*model.PPTRecord, error) {
+	ppt, err := s.GetPPT(rev.AuthorID, pptID)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	previous := ppt.Status
+	if !generationFinished(previous) {
+		return nil, ErrPPTNotReady
+	}
+	if engine == "" {
+		engine = ppt.Engine
+	}
+	// 使用生成时的模板版本，模板之后的修改不影响已有的 PPT
+	tmpl, err := s.templateService.ResolveFor(ppt)
+	if err != nil {
+		return nil, err
 	}
 
-	filename := fmt.Sprintf("ppt_%d_%d.pdf", ppt.ID, time.Now().Unix())
-	pdfPath, err := s.compiler.Compile(latexContent, filename)
+	filename := fmt.Sprintf("ppt_%d_%d.pdf", ppt.ID, time.Now().UnixNano())
+	result, err := s.compiler.CompileWithOptions(ctx, latexContent, filename, latex.Options{
+		Engine:          engine,
+		PreferredEngine: tmpl.Engine,
+		Assets:          tmpl.Assets,
+	})
 	if err != nil {
-		return err
+		// 只写诊断列，整行保存会覆盖编译期间其他请求对记录的修改
+		if diags := diagnosticsOf(err); diags != nil {
+			ppt.Diagnostics = diags
+			ppt.Passes = passesOf(err)
+			if _, updateErr := s.pptRepo.UpdateDiagnostics(ppt.ID, previous, ppt.Diagnostics, ppt.Passes); updateErr != nil {
+				log.Printf("Compile: failed to save diagnostics of PPT %d: %v", ppt.ID, updateErr)
+			}
+		}
+		return ppt, err
 	}
 
-	ppt.LatexContent = latexContent
-	ppt.PDFPath = pdfPath
+	setLatexContent(ppt, latexContent)
+	ppt.PDFPath = result.PDFPath
+	ppt.Engine = result.Engine
+	ppt.Diagnostics = result.Diagnostics
+	ppt.Passes = result.Passes
+
+	// 编译期间记录可能被重新排队生成，只在状态未变时接管
+	claimed, err := s.pptRepo.TransitionStatus(ppt.ID, previous, "completed")
+	if err != nil {
+		return nil, err
+	}
+	if !claimed {
+		return nil, ErrPPTNotReady
+	}
 	ppt.Status = "completed"
-	return s.pptRepo.Update(ppt)
+
+	rev.Source = latexContent
+	rev.PDFPath = result.PDFPath
+	rev.Engine = result.Engine
+	if err := s.addRevision(ppt, rev); err != nil {
+		return nil, err
+	}
+	return ppt, s.pptRepo.Update(ppt)
+}
+
+// CompileScratch 编译任意 LaTeX 源码并返回 PDF 内容，不创建记录、不记录版本，也不调用模型。
+// templateName 非空时使用该模板的资源文件和偏好引擎
+func (s *PPTService) CompileScratch(ctx context.Context, userID uint, latexContent string, templateName string, engine string) (*latex.Result, []byte, error) {
+	opts := latex.Options{Engine: engine}
+	if templateName != "" {
+		tmpl, err := s.templateService.Resolve(userID, templateName)
+		if err != nil {
+			return nil, nil, err
+		}
+		opts.PreferredEngine = tmpl.Engine
+		opts.Assets = tmpl.Assets
+	}
+
+	filename := fmt.Sprintf("scratch_%d_%d.pdf", userID, time.Now().UnixNano())
+	result, err := s.compiler.CompileWithOptions(ctx, latexContent, filename, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer os.Remove(result.PDFPath)
+
+	data, err := os.ReadFile(result.PDFPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	return result, data, nil
+}
+
+// GetRevisions 返回 PPT 的版本列表（不含源码），最新的在前
+func (s *PPTService) GetRevisions(userID, pptID uint) ([]model.PPTRevision, error) {
+	if _, err := s.GetPPT(userID, pptID); err != nil {
+		return nil, err
+	}
+	return s.pptRepo.FindRevisionsByPPTID(pptID)
+}
+
+func (s *PPTService) GetRevision(userID, pptID uint, number int) (*model.PPTRevision, error) {
+	if _, err := s.GetPPT(userID, pptID); err != nil {
+		return nil, err
+	}
+	return s.revision(pptID, number)
+}
+
+func (s *PPTService) revision(pptID uint, number int) (*model.PPTRevision, error) {
+	rev, err := s.pptRepo.FindRevision(pptID, number)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrRevisionNotFound
+	}
+	return rev, err
+}
+
+// DiffRevisions 返回版本 from 到版本 to 的 unified diff，to 为 0 时与当前内容比较。
+// ppt 应由 GetPPT 取得
+func (s *PPTService) DiffRevisions(ppt *model.PPTRecord, from, to int) (string, error) {
+	old, err := s.revision(ppt.ID, from)
+	if err != nil {
+		return "", err
+	}
+
+	toName, source := "current", ppt.LatexContent
+	if to > 0 {
+		rev, err := s.revision(ppt.ID, to)
+		if err != nil {
+			return "", err
+		}
+		toName, source = fmt.Sprintf("revision %d", to), rev.Source
+	}
+	return diff.Unified(fmt.Sprintf("revision %d", from), toName, old.Source, source, 3), nil
+}
+
+// RestoreRevision 重新编译历史版本的源码，成功后作为新的 restore 版本成为当前内容。
+// engine 为空时使用该版本当时的引擎
+func (s *PPTService) RestoreRevision(ctx context.Context, pptID uint, userID uint, number int, engine string) (*model.PPTRecord, error) {
+	rev, err := s.GetRevision(userID, pptID, number)
+	if err != nil {
+		return nil, err
+	}
+	if engine == "" {
+		engine = rev.Engine
+	}
+	return s.compileRevision(ctx, pptID, rev.Source, engine, model.PPTRevision{
+		AuthorID:     userID,
+		Origin:       RevisionOriginRestore,
+		RestoredFrom: number,
+	})
+}
+
+// MarkdownParams 描述一次 Markdown 导入，非空的元信息覆盖 front matter 中的值
+type MarkdownParams struct {
+	Markdown  string
+	Template  string
+	Engine    string
+	Title     string
+	Subtitle  string
+	Author    string
+	Institute string
+}
+
+// ConvertMarkdown 把 Markdown 幻灯片套入模板，返回完整的 LaTeX 源码和合并后的元信息
+func (s *PPTService) ConvertMarkdown(userID uint, params MarkdownParams) (string, latex.TemplateData, *ResolvedTemplate, error) {
+	tmpl, err := s.templateService.Resolve(userID, params.Template)
+	if err != nil {
+		return "", latex.TemplateData{}, nil, err
+	}
+
+	data := latex.ParseMarkdown(params.Markdown)
+	for _, field := range []struct {
+		dst   *string
+		value string
+	}{
+		{&data.Title, params.Title}, {&data.Subtitle, params.Subtitle},
+		{&data.Author, params.Author}, {&data.Institute, params.Institute},
+	} {
+		if field.value != "" {
+			*field.dst = field.value
+		}
+	}
+	return latex.RenderTemplate(tmpl.Source, data), data, tmpl, nil
+}
+
+// ImportMarkdown 不经过模型，直接把 Markdown 幻灯片转换为 PPT 并同步编译。
+// 编译失败时仍会保存记录（状态为 failed），以便用户在编辑器中修改后重新编译
+func (s *PPTService) ImportMarkdown(ctx context.Context, userID uint, params MarkdownParams) (*model.PPTRecord, error) {
+	latexContent, data, tmpl, err := s.ConvertMarkdown(userID, params)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(data.Title) == "" {
+		return nil, ErrMarkdownTitleRequired
+	}
+
+	// 记录在编译结束后才创建，避免任务恢复把它当作未完成的生成任务交给模型
+	now := time.Now()
+	ppt := &model.PPTRecord{
+		UserID:          userID,
+		Title:           data.Title,
+		Prompt:          params.Markdown,
+		Template:        params.Template,
+		TemplateVersion: tmpl.Version,
+		Mode:            GenerationModeMarkdown,
+		Subtitle:        data.Subtitle,
+		Author:          data.Author,
+		Institute:       data.Institute,
+		DocumentIDs:     "[]",
+		StartedAt:       &now,
+	}
+	setLatexContent(ppt, latexContent)
+
+	filename := fmt.Sprintf("markdown_%d_%d.pdf", userID, now.UnixNano())
+	result, compileErr := s.compiler.CompileWithOptions(ctx, latexContent, filename, latex.Options{
+		Engine:          params.Engine,
+		PreferredEngine: tmpl.Engine,
+		Assets:          tmpl.Assets,
+	})
+
+	finished := time.Now()
+	ppt.FinishedAt = &finished
+	if compileErr != nil {
+		var ce *latex.CompileError
+		if errors.As(compileErr, &ce) {
+			ppt.Engine = ce.Engine
+		}
+		ppt.Status = "failed"
+		ppt.ErrorMessage = fmt.Sprintf("Compilation failed: %v", compileErr)
+		ppt.Diagnostics = diagnosticsOf(compileErr)
+		ppt.Passes = passesOf(compileErr)
+	} else {
+		ppt.Status = "completed"
+		ppt.PDFPath = result.PDFPath
+		ppt.Engine = result.Engine
+		ppt.Diagnostics = result.Diagnostics
+		ppt.Passes = result.Passes
+	}
+
+	if err := s.pptRepo.Create(ppt); err != nil {
+		return nil, err
+	}
+	err = s.addRevision(ppt, model.PPTRevision{
+		AuthorID: userID,
+		Origin:   RevisionOriginManual,
+		Source:   latexContent,
+		PDFPath:  ppt.PDFPath,
+		Engine:   ppt.Engine,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := s.pptRepo.Update(ppt); err != nil {
+		return nil, err
+	}
+	return ppt, compileErr
+}
+
+// GetDeck 返回 PPT 的结构化文档
+func (s *PPTService) GetDeck(ppt *model.PPTRecord) (*latex.Deck, error) {
+	if strings.TrimSpace(ppt.LatexContent) == "" {
+		return nil, ErrLaTeXNotAvailable
+	}
+	return deckOf(ppt), nil
+}
+
+// UpdateDeck 由编辑后的结构化文档重新生成 LaTeX 并编译，行为与 CompileLaTeX 相同
+func (s *PPTService) UpdateDeck(ctx context.Context, pptID uint, userID uint, deck *latex.Deck, engine string) (*model.PPTRecord, error) {
+	return s.CompileLaTeX(ctx, pptID, userID, latex.RenderDeck(deck), engine)
+}
+
+// RegenerateFrame 按指令重写第 n 个 frame（从 1 开始，与 Deck.Frames 顺序一致），前后 frame 作为上下文，
+// 其余源码保持不变并重新编译。编译失败时原有内容不变
+func (s *PPTService) RegenerateFrame(ctx context.Context, pptID uint, userID uint, n int, instruction string, sel ModelSelection, engine string) (*model.PPTRecord, error) {
+	ppt, frames, err := s.editableFrame(userID, pptID, n)
+	if err != nil {
+		return nil, err
+	}
+	if sel.Provider == "" && sel.Model == "" {
+		sel = selectionOf(ppt)
+	}
+
+	frameCtx := FrameContext{
+		DeckTitle: ppt.Title,
+		Section:   latex.PlainText(frames[n-1].Section),
+		Packages:  preamblePackages(deckOf(ppt).Preamble),
+		Frame:     frames[n-1].Source,
+	}
+	if n > 1 {
+		frameCtx.Previous = frames[n-2].Source
+	}
+	if n < len(frames) {
+		frameCtx.Next = frames[n].Source
+	}
+
+	output, err := s.aiService.RegenerateFrame(ctx, frameCtx, instruction, sel)
+	if err != nil {
+		return nil, err
+	}
+	frame, ok := latex.FirstFrame(extractLatexCode(output))
+	if !ok {
+		return nil, fmt.Errorf("%w: model output has no frame", ErrInvalidFrame)
+	}
+	return s.replaceFrame(ctx, ppt, n, frame, engine, model.PPTRevision{AuthorID: userID, Origin: RevisionOriginGenerated})
+}
+
+// UpdateFrame 用 source 替换第 n 个 frame 并重新编译
+func (s *PPTService) UpdateFrame(ctx context.Context, pptID uint, userID uint, n int, source string, engine string) (*model.PPTRecord, error) {
+	ppt, _, err := s.editableFrame(userID, pptID, n)
+	if err != nil {
+		return nil, err
+	}
+	frame, ok := latex.FirstFrame(source)
+	if !ok || strings.TrimSpace(source) != frame {
+		return nil, ErrInvalidFrame
+	}
+	return s.replaceFrame(ctx, ppt, n, frame, engine, model.PPTRevision{AuthorID: userID, Origin: RevisionOriginManual})
+}
+
+// editableFrame 加载 userID 的 PPT 并检查第 n 个 frame 可以修改
+func (s *PPTService) editableFrame(userID, pptID uint, n int) (*model.PPTRecord, []latex.Frame, error) {
+	ppt, err := s.GetPPT(userID, pptID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !generationFinished(ppt.Status) {
+		return nil, nil, ErrPPTNotReady
+	}
+	if strings.TrimSpace(ppt.LatexContent) == "" {
+		return nil, nil, ErrLaTeXNotAvailable
+	}
+
+	frames := deckOf(ppt).Frames()
+	if n < 1 || n > len(frames) {
+		return nil, nil, ErrSlideNotFound
+	}
+	if frames[n-1].TitlePage {
+		return nil, nil, ErrTitlePageFrame
+	}
+	return ppt, frames, nil
+}
+
+func (s *PPTService) replaceFrame(ctx context.Context, ppt *model.PPTRecord, n int, frame string, engine string, rev model.PPTRevision) (*model.PPTRecord, error) {
+	source, ok := latex.ReplaceFrame(ppt.LatexContent, n-1, frame)
+	if !ok {
+		return nil, ErrSlideNotFound
+	}
+	return s.compileRevision(ctx, ppt.ID, source, engine, rev)
+}
+
+var preamblePackagePattern = regexp.MustCompile(`(?m)^\s*\\(usepackage|usetheme|usecolortheme|usefonttheme|usetikzlibrary)\b.*$`)
+
+// preamblePackages 返回导言区中加载宏包和主题的命令
+func preamblePackages(preamble string) []string {
+	var packages []string
+	for _, line := range preamblePackagePattern.FindAllString(preamble, -1) {
+		packages = append(packages, strings.TrimSpace(line))
+	}
+	return packages
 }
 
 func (s *PPTService) GetPPTHistory(userID uint) ([]model.PPTRecord, error) {
 	return s.pptRepo.FindByUserID(userID)
 }
 
-func (s *PPTService) GetPPT(id uint) (*model.PPTRecord, error) {
-	return s.pptRepo.FindByID(id)
+// GetPPT 返回属于 userID 的 PPT，记录不存在或属于其他用户时都返回 ErrPPTNotFound
+func (s *PPTService) GetPPT(userID, id uint) (*model.PPTRecord, error) {
+	ppt, err := s.pptRepo.FindByIDAndUserID(id, userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrPPTNotFound
+	}
+	return ppt, err
 }
 
-func (s *PPTService) DeletePPT(id uint) error {
-	_, err := s.pptRepo.FindByID(id)
+// DeletePPT 删除 PPT 及其关联记录，再删除当前和历史版本的 PDF
+func (s *PPTService) DeletePPT(userID, id uint) error {
+	ppt, err := s.GetPPT(userID, id)
+	if err != nil {
+		return err
+	}
+	revs, err := s.pptRepo.FindRevisionsByPPTID(id)
 	if err != nil {
 		return err
 	}
 
-	// Delete PDF file if exists (simplified for now)
-	
-	return s.pptRepo.Delete(id)
+	if err := s.pptRepo.Delete(id); err != nil {
+		return err
+	}
+
+	// 历史版本可能与当前内容共用同一个 PDF
+	pdfs := map[string]bool{ppt.PDFPath: true}
+	for _, rev := range revs {
+		pdfs[rev.PDFPath] = true
+	}
+	for path := range pdfs {
+		if path == "" {
+			continue
+		}
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			log.Printf("Failed to remove PDF of PPT %d: %v", id, err)
+		}
+	}
+	return nil
+}
+
+// GetTemplates 返回内置模板和用户可见的自定义模板名称
+func (s *PPTService) GetTemplates(userID uint) []string {
+	return s.templateService.Names(userID)
+}
+
+// ValidateTemplate 检查模板存在且对用户可见
+func (s *PPTService) ValidateTemplate(userID uint, name string) error {
+	_, err := s.templateService.Resolve(userID, name)
+	return err
+}
+
+// GetEngines 返回 TeX 引擎及其在本机的可用情况
+func (s *PPTService) GetEngines() []latex.EngineInfo {
+	return s.compiler.Engines()
+}
+
+// ValidateEngine 检查请求指定的引擎存在且已安装，空串表示自动选择
+func (s *PPTService) ValidateEngine(name string) error {
+	if name == "" {
+		return nil
+	}
+	_, err := s.compiler.ResolveEngine(name, "")
+	return err
 }
 
-func (s *PPTService) GetTemplates() []string {
-	return latex.ListTemplates()
+func (s *PPTService) GetProviders() []ai.ProviderInfo {
+	return s.aiService.ListProviders()
 }
 
 func extractLatexCode(content string) string {
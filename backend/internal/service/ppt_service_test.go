@@ -0,0 +1,288 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/qingbingwei/latex_ppt_by_claude/backend/internal/model"
+	"github.com/qingbingwei/latex_ppt_by_claude/backend/internal/repository"
+	"github.com/qingbingwei/latex_ppt_by_claude/backend/pkg/ai"
+	"github.com/qingbingwei/latex_ppt_by_claude/backend/pkg/embedding"
+	"github.com/qingbingwei/latex_ppt_by_claude/backend/pkg/latex"
+	"github.com/qingbingwei/latex_ppt_by_claude/backend/pkg/vectordb"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+const testUserID uint = 1
+
+const testLatex = `\documentclass{beamer}
+\begin{document}
+\begin{frame}{First}
+Hello
+\end{frame}
+\end{document}
+`
+
+// fakeXeLaTeX 代替 xelatex：源码包含 \FAIL 时写出错误日志并失败，否则生成 PDF。
+// 模板资源 asset.txt 的内容会追加到 PDF 中，便于检查编译使用的模板版本
+const fakeXeLaTeX = `#!/bin/sh
+if grep -q 'FAIL' main.tex; then
+  printf '! Undefined control sequence.\nl.4 \\FAIL\n' > main.log
+  exit 1
+fi
+printf '%%PDF-1.5\n' > main.pdf
+if [ -f asset.txt ]; then cat asset.txt >> main.pdf; fi
+printf 'Output written on main.pdf\n' > main.log
+`
+
+// testServices 是基于 sqlite 数据库和假 TeX 引擎的服务
+type testServices struct {
+	db        *gorm.DB
+	pptRepo   *repository.PPTRepository
+	templates *TemplateService
+	knowledge *KnowledgeService
+	ppt       *PPTService
+	outputDir string
+}
+
+func newTestServices(t *testing.T, store vectordb.VectorStore) *testServices {
+	t.Helper()
+	dir := t.TempDir()
+
+	bin := filepath.Join(dir, "bin")
+	if err := os.MkdirAll(bin, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(bin, "xelatex"), []byte(fakeXeLaTeX), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	db, err := gorm.Open(sqlite.Open(filepath.Join(dir, "test.db")), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.AutoMigrate(
+		&model.User{},
+		&model.Document{},
+		&model.Chunk{},
+		&model.PPTRecord{},
+		&model.PPTKnowledgeRef{},
+		&model.PPTRepairAttempt{},
+		&model.PPTRevision{},
+		&model.Team{},
+		&model.TeamMember{},
+		&model.Template{},
+		&model.TemplateVersion{},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	userRepo := repository.NewUserRepository(db)
+	if err := userRepo.Create(&model.User{ID: testUserID, Username: "user1", Email: "user1@example.com", PasswordHash: "x"}); err != nil {
+		t.Fatal(err)
+	}
+	pptRepo := repository.NewPPTRepository(db)
+	teamService := NewTeamService(repository.NewTeamRepository(db), userRepo)
+
+	outputDir := filepath.Join(dir, "outputs")
+	compiler := latex.NewCompiler(outputDir, latex.Limits{})
+	compiler.DetectEngines()
+	rasterizer := latex.NewRasterizer(latex.Limits{})
+	knowledge := NewKnowledgeService(repository.NewDocumentRepository(db), embedding.NewOpenAIEmbedding("", ""), store, filepath.Join(dir, "uploads"))
+	templates := NewTemplateService(repository.NewTemplateRepository(db), teamService, compiler, rasterizer, filepath.Join(dir, "templates"), filepath.Join(outputDir, "previews"))
+
+	return &testServices{
+		db:        db,
+		pptRepo:   pptRepo,
+		templates: templates,
+		knowledge: knowledge,
+		ppt:       NewPPTService(pptRepo, knowledge, NewAIService(ai.NewRegistry()), templates, compiler, outputDir, 0),
+		outputDir: outputDir,
+	}
+}
+
+// createPPT 保存一个已完成的 PPT 及其第一个版本
+func (s *testServices) createPPT(t *testing.T, record *model.PPTRecord) *model.PPTRecord {
+	t.Helper()
+	record.UserID = testUserID
+	record.Prompt = "prompt"
+	if record.Template == "" {
+		record.Template = "default"
+	}
+	if record.LatexContent == "" {
+		record.LatexContent = testLatex
+	}
+	record.PDFPath = filepath.Join(s.outputDir, "first.pdf")
+	record.Status = "completed"
+	if err := s.pptRepo.Create(record); err != nil {
+		t.Fatal(err)
+	}
+	rev := model.PPTRevision{AuthorID: testUserID, Origin: RevisionOriginGenerated, Source: record.LatexContent, PDFPath: record.PDFPath}
+	if err := s.ppt.addRevision(record, rev); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.pptRepo.Update(record); err != nil {
+		t.Fatal(err)
+	}
+	return record
+}
+
+func (s *testServices) reload(t *testing.T, id uint) *model.PPTRecord {
+	t.Helper()
+	ppt, err := s.pptRepo.FindByID(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ppt
+}
+
+func TestCompileLaTeX(t *testing.T) {
+	failing := strings.Replace(testLatex, "Hello", `\FAIL`, 1)
+	changed := strings.Replace(testLatex, "Hello", "Changed", 1)
+
+	tests := []struct {
+		name         string
+		source       string
+		wantErr      bool
+		wantContent  string
+		wantRevision int
+		wantDiags    bool
+	}{
+		{name: "success adds a revision", source: changed, wantContent: changed, wantRevision: 2},
+		{name: "failure keeps the content", source: failing, wantErr: true, wantContent: testLatex, wantRevision: 1, wantDiags: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServices(t, vectordb.Unavailable(errors.New("test")))
+			record := s.createPPT(t, &model.PPTRecord{Title: "Deck"})
+
+			_, err := s.ppt.CompileLaTeX(context.Background(), record.ID, testUserID, tt.source, "")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CompileLaTeX() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			stored := s.reload(t, record.ID)
+			if stored.LatexContent != tt.wantContent {
+				t.Errorf("LatexContent = %q, want %q", stored.LatexContent, tt.wantContent)
+			}
+			if stored.Revision != tt.wantRevision {
+				t.Errorf("Revision = %d, want %d", stored.Revision, tt.wantRevision)
+			}
+			if stored.Status != "completed" {
+				t.Errorf("Status = %q, want completed", stored.Status)
+			}
+			if tt.wantErr && stored.PDFPath != record.PDFPath {
+				t.Errorf("PDFPath = %q, want the previous PDF %q", stored.PDFPath, record.PDFPath)
+			}
+			if got := len(stored.Diagnostics) > 0 && stored.Diagnostics[0].Severity == "error"; got != tt.wantDiags {
+				t.Errorf("error diagnostics saved = %v, want %v: %+v", got, tt.wantDiags, stored.Diagnostics)
+			}
+		})
+	}
+}
+
+func TestUpdateDiagnosticsOnlyWritesDiagnostics(t *testing.T) {
+	s := newTestServices(t, vectordb.Unavailable(errors.New("test")))
+	record := s.createPPT(t, &model.PPTRecord{Title: "Deck"})
+	diags := model.Diagnostics{{Severity: "error", Message: "Undefined control sequence."}}
+
+	// 其他请求在编译期间修改了记录
+	if err := s.db.Model(&model.PPTRecord{}).Where("id = ?", record.ID).Update("title", "Renamed").Error; err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		status  string
+		updated bool
+	}{
+		{"status changed", "generating", false},
+		{"same status", "completed", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			updated, err := s.pptRepo.UpdateDiagnostics(record.ID, tt.status, diags, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if updated != tt.updated {
+				t.Errorf("UpdateDiagnostics() = %v, want %v", updated, tt.updated)
+			}
+			stored := s.reload(t, record.ID)
+			if stored.Title != "Renamed" {
+				t.Errorf("Title = %q, concurrent change was overwritten", stored.Title)
+			}
+			if got := len(stored.Diagnostics) == 1; got != tt.updated {
+				t.Errorf("diagnostics written = %v, want %v", got, tt.updated)
+			}
+		})
+	}
+}
+
+func TestRevisions(t *testing.T) {
+	s := newTestServices(t, vectordb.Unavailable(errors.New("test")))
+	record := s.createPPT(t, &model.PPTRecord{Title: "Deck"})
+	ctx := context.Background()
+
+	second := strings.Replace(testLatex, "Hello", "Second", 1)
+	if _, err := s.ppt.CompileLaTeX(ctx, record.ID, testUserID, second, ""); err != nil {
+		t.Fatal(err)
+	}
+	// 恢复版本 1 产生新的版本 3，而不是回退版本号
+	ppt, err := s.ppt.RestoreRevision(ctx, record.ID, testUserID, 1, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ppt.Revision != 3 || ppt.LatexContent != testLatex {
+		t.Errorf("after restore Revision = %d, content = %q", ppt.Revision, ppt.LatexContent)
+	}
+
+	revisions, err := s.ppt.GetRevisions(testUserID, record.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var origins []string
+	for _, rev := range revisions {
+		origins = append(origins, fmt.Sprintf("%d:%s:%d", rev.Number, rev.Origin, rev.RestoredFrom))
+	}
+	if got, want := strings.Join(origins, " "), "3:restore:1 2:manual:0 1:generated:0"; got != want {
+		t.Errorf("revisions = %s, want %s", got, want)
+	}
+
+	tests := []struct {
+		name     string
+		from, to int
+		want     []string
+		wantErr  error
+	}{
+		{name: "two revisions", from: 1, to: 2, want: []string{"--- revision 1\n+++ revision 2\n", "-Hello\n+Second\n"}},
+		{name: "against current", from: 2, want: []string{"+++ current\n", "-Second\n+Hello\n"}},
+		{name: "no changes", from: 1, to: 3},
+		{name: "missing revision", from: 9, wantErr: ErrRevisionNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.ppt.DiffRevisions(ppt, tt.from, tt.to)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("DiffRevisions() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.want == nil && got != "" {
+				t.Errorf("DiffRevisions() =\n%s\nwant no changes", got)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("DiffRevisions() =\n%s\nwant it to contain %q", got, want)
+				}
+			}
+		})
+	}
+}
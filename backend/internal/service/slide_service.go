@@ -0,0 +1,113 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/qingbingwei/latex_ppt_by_claude/backend/internal/model"
+	"github.com/qingbingwei/latex_ppt_by_claude/backend/pkg/latex"
+)
+
+var (
+	ErrPDFNotAvailable = errors.New("PDF not available")
+	ErrSlideNotFound   = errors.New("slide not found")
+)
+
+const (
+	slideDPI     = 144
+	thumbnailDPI = 36
+)
+
+// SlideService 把 PPT 的 PDF 渲染为逐页 PNG 和缩略图。
+// 渲染结果保存在 OutputDir/slides/<PDF 文件名>/ 下，PDF 重新编译后文件名变化，自动重新渲染
+type SlideService struct {
+	rasterizer *latex.Rasterizer
+	slidesDir  string
+	// mu 串行化渲染，避免同一 PDF 被并发渲染
+	mu sync.Mutex
+}
+
+func NewSlideService(rasterizer *latex.Rasterizer, outputDir string) *SlideService {
+	return &SlideService{
+		rasterizer: rasterizer,
+		slidesDir:  slidesDir(outputDir),
+	}
+}
+
+// slidesDir 是逐页 PNG 的存放目录，janitor 也据此清理不再被引用的渲染结果
+func slidesDir(outputDir string) string {
+	return filepath.Join(outputDir, "slides")
+}
+
+// Slides 返回 PPT 每一页 PNG 的路径，按页码排序，首次访问时渲染
+func (s *SlideService) Slides(ctx context.Context, ppt *model.PPTRecord) ([]string, error) {
+	if ppt.PDFPath == "" {
+		return nil, ErrPDFNotAvailable
+	}
+	if _, err := os.Stat(ppt.PDFPath); err != nil {
+		return nil, ErrPDFNotAvailable
+	}
+
+	dir := filepath.Join(s.slidesDir, strings.TrimSuffix(filepath.Base(ppt.PDFPath), filepath.Ext(ppt.PDFPath)))
+	if pages, err := renderedSlides(dir); err == nil {
+		return pages, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if pages, err := renderedSlides(dir); err == nil {
+		return pages, nil
+	}
+
+	if err := os.MkdirAll(s.slidesDir, 0755); err != nil {
+		return nil, err
+	}
+	workDir, err := os.MkdirTemp(s.slidesDir, "render-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(workDir)
+
+	if _, err := s.rasterizer.RenderPages(ctx, ppt.PDFPath, workDir, slideDPI); err != nil {
+		return nil, err
+	}
+	if _, err := s.rasterizer.RenderPages(ctx, ppt.PDFPath, filepath.Join(workDir, "thumbs"), thumbnailDPI); err != nil {
+		return nil, err
+	}
+
+	// 渲染完成后整体 rename，读取方不会看到只渲染了一半的目录
+	os.RemoveAll(dir)
+	if err := os.Rename(workDir, dir); err != nil {
+		return nil, err
+	}
+	return renderedSlides(dir)
+}
+
+// Slide 返回第 n 页（从 1 开始）的 PNG 路径，thumbnail 为 true 时返回缩略图
+func (s *SlideService) Slide(ctx context.Context, ppt *model.PPTRecord, n int, thumbnail bool) (string, error) {
+	pages, err := s.Slides(ctx, ppt)
+	if err != nil {
+		return "", err
+	}
+	if n < 1 || n > len(pages) {
+		return "", ErrSlideNotFound
+	}
+
+	path := pages[n-1]
+	if thumbnail {
+		path = filepath.Join(filepath.Dir(path), "thumbs", filepath.Base(path))
+	}
+	return path, nil
+}
+
+// renderedSlides 读取已渲染的页面，缩略图目录缺失时视为未渲染
+func renderedSlides(dir string) ([]string, error) {
+	if _, err := os.Stat(filepath.Join(dir, "thumbs")); err != nil {
+		return nil, err
+	}
+	return latex.RenderedPages(dir)
+}
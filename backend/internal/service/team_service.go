@@ -0,0 +1,119 @@
+package service
+
+import (
+	"errors"
+
+	"github.com/qingbingwei/latex_ppt_by_claude/backend/internal/model"
+	"github.com/qingbingwei/latex_ppt_by_claude/backend/internal/repository"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrTeamNotFound  = errors.New("team not found")
+	ErrNotTeamMember = errors.New("not a member of this team")
+	ErrNotTeamOwner  = errors.New("only the team owner can manage members")
+	ErrUserNotFound  = errors.New("user not found")
+	ErrAlreadyMember = errors.New("user is already a member of this team")
+	ErrRemoveOwner   = errors.New("the team owner cannot be removed")
+)
+
+type TeamService struct {
+	teamRepo *repository.TeamRepository
+	userRepo *repository.UserRepository
+}
+
+func NewTeamService(teamRepo *repository.TeamRepository, userRepo *repository.UserRepository) *TeamService {
+	return &TeamService{
+		teamRepo: teamRepo,
+		userRepo: userRepo,
+	}
+}
+
+func (s *TeamService) CreateTeam(userID uint, name string) (*model.Team, error) {
+	team := &model.Team{Name: name, OwnerID: userID}
+	if err := s.teamRepo.Create(team); err != nil {
+		return nil, err
+	}
+	return team, nil
+}
+
+func (s *TeamService) ListTeams(userID uint) ([]model.Team, error) {
+	return s.teamRepo.FindByUserID(userID)
+}
+
+// GetMembers 返回团队成员，只有成员可以查看
+func (s *TeamService) GetMembers(userID, teamID uint) ([]model.TeamMember, error) {
+	if _, err := s.findTeam(teamID); err != nil {
+		return nil, err
+	}
+	if err := s.requireMember(teamID, userID); err != nil {
+		return nil, err
+	}
+	return s.teamRepo.FindMembers(teamID)
+}
+
+// AddMember 由团队 owner 按用户名添加成员
+func (s *TeamService) AddMember(userID, teamID uint, username string) (*model.TeamMember, error) {
+	team, err := s.findTeam(teamID)
+	if err != nil {
+		return nil, err
+	}
+	if team.OwnerID != userID {
+		return nil, ErrNotTeamOwner
+	}
+
+	user, err := s.userRepo.FindByUsername(username)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	if ok, err := s.teamRepo.IsMember(teamID, user.ID); err != nil {
+		return nil, err
+	} else if ok {
+		return nil, ErrAlreadyMember
+	}
+
+	member := &model.TeamMember{TeamID: teamID, UserID: user.ID, Role: "member"}
+	if err := s.teamRepo.AddMember(member); err != nil {
+		return nil, err
+	}
+	return member, nil
+}
+
+// RemoveMember 由团队 owner 移除成员，成员也可以移除自己
+func (s *TeamService) RemoveMember(userID, teamID, memberID uint) error {
+	team, err := s.findTeam(teamID)
+	if err != nil {
+		return err
+	}
+	if team.OwnerID != userID && memberID != userID {
+		return ErrNotTeamOwner
+	}
+	if memberID == team.OwnerID {
+		return ErrRemoveOwner
+	}
+	return s.teamRepo.RemoveMember(teamID, memberID)
+}
+
+// IsMember 判断用户是否属于团队
+func (s *TeamService) IsMember(teamID, userID uint) (bool, error) {
+	return s.teamRepo.IsMember(teamID, userID)
+}
+
+func (s *TeamService) findTeam(teamID uint) (*model.Team, error) {
+	team, err := s.teamRepo.FindByID(teamID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrTeamNotFound
+	}
+	return team, err
+}
+
+func (s *TeamService) requireMember(teamID, userID uint) error {
+	ok, err := s.teamRepo.IsMember(teamID, userID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrNotTeamMember
+	}
+	return nil
+}
@@ -0,0 +1,543 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/qingbingwei/latex_ppt_by_claude/backend/internal/model"
+	"github.com/qingbingwei/latex_ppt_by_claude/backend/internal/repository"
+	"github.com/qingbingwei/latex_ppt_by_claude/backend/pkg/latex"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrTemplateNotFound = errors.New("template not found")
+	ErrTemplateExists   = errors.New("template name already exists")
+	ErrNotTemplateOwner = errors.New("only the template owner can modify it")
+	ErrInvalidTemplate  = errors.New("invalid template")
+	ErrTemplateInUse    = errors.New("template is used by existing PPTs")
+)
+
+const (
+	maxAssetBytes      = 10 << 20
+	maxTotalAssetBytes = 50 << 20
+)
+
+var templateNameRe = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{1,49}$`)
+
+// TemplateInput 是上传模板或新版本的内容
+type TemplateInput struct {
+	Name        string
+	Description string
+	Engine      string
+	TeamID      *uint
+	Source      string
+	Assets      map[string][]byte
+}
+
+// ResolvedTemplate 是生成时实际使用的模板内容
+type ResolvedTemplate struct {
+	Name    string
+	Version int // 内置模板为 0
+	Source  string
+	Engine  string
+	Assets  map[string][]byte
+}
+
+// TemplateDetail 是模板及其全部版本
+type TemplateDetail struct {
+	model.Template
+	Versions []model.TemplateVersion `json:"versions"`
+}
+
+// TemplateService 管理用户上传的模板，并统一解析内置模板与自定义模板
+type TemplateService struct {
+	templateRepo *repository.TemplateRepository
+	teamService  *TeamService
+	compiler     *latex.Compiler
+	rasterizer   *latex.Rasterizer
+	assetDir     string
+	previewDir   string
+	// previewMu 串行化预览渲染，避免同一模板被并发编译
+	previewMu sync.Mutex
+}
+
+func NewTemplateService(
+	templateRepo *repository.TemplateRepository,
+	teamService *TeamService,
+	compiler *latex.Compiler,
+	rasterizer *latex.Rasterizer,
+	assetDir string,
+	previewDir string,
+) *TemplateService {
+	return &TemplateService{
+		templateRepo: templateRepo,
+		teamService:  teamService,
+		compiler:     compiler,
+		rasterizer:   rasterizer,
+		assetDir:     assetDir,
+		previewDir:   previewDir,
+	}
+}
+
+// Create 校验模板能用示例内容编译通过后保存为版本 1
+func (s *TemplateService) Create(ctx context.Context, userID uint, input TemplateInput) (*model.Template, error) {
+	if !templateNameRe.MatchString(input.Name) || slices.Contains(latex.ListTemplates(), input.Name) {
+		return nil, fmt.Errorf("%w: name must match %s and not be a built-in template", ErrInvalidTemplate, templateNameRe)
+	}
+	// 名称只需在自己的模板中唯一，其他用户的同名模板不影响创建
+	if _, err := s.templateRepo.FindByOwnerAndName(userID, input.Name); err == nil {
+		return nil, ErrTemplateExists
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if input.TeamID != nil {
+		if err := s.teamService.requireMember(*input.TeamID, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	tmpl := &model.Template{
+		Name:        input.Name,
+		Description: input.Description,
+		OwnerID:     userID,
+		TeamID:      input.TeamID,
+		Engine:      input.Engine,
+		Version:     1,
+	}
+	version, err := s.buildVersion(ctx, userID, tmpl, 1, input)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.templateRepo.Create(tmpl, version); err != nil {
+		os.RemoveAll(version.AssetDir)
+		return nil, err
+	}
+	return tmpl, nil
+}
+
+// AddVersion 上传模板的新版本，同样需要通过编译校验
+func (s *TemplateService) AddVersion(ctx context.Context, userID, templateID uint, input TemplateInput) (*model.TemplateVersion, error) {
+	tmpl, err := s.findOwned(userID, templateID)
+	if err != nil {
+		return nil, err
+	}
+	if input.Engine != "" {
+		tmpl.Engine = input.Engine
+	}
+	if input.Description != "" {
+		tmpl.Description = input.Description
+	}
+
+	version, err := s.buildVersion(ctx, userID, tmpl, tmpl.Version+1, input)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.templateRepo.AddVersion(tmpl, version); err != nil {
+		os.RemoveAll(version.AssetDir)
+		return nil, err
+	}
+	return version, nil
+}
+
+// buildVersion 校验模板并把资源写入磁盘
+func (s *TemplateService) buildVersion(ctx context.Context, userID uint, tmpl *model.Template, number int, input TemplateInput) (*model.TemplateVersion, error) {
+	if err := validateTemplateInput(input); err != nil {
+		return nil, err
+	}
+
+	diags, err := s.validateCompile(ctx, input.Source, input.Assets, tmpl.Engine)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(input.Assets))
+	for name := range input.Assets {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	assets, err := json.Marshal(names)
+	if err != nil {
+		return nil, err
+	}
+
+	version := &model.TemplateVersion{
+		TemplateID:  tmpl.ID,
+		Version:     number,
+		Source:      input.Source,
+		Assets:      string(assets),
+		Diagnostics: diags,
+		CreatedBy:   userID,
+	}
+	if len(input.Assets) > 0 {
+		version.AssetDir = filepath.Join(s.templateDir(tmpl), "v"+strconv.Itoa(number))
+		if err := writeAssets(version.AssetDir, input.Assets); err != nil {
+			os.RemoveAll(version.AssetDir)
+			return nil, err
+		}
+	}
+	return version, nil
+}
+
+func validateTemplateInput(input TemplateInput) error {
+	for _, required := range []string{`\documentclass`, `\begin{document}`, `\end{document}`, "{{CONTENT}}"} {
+		if !strings.Contains(input.Source, required) {
+			return fmt.Errorf("%w: source must contain %s", ErrInvalidTemplate, required)
+		}
+	}
+	if input.Engine != "" {
+		if _, err := latex.LookupEngine(input.Engine); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidTemplate, err)
+		}
+	}
+
+	total := 0
+	for name, data := range input.Assets {
+		if !filepath.IsLocal(name) || name == "main.tex" {
+			return fmt.Errorf("%w: invalid asset name %q", ErrInvalidTemplate, name)
+		}
+		if len(data) > maxAssetBytes {
+			return fmt.Errorf("%w: asset %q exceeds %d MB", ErrInvalidTemplate, name, maxAssetBytes>>20)
+		}
+		total += len(data)
+	}
+	if total > maxTotalAssetBytes {
+		return fmt.Errorf("%w: assets exceed %d MB in total", ErrInvalidTemplate, maxTotalAssetBytes>>20)
+	}
+	return nil
+}
+
+// validateCompile 用示例内容渲染并编译模板，失败时返回 *latex.CompileError
+func (s *TemplateService) validateCompile(ctx context.Context, source string, assets map[string][]byte, engine string) (model.Diagnostics, error) {
+	content := latex.RenderTemplate(source, latex.TemplateData{
+		Title:     "Template Check",
+		Subtitle:  "Sample Subtitle",
+		Author:    "Author",
+		Institute: "Institute",
+		Content:   latex.SampleContent,
+	})
+
+	filename := fmt.Sprintf("template_check_%d.pdf", time.Now().UnixNano())
+	result, err := s.compiler.CompileWithOptions(ctx, content, filename, latex.Options{
+		PreferredEngine: engine,
+		Assets:          assets,
+	})
+	if err != nil {
+		return nil, err
+	}
+	os.Remove(result.PDFPath)
+	return result.Diagnostics, nil
+}
+
+func writeAssets(dir string, assets map[string][]byte) error {
+	for name, data := range assets {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// List 返回用户可见的自定义模板
+func (s *TemplateService) List(userID uint) ([]model.Template, error) {
+	return s.templateRepo.FindVisible(userID)
+}
+
+// Get 返回模板及其版本，不可见的模板视为不存在
+func (s *TemplateService) Get(userID, templateID uint) (*TemplateDetail, error) {
+	tmpl, err := s.findVisible(userID, templateID)
+	if err != nil {
+		return nil, err
+	}
+	versions, err := s.templateRepo.FindVersions(tmpl.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &TemplateDetail{Template: *tmpl, Versions: versions}, nil
+}
+
+// Share 把模板共享给团队，teamID 为 nil 时取消共享
+func (s *TemplateService) Share(userID, templateID uint, teamID *uint) (*model.Template, error) {
+	tmpl, err := s.findOwned(userID, templateID)
+	if err != nil {
+		return nil, err
+	}
+	if teamID != nil {
+		if err := s.teamService.requireMember(*teamID, userID); err != nil {
+			return nil, err
+		}
+	}
+	tmpl.TeamID = teamID
+	if err := s.templateRepo.Update(tmpl); err != nil {
+		return nil, err
+	}
+	return tmpl, nil
+}
+
+// Delete 删除模板及其全部版本和资源。仍有 PPT 使用该模板时返回 ErrTemplateInUse，
+// 否则这些 PPT 重新编译、导出时将无法解析模板
+func (s *TemplateService) Delete(userID, templateID uint) error {
+	tmpl, err := s.findOwned(userID, templateID)
+	if err != nil {
+		return err
+	}
+	count, err := s.templateRepo.CountReferences(tmpl)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return fmt.Errorf("%w: %d PPT(s)", ErrTemplateInUse, count)
+	}
+	if err := s.templateRepo.Delete(tmpl.ID); err != nil {
+		return err
+	}
+	os.RemoveAll(s.templateDir(tmpl))
+	return nil
+}
+
+// templateDir 是模板资源文件的目录，不同所有者的模板可以同名，因此按所有者分开
+func (s *TemplateService) templateDir(tmpl *model.Template) string {
+	return filepath.Join(s.assetDir, strconv.FormatUint(uint64(tmpl.OwnerID), 10), tmpl.Name)
+}
+
+// Names 返回内置模板和用户可见的自定义模板名称
+func (s *TemplateService) Names(userID uint) []string {
+	names := latex.ListTemplates()
+	if templates, err := s.templateRepo.FindVisible(userID); err == nil {
+		for _, t := range templates {
+			// 自己的模板和团队共享的模板可能同名
+			if !slices.Contains(names, t.Name) {
+				names = append(names, t.Name)
+			}
+		}
+	}
+	return names
+}
+
+// Resolve 解析 GenerateRequest.Template：内置模板名、自定义模板名或 "name@版本号"
+func (s *TemplateService) Resolve(userID uint, ref string) (*ResolvedTemplate, error) {
+	name, versionStr, pinned := strings.Cut(ref, "@")
+	if name == "" {
+		name = "default"
+	}
+
+	if slices.Contains(latex.ListTemplates(), name) {
+		return &ResolvedTemplate{
+			Name:   name,
+			Source: latex.GetTemplate(name),
+			Engine: latex.TemplateEngine(name),
+		}, nil
+	}
+
+	tmpl, err := s.findVisibleByName(userID, name)
+	if err != nil {
+		return nil, err
+	}
+
+	number := tmpl.Version
+	if pinned {
+		if number, err = strconv.Atoi(versionStr); err != nil {
+			return nil, ErrTemplateNotFound
+		}
+	}
+	version, err := s.templateRepo.FindVersion(tmpl.ID, number)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrTemplateNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	assets, err := readAssets(version)
+	if err != nil {
+		return nil, err
+	}
+	return &ResolvedTemplate{
+		Name:    tmpl.Name,
+		Version: version.Version,
+		Source:  version.Source,
+		Engine:  tmpl.Engine,
+		Assets:  assets,
+	}, nil
+}
+
+// ResolveFor 解析 PPT 生成时使用的模板版本，之后模板发布的新版本不影响已有的 PPT
+func (s *TemplateService) ResolveFor(ppt *model.PPTRecord) (*ResolvedTemplate, error) {
+	ref := ppt.Template
+	if !strings.Contains(ref, "@") && ppt.TemplateVersion > 0 {
+		ref = fmt.Sprintf("%s@%d", ref, ppt.TemplateVersion)
+	}
+	return s.Resolve(ppt.UserID, ref)
+}
+
+const previewDPI = 96
+
+// Preview 返回模板预览图（标题页与示例内容页横向拼接）的路径。
+// 预览图按模板内容哈希缓存在磁盘上，模板或其资源变化后重新生成
+func (s *TemplateService) Preview(ctx context.Context, userID uint, name string) (string, error) {
+	tmpl, err := s.Resolve(userID, name)
+	if err != nil {
+		return "", err
+	}
+
+	key := previewKey(tmpl)
+	path := filepath.Join(s.previewDir, key+".png")
+	if _, err := os.Stat(path); err == nil {
+		return path, nil
+	}
+
+	s.previewMu.Lock()
+	defer s.previewMu.Unlock()
+	if _, err := os.Stat(path); err == nil {
+		return path, nil
+	}
+
+	content := latex.RenderTemplate(tmpl.Source, latex.TemplateData{
+		Title:     tmpl.Name,
+		Subtitle:  "Template Preview",
+		Author:    "Author",
+		Institute: "Institute",
+		Content:   latex.SampleContent,
+	})
+	result, err := s.compiler.CompileWithOptions(ctx, content, "preview_"+key+".pdf", latex.Options{
+		PreferredEngine: tmpl.Engine,
+		Assets:          tmpl.Assets,
+	})
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(result.PDFPath)
+
+	if err := os.MkdirAll(s.previewDir, 0755); err != nil {
+		return "", err
+	}
+	workDir, err := os.MkdirTemp(s.previewDir, "render-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(workDir)
+
+	pages, err := s.rasterizer.RenderPages(ctx, result.PDFPath, workDir, previewDPI)
+	if err != nil {
+		return "", err
+	}
+	// 标题页和最后一页（示例内容页）
+	selected := []string{pages[0]}
+	if len(pages) > 1 {
+		selected = append(selected, pages[len(pages)-1])
+	}
+	if err := latex.ComposePNG(selected, path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// previewKey 由模板源码、资源和偏好引擎计算，内容不变时复用已生成的预览
+func previewKey(tmpl *ResolvedTemplate) string {
+	h := sha256.New()
+	io.WriteString(h, tmpl.Name+"\x00"+tmpl.Engine+"\x00"+tmpl.Source)
+	names := make([]string, 0, len(tmpl.Assets))
+	for name := range tmpl.Assets {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		io.WriteString(h, "\x00"+name+"\x00")
+		h.Write(tmpl.Assets[name])
+	}
+	return tmpl.Name + "-" + hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+func readAssets(version *model.TemplateVersion) (map[string][]byte, error) {
+	var names []string
+	if version.Assets != "" {
+		if err := json.Unmarshal([]byte(version.Assets), &names); err != nil {
+			return nil, err
+		}
+	}
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	assets := make(map[string][]byte, len(names))
+	for _, name := range names {
+		data, err := os.ReadFile(filepath.Join(version.AssetDir, name))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read template asset %s: %w", name, err)
+		}
+		assets[name] = data
+	}
+	return assets, nil
+}
+
+func (s *TemplateService) canView(userID uint, tmpl *model.Template) bool {
+	if tmpl.OwnerID == userID {
+		return true
+	}
+	if tmpl.TeamID == nil {
+		return false
+	}
+	ok, err := s.teamService.IsMember(*tmpl.TeamID, userID)
+	return err == nil && ok
+}
+
+// findVisibleByName 在用户可见的模板中按名称查找，自己的模板优先，
+// 其次是团队共享的模板中最早创建的一个
+func (s *TemplateService) findVisibleByName(userID uint, name string) (*model.Template, error) {
+	templates, err := s.templateRepo.FindVisibleByName(userID, name)
+	if err != nil {
+		return nil, err
+	}
+	if len(templates) == 0 {
+		return nil, ErrTemplateNotFound
+	}
+	for i := range templates {
+		if templates[i].OwnerID == userID {
+			return &templates[i], nil
+		}
+	}
+	return &templates[0], nil
+}
+
+func (s *TemplateService) findVisible(userID, templateID uint) (*model.Template, error) {
+	tmpl, err := s.templateRepo.FindByID(templateID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrTemplateNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !s.canView(userID, tmpl) {
+		return nil, ErrTemplateNotFound
+	}
+	return tmpl, nil
+}
+
+func (s *TemplateService) findOwned(userID, templateID uint) (*model.Template, error) {
+	tmpl, err := s.findVisible(userID, templateID)
+	if err != nil {
+		return nil, err
+	}
+	if tmpl.OwnerID != userID {
+		return nil, ErrNotTemplateOwner
+	}
+	return tmpl, nil
+}
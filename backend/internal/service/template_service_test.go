@@ -0,0 +1,91 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/qingbingwei/latex_ppt_by_claude/backend/internal/model"
+	"github.com/qingbingwei/latex_ppt_by_claude/backend/pkg/vectordb"
+)
+
+// createTestTemplate 上传名为 name 的模板并发布 versions-1 个新版本，第 n 版的资源 asset.txt 内容为 "vn"
+func createTestTemplate(t *testing.T, s *testServices, name string, versions int) *model.Template {
+	t.Helper()
+	input := func(n int) TemplateInput {
+		return TemplateInput{
+			Name:   name,
+			Source: "\\documentclass{beamer}\n% v" + strconv.Itoa(n) + "\n\\begin{document}\n{{CONTENT}}\n\\end{document}\n",
+			Assets: map[string][]byte{"asset.txt": []byte("v" + strconv.Itoa(n))},
+		}
+	}
+	ctx := context.Background()
+	tmpl, err := s.templates.Create(ctx, testUserID, input(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for n := 2; n <= versions; n++ {
+		if _, err := s.templates.AddVersion(ctx, testUserID, tmpl.ID, input(n)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return tmpl
+}
+
+func TestResolveFor(t *testing.T) {
+	s := newTestServices(t, vectordb.Unavailable(errors.New("test")))
+	createTestTemplate(t, s, "mine", 2)
+
+	tests := []struct {
+		name        string
+		template    string
+		version     int
+		wantVersion int
+		wantErr     error
+	}{
+		{name: "pinned version", template: "mine", version: 1, wantVersion: 1},
+		{name: "latest version", template: "mine", version: 2, wantVersion: 2},
+		{name: "not pinned", template: "mine", wantVersion: 2},
+		{name: "explicit reference", template: "mine@1", version: 2, wantVersion: 1},
+		{name: "built-in template", template: "default", wantVersion: 0},
+		{name: "missing version", template: "mine", version: 3, wantErr: ErrTemplateNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ppt := &model.PPTRecord{UserID: testUserID, Template: tt.template, TemplateVersion: tt.version}
+			tmpl, err := s.templates.ResolveFor(ppt)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ResolveFor() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if tmpl.Version != tt.wantVersion {
+				t.Errorf("Version = %d, want %d", tmpl.Version, tt.wantVersion)
+			}
+		})
+	}
+}
+
+func TestCompileLaTeXUsesPinnedTemplate(t *testing.T) {
+	s := newTestServices(t, vectordb.Unavailable(errors.New("test")))
+	createTestTemplate(t, s, "mine", 2)
+	record := s.createPPT(t, &model.PPTRecord{Title: "Deck", Template: "mine", TemplateVersion: 1})
+
+	source := strings.Replace(testLatex, "Hello", "Changed", 1)
+	ppt, err := s.ppt.CompileLaTeX(context.Background(), record.ID, testUserID, source, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pdf, err := os.ReadFile(ppt.PDFPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 假引擎把 asset.txt 追加到 PDF，v1 说明使用的是生成时的版本而不是最新版本
+	if !strings.HasSuffix(string(pdf), "v1") {
+		t.Errorf("compiled with the wrong template version, PDF = %q", pdf)
+	}
+}
@@ -23,6 +23,7 @@ func NewClaudeClient(apiKey string) *ClaudeClient {
 
 type claudeRequest struct {
 	Model     string          `json:"model"`
+	System    string          `json:"system,omitempty"`
 	Messages  []claudeMessage `json:"messages"`
 	MaxTokens int             `json:"max_tokens"`
 	Stream    bool            `json:"stream,omitempty"`
@@ -39,34 +40,70 @@ type claudeResponse struct {
 	} `json:"content"`
 }
 
-func (c *ClaudeClient) GenerateLaTeX(ctx context.Context, prompt string) (string, error) {
-	reqBody := claudeRequest{
-		Model: "claude-3-sonnet-20240229",
+func (c *ClaudeClient) Name() string {
+	return "claude"
+}
+
+func (c *ClaudeClient) Models() []ModelInfo {
+	return []ModelInfo{
+		{Name: "claude-3-sonnet-20240229", ContextWindow: 200000, MaxTokens: 4096},
+		{Name: "claude-3-5-sonnet-20241022", ContextWindow: 200000, MaxTokens: 8192},
+		{Name: "claude-3-haiku-20240307", ContextWindow: 200000, MaxTokens: 4096},
+	}
+}
+
+// claudeStreamEvent 覆盖 Messages 流式接口中用到的事件字段
+type claudeStreamEvent struct {
+	Type  string `json:"type"`
+	Delta struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"delta"`
+	Error struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func (c *ClaudeClient) buildRequest(req Request, stream bool) claudeRequest {
+	return claudeRequest{
+		Model:  resolveModel(c, req.Model),
+		System: req.systemPrompt(),
 		Messages: []claudeMessage{
 			{
 				Role:    "user",
-				Content: fmt.Sprintf("You are an expert in creating LaTeX Beamer presentations. Generate complete, compilable LaTeX code for presentations.\n\n%s", prompt),
+				Content: req.Prompt,
 			},
 		},
-		MaxTokens: 4096,
+		MaxTokens: req.maxTokens(),
+		Stream:    stream,
 	}
+}
 
+func (c *ClaudeClient) do(ctx context.Context, reqBody claudeRequest) (*http.Response, error) {
 	jsonData, err := json.Marshal(reqBody)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/messages", bytes.NewBuffer(jsonData))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/messages", bytes.NewBuffer(jsonData))
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("x-api-key", c.apiKey)
-	req.Header.Set("anthropic-version", "2023-06-01")
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("x-api-key", c.apiKey)
+	httpReq.Header.Set("anthropic-version", "2023-06-01")
+	if reqBody.Stream {
+		httpReq.Header.Set("Accept", "text/event-stream")
+	}
 
 	client := &http.Client{}
-	resp, err := client.Do(req)
+	return client.Do(httpReq)
+}
+
+func (c *ClaudeClient) Generate(ctx context.Context, req Request) (string, error) {
+	resp, err := c.do(ctx, c.buildRequest(req, false))
 	if err != nil {
 		return "", err
 	}
@@ -92,3 +129,37 @@ func (c *ClaudeClient) GenerateLaTeX(ctx context.Context, prompt string) (string
 
 	return "", fmt.Errorf("no content in response")
 }
+
+func (c *ClaudeClient) Stream(ctx context.Context, req Request, streamCh chan<- string) error {
+	defer close(streamCh)
+
+	resp, err := c.do(ctx, c.buildRequest(req, true))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("claude API error: %s", string(body))
+	}
+
+	return readSSE(resp.Body, func(ev sseEvent) error {
+		var event claudeStreamEvent
+		if err := json.Unmarshal([]byte(ev.Data), &event); err != nil {
+			return fmt.Errorf("failed to decode stream event: %v", err)
+		}
+
+		switch event.Type {
+		case "content_block_delta":
+			if event.Delta.Type == "text_delta" {
+				return sendDelta(ctx, streamCh, event.Delta.Text)
+			}
+		case "error":
+			return fmt.Errorf("claude API error: %s: %s", event.Error.Type, event.Error.Message)
+		case "message_stop":
+			return io.EOF
+		}
+		return nil
+	})
+}
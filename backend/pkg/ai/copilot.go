@@ -8,6 +8,7 @@ import (
 	"io"
 	"log"
 	"net/http"
+	"sync"
 	"time"
 )
 
@@ -19,10 +20,12 @@ const (
 )
 
 type CopilotClient struct {
-	githubToken string
-	accessToken string
-	tokenExpiry time.Time
-	httpClient  *http.Client
+	githubToken  string
+	accessToken  string
+	tokenExpiry  time.Time
+	httpClient   *http.Client
+	streamClient *http.Client
+	mu           sync.Mutex
 }
 
 type copilotTokenResponse struct {
@@ -64,18 +67,22 @@ func NewCopilotClient(githubToken string) *CopilotClient {
 		httpClient: &http.Client{
 			Timeout: 120 * time.Second,
 		},
+		streamClient: &http.Client{},
 	}
 }
 
-// refreshToken 获取或刷新 Copilot 访问令牌
-func (c *CopilotClient) refreshToken() error {
+// refreshToken 获取或刷新 Copilot 访问令牌，返回当前有效的令牌
+func (c *CopilotClient) refreshToken() (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	if c.accessToken != "" && time.Now().Before(c.tokenExpiry) {
-		return nil // Token still valid
+		return c.accessToken, nil // Token still valid
 	}
 
 	req, err := http.NewRequest("GET", copilotTokenURL, nil)
 	if err != nil {
-		return fmt.Errorf("failed to create token request: %v", err)
+		return "", fmt.Errorf("failed to create token request: %v", err)
 	}
 
 	req.Header.Set("Authorization", "token "+c.githubToken)
@@ -85,73 +92,110 @@ func (c *CopilotClient) refreshToken() error {
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return fmt.Errorf("failed to get copilot token: %v", err)
+		return "", fmt.Errorf("failed to get copilot token: %v", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("failed to get copilot token: status %d, body: %s", resp.StatusCode, string(body))
+		return "", fmt.Errorf("failed to get copilot token: status %d, body: %s", resp.StatusCode, string(body))
 	}
 
 	var tokenResp copilotTokenResponse
 	if err := json.NewDecoder(resp.Body).Decode(&tokenResp); err != nil {
-		return fmt.Errorf("failed to decode token response: %v", err)
+		return "", fmt.Errorf("failed to decode token response: %v", err)
 	}
 
 	c.accessToken = tokenResp.Token
 	c.tokenExpiry = time.Unix(tokenResp.ExpiresAt, 0).Add(-5 * time.Minute) // 提前5分钟过期
 
 	log.Printf("Copilot token refreshed, expires at: %v", c.tokenExpiry)
-	return nil
+	return c.accessToken, nil
 }
 
-func (c *CopilotClient) GenerateLaTeX(ctx context.Context, prompt string) (string, error) {
-	// 刷新令牌
-	if err := c.refreshToken(); err != nil {
-		return "", fmt.Errorf("failed to refresh token: %v", err)
+func (c *CopilotClient) Name() string {
+	return "copilot"
+}
+
+func (c *CopilotClient) Models() []ModelInfo {
+	return []ModelInfo{
+		{Name: "gpt-4o", ContextWindow: 128000, MaxTokens: 4096},
+		{Name: "claude-3.5-sonnet", ContextWindow: 200000, MaxTokens: 8192},
+		{Name: "o1-mini", ContextWindow: 128000, MaxTokens: 4096},
 	}
+}
 
-	log.Printf("Generating LaTeX with GitHub Copilot")
+type copilotStreamChunk struct {
+	Choices []struct {
+		Delta struct {
+			Content string `json:"content"`
+		} `json:"delta"`
+		FinishReason string `json:"finish_reason"`
+	} `json:"choices"`
+}
 
-	chatReq := copilotChatRequest{
-		Model: "gpt-4o", // Copilot 支持的模型
+func (c *CopilotClient) buildRequest(req Request, stream bool) copilotChatRequest {
+	return copilotChatRequest{
+		Model: resolveModel(c, req.Model),
 		Messages: []copilotChatMessage{
 			{
 				Role:    "system",
-				Content: "You are an expert in creating LaTeX Beamer presentations. Generate complete, compilable LaTeX code for presentations.",
+				Content: req.systemPrompt(),
 			},
 			{
 				Role:    "user",
-				Content: prompt,
+				Content: req.Prompt,
 			},
 		},
-		Temperature: 0.7,
-		MaxTokens:   4096,
-		Stream:      false,
+		Temperature: req.temperature(),
+		MaxTokens:   req.maxTokens(),
+		Stream:      stream,
+	}
+}
+
+func (c *CopilotClient) do(ctx context.Context, client *http.Client, chatReq copilotChatRequest) (*http.Response, error) {
+	// 刷新令牌
+	accessToken, err := c.refreshToken()
+	if err != nil {
+		return nil, fmt.Errorf("failed to refresh token: %v", err)
 	}
 
 	reqBody, err := json.Marshal(chatReq)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal request: %v", err)
+		return nil, fmt.Errorf("failed to marshal request: %v", err)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", copilotChatURL, bytes.NewReader(reqBody))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", copilotChatURL, bytes.NewReader(reqBody))
 	if err != nil {
-		return "", fmt.Errorf("failed to create request: %v", err)
+		return nil, fmt.Errorf("failed to create request: %v", err)
 	}
 
-	req.Header.Set("Authorization", "Bearer "+c.accessToken)
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Accept", "application/json")
-	req.Header.Set("Editor-Version", copilotEditorVersion)
-	req.Header.Set("Editor-Plugin-Version", copilotEditorPlugin)
-	req.Header.Set("Copilot-Integration-Id", "vscode-chat")
-	req.Header.Set("Openai-Intent", "conversation-panel")
+	accept := "application/json"
+	if chatReq.Stream {
+		accept = "text/event-stream"
+	}
 
-	resp, err := c.httpClient.Do(req)
+	httpReq.Header.Set("Authorization", "Bearer "+accessToken)
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Accept", accept)
+	httpReq.Header.Set("Editor-Version", copilotEditorVersion)
+	httpReq.Header.Set("Editor-Plugin-Version", copilotEditorPlugin)
+	httpReq.Header.Set("Copilot-Integration-Id", "vscode-chat")
+	httpReq.Header.Set("Openai-Intent", "conversation-panel")
+
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %v", err)
+	}
+	return resp, nil
+}
+
+func (c *CopilotClient) Generate(ctx context.Context, req Request) (string, error) {
+	log.Printf("Generating LaTeX with GitHub Copilot")
+
+	resp, err := c.do(ctx, c.httpClient, c.buildRequest(req, false))
 	if err != nil {
-		return "", fmt.Errorf("failed to send request: %v", err)
+		return "", err
 	}
 	defer resp.Body.Close()
 
@@ -174,3 +218,38 @@ func (c *CopilotClient) GenerateLaTeX(ctx context.Context, prompt string) (strin
 	log.Printf("Copilot response received, tokens used: %d", chatResp.Usage.TotalTokens)
 	return chatResp.Choices[0].Message.Content, nil
 }
+
+func (c *CopilotClient) Stream(ctx context.Context, req Request, streamCh chan<- string) error {
+	defer close(streamCh)
+
+	log.Printf("Streaming LaTeX with GitHub Copilot")
+
+	// 流式响应耗时不固定，不使用 httpClient 的整体超时，由 ctx 控制取消
+	resp, err := c.do(ctx, c.streamClient, c.buildRequest(req, true))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		log.Printf("Copilot API error: status %d, body: %s", resp.StatusCode, string(body))
+		return fmt.Errorf("copilot API error: status %d", resp.StatusCode)
+	}
+
+	return readSSE(resp.Body, func(ev sseEvent) error {
+		if ev.Data == "[DONE]" {
+			return io.EOF
+		}
+
+		var chunk copilotStreamChunk
+		if err := json.Unmarshal([]byte(ev.Data), &chunk); err != nil {
+			return fmt.Errorf("failed to decode stream chunk: %v", err)
+		}
+
+		if len(chunk.Choices) > 0 {
+			return sendDelta(ctx, streamCh, chunk.Choices[0].Delta.Content)
+		}
+		return nil
+	})
+}
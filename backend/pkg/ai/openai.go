@@ -2,7 +2,9 @@ package ai
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 
 	"github.com/sashabaranov/go-openai"
@@ -30,27 +32,41 @@ func NewOpenAIClient(apiKey, baseURL string) *OpenAIClient {
 	}
 }
 
-func (c *OpenAIClient) GenerateLaTeX(ctx context.Context, prompt string) (string, error) {
-	log.Printf("Generating LaTeX with model: %s, baseURL: %s", c.model, c.baseURL)
-
-	resp, err := c.client.CreateChatCompletion(
-		ctx,
-		openai.ChatCompletionRequest{
-			Model: c.model,
-			Messages: []openai.ChatCompletionMessage{
-				{
-					Role:    openai.ChatMessageRoleSystem,
-					Content: "You are an expert in creating LaTeX Beamer presentations. Generate complete, compilable LaTeX code for presentations.",
-				},
-				{
-					Role:    openai.ChatMessageRoleUser,
-					Content: prompt,
-				},
+func (c *OpenAIClient) Name() string {
+	return "openai"
+}
+
+func (c *OpenAIClient) Models() []ModelInfo {
+	return []ModelInfo{
+		{Name: c.model, ContextWindow: 128000, MaxTokens: 16384},
+		{Name: "gpt-4o-mini", ContextWindow: 128000, MaxTokens: 16384},
+		{Name: "gpt-4-turbo", ContextWindow: 128000, MaxTokens: 4096},
+	}
+}
+
+func (c *OpenAIClient) chatRequest(req Request) openai.ChatCompletionRequest {
+	return openai.ChatCompletionRequest{
+		Model: resolveModel(c, req.Model),
+		Messages: []openai.ChatCompletionMessage{
+			{
+				Role:    openai.ChatMessageRoleSystem,
+				Content: req.systemPrompt(),
+			},
+			{
+				Role:    openai.ChatMessageRoleUser,
+				Content: req.Prompt,
 			},
-			Temperature: 0.7,
 		},
-	)
+		MaxTokens:   req.maxTokens(),
+		Temperature: float32(req.temperature()),
+	}
+}
+
+func (c *OpenAIClient) Generate(ctx context.Context, req Request) (string, error) {
+	chatReq := c.chatRequest(req)
+	log.Printf("Generating LaTeX with model: %s, baseURL: %s", chatReq.Model, c.baseURL)
 
+	resp, err := c.client.CreateChatCompletion(ctx, chatReq)
 	if err != nil {
 		log.Printf("OpenAI API error: %v", err)
 		return "", fmt.Errorf("AI API error: %v", err)
@@ -63,26 +79,13 @@ func (c *OpenAIClient) GenerateLaTeX(ctx context.Context, prompt string) (string
 	return resp.Choices[0].Message.Content, nil
 }
 
-func (c *OpenAIClient) StreamGenerateLaTeX(ctx context.Context, prompt string, streamCh chan<- string) error {
-	stream, err := c.client.CreateChatCompletionStream(
-		ctx,
-		openai.ChatCompletionRequest{
-			Model: c.model,
-			Messages: []openai.ChatCompletionMessage{
-				{
-					Role:    openai.ChatMessageRoleSystem,
-					Content: "You are an expert in creating LaTeX Beamer presentations. Generate complete, compilable LaTeX code for presentations.",
-				},
-				{
-					Role:    openai.ChatMessageRoleUser,
-					Content: prompt,
-				},
-			},
-			Temperature: 0.7,
-			Stream:      true,
-		},
-	)
+func (c *OpenAIClient) Stream(ctx context.Context, req Request, streamCh chan<- string) error {
+	defer close(streamCh)
+
+	chatReq := c.chatRequest(req)
+	chatReq.Stream = true
 
+	stream, err := c.client.CreateChatCompletionStream(ctx, chatReq)
 	if err != nil {
 		return err
 	}
@@ -90,13 +93,17 @@ func (c *OpenAIClient) StreamGenerateLaTeX(ctx context.Context, prompt string, s
 
 	for {
 		response, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
 		if err != nil {
-			close(streamCh)
 			return err
 		}
 
 		if len(response.Choices) > 0 {
-			streamCh <- response.Choices[0].Delta.Content
+			if err := sendDelta(ctx, streamCh, response.Choices[0].Delta.Content); err != nil {
+				return err
+			}
 		}
 	}
 }
@@ -0,0 +1,156 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+const defaultSystemPrompt = "You are an expert in creating LaTeX Beamer presentations. Generate complete, compilable LaTeX code for presentations."
+
+const defaultMaxTokens = 4096
+
+var (
+	ErrProviderNotFound      = errors.New("AI provider not found")
+	ErrNoProvider            = errors.New("no AI client available")
+	ErrStreamingNotSupported = errors.New("streaming not supported by this provider")
+)
+
+// Request 描述一次模型调用，各 Provider 自行映射到对应的 API 格式
+type Request struct {
+	Model        string
+	SystemPrompt string
+	Prompt       string
+	MaxTokens    int
+	Temperature  float64
+}
+
+// ModelInfo 模型元信息
+type ModelInfo struct {
+	Name          string `json:"name"`
+	ContextWindow int    `json:"context_window"`
+	MaxTokens     int    `json:"max_tokens"`
+}
+
+// Provider 是所有 LLM 后端需要实现的接口
+type Provider interface {
+	// Name 返回注册表中使用的唯一名称
+	Name() string
+	// Models 返回该 Provider 支持的模型列表，第一个为默认模型
+	Models() []ModelInfo
+	// Generate 同步生成完整回复
+	Generate(ctx context.Context, req Request) (string, error)
+	// Stream 将增量文本写入 streamCh，结束时关闭 streamCh
+	Stream(ctx context.Context, req Request, streamCh chan<- string) error
+}
+
+// ProviderInfo 用于对外展示已注册的 Provider
+type ProviderInfo struct {
+	Name      string      `json:"name"`
+	IsDefault bool        `json:"is_default"`
+	Models    []ModelInfo `json:"models"`
+}
+
+// Registry 按名称管理 Provider
+type Registry struct {
+	mu          sync.RWMutex
+	providers   map[string]Provider
+	defaultName string
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		providers: make(map[string]Provider),
+	}
+}
+
+// Register 注册 Provider，第一个注册的 Provider 成为默认值
+func (r *Registry) Register(p Provider) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.providers[p.Name()] = p
+	if r.defaultName == "" {
+		r.defaultName = p.Name()
+	}
+}
+
+func (r *Registry) SetDefault(name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.providers[name]; !ok {
+		return fmt.Errorf("%w: %s", ErrProviderNotFound, name)
+	}
+	r.defaultName = name
+	return nil
+}
+
+// Get 按名称查找 Provider，name 为空时返回默认 Provider
+func (r *Registry) Get(name string) (Provider, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if name == "" {
+		name = r.defaultName
+	}
+	if name == "" {
+		return nil, ErrNoProvider
+	}
+
+	p, ok := r.providers[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrProviderNotFound, name)
+	}
+	return p, nil
+}
+
+func (r *Registry) List() []ProviderInfo {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	infos := make([]ProviderInfo, 0, len(r.providers))
+	for name, p := range r.providers {
+		infos = append(infos, ProviderInfo{
+			Name:      name,
+			IsDefault: name == r.defaultName,
+			Models:    p.Models(),
+		})
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos
+}
+
+// resolveModel 返回请求中的模型，未指定时使用 Provider 的默认模型
+func resolveModel(p Provider, model string) string {
+	if model != "" {
+		return model
+	}
+	if models := p.Models(); len(models) > 0 {
+		return models[0].Name
+	}
+	return ""
+}
+
+func (req Request) systemPrompt() string {
+	if req.SystemPrompt != "" {
+		return req.SystemPrompt
+	}
+	return defaultSystemPrompt
+}
+
+func (req Request) maxTokens() int {
+	if req.MaxTokens > 0 {
+		return req.MaxTokens
+	}
+	return defaultMaxTokens
+}
+
+func (req Request) temperature() float64 {
+	if req.Temperature > 0 {
+		return req.Temperature
+	}
+	return 0.7
+}
@@ -0,0 +1,77 @@
+package ai
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"strings"
+)
+
+// sseEvent 是 text/event-stream 中的一条消息
+type sseEvent struct {
+	Event string
+	Data  string
+}
+
+// readSSE 逐条解析 Server-Sent Events，直到流结束或 handle 返回错误。
+// handle 返回 io.EOF 表示提前正常结束。
+func readSSE(r io.Reader, handle func(ev sseEvent) error) error {
+	err := scanSSE(r, handle)
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
+	return err
+}
+
+func scanSSE(r io.Reader, handle func(ev sseEvent) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	var ev sseEvent
+	var data []string
+	dispatch := func() error {
+		if len(data) == 0 {
+			ev = sseEvent{}
+			return nil
+		}
+		ev.Data = strings.Join(data, "\n")
+		err := handle(ev)
+		ev = sseEvent{}
+		data = data[:0]
+		return err
+	}
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if err := dispatch(); err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, ":"):
+			// 注释行（心跳）
+		case strings.HasPrefix(line, "event:"):
+			ev.Event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			data = append(data, strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return dispatch()
+}
+
+// sendDelta 把增量文本写入 streamCh，ctx 取消时返回
+func sendDelta(ctx context.Context, streamCh chan<- string, delta string) error {
+	if delta == "" {
+		return nil
+	}
+	select {
+	case streamCh <- delta:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,196 @@
+package diff
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+type opKind byte
+
+const (
+	opEqual  opKind = ' '
+	opDelete opKind = '-'
+	opInsert opKind = '+'
+)
+
+// edit 是编辑脚本中的一行，a、b 为该行之前两边已经处理的行数
+type edit struct {
+	kind opKind
+	line string
+	a, b int
+}
+
+// Unified 返回 a 到 b 的逐行 unified diff，context 为每个变更前后保留的上下文行数。
+// 内容相同时返回空字符串
+func Unified(fromName, toName, a, b string, context int) string {
+	edits := lineDiff(splitLines(a), splitLines(b))
+
+	var out strings.Builder
+	for i := 0; i < len(edits); {
+		if edits[i].kind == opEqual {
+			i++
+			continue
+		}
+
+		// 间隔不超过 2*context 行的变更合并到同一个 hunk
+		end := i
+		for end < len(edits) {
+			if edits[end].kind != opEqual {
+				end++
+				continue
+			}
+			j := end
+			for j < len(edits) && edits[j].kind == opEqual {
+				j++
+			}
+			if j == len(edits) || j-end > 2*context {
+				break
+			}
+			end = j
+		}
+
+		start, stop := max(i-context, 0), min(end+context, len(edits))
+		if out.Len() == 0 {
+			fmt.Fprintf(&out, "--- %s\n+++ %s\n", fromName, toName)
+		}
+		writeHunk(&out, edits[start:stop])
+		i = stop
+	}
+	return out.String()
+}
+
+func writeHunk(out *strings.Builder, edits []edit) {
+	var aCount, bCount int
+	for _, e := range edits {
+		if e.kind != opInsert {
+			aCount++
+		}
+		if e.kind != opDelete {
+			bCount++
+		}
+	}
+	fmt.Fprintf(out, "@@ -%s +%s @@\n", hunkRange(edits[0].a, aCount), hunkRange(edits[0].b, bCount))
+	for _, e := range edits {
+		out.WriteByte(byte(e.kind))
+		out.WriteString(e.line)
+		out.WriteByte('\n')
+	}
+}
+
+// hunkRange 按 unified 格式输出起始行和行数，行数为 0 时起始行为变更位置之前的一行
+func hunkRange(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
+
+// noNewline 是 unified diff 中最后一行没有换行符时的标记
+const noNewline = "\n\\ No newline at end of file"
+
+// splitLines 按行拆分，没有换行符的最后一行带上 noNewline 标记，
+// 因此只有末尾换行不同的两行也视为不同，并在输出时带上标记
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	lines := strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+	if !strings.HasSuffix(s, "\n") {
+		lines[len(lines)-1] += noNewline
+	}
+	return lines
+}
+
+// lineDiff 用 Myers 算法计算最短编辑脚本，先去掉相同的首尾行以减少计算量
+func lineDiff(a, b []string) []edit {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	var edits []edit
+	for i := 0; i < prefix; i++ {
+		edits = append(edits, edit{kind: opEqual, line: a[i], a: i, b: i})
+	}
+	for _, e := range myers(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]) {
+		e.a += prefix
+		e.b += prefix
+		edits = append(edits, e)
+	}
+	for i := suffix; i > 0; i-- {
+		edits = append(edits, edit{kind: opEqual, line: a[len(a)-i], a: len(a) - i, b: len(b) - i})
+	}
+	return edits
+}
+
+func myers(a, b []string) []edit {
+	n, m := len(a), len(b)
+	offset := n + m
+	v := make([]int, 2*offset+2)
+	// trace[d] 只保存第 d 轮开始前对角线 -d..d 上的状态（第 d 轮只会读到这些），
+	// 总内存为 O(D²) 而不是 O(D·(N+M))
+	var trace [][]int
+
+search:
+	for d := 0; d <= n+m; d++ {
+		trace = append(trace, slices.Clone(v[offset-d:offset+d+1]))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || k != d && v[offset+k-1] < v[offset+k+1] {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				break search
+			}
+		}
+	}
+
+	// 从终点回溯，trace[d][d+k] 是第 d 轮开始前对角线 k 上的 x
+	var edits []edit
+	x, y := n, m
+	for d := len(trace) - 1; d > 0; d-- {
+		v := trace[d]
+		k := x - y
+		prevK := k - 1
+		if k == -d || k != d && v[d+k-1] < v[d+k+1] {
+			prevK = k + 1
+		}
+		prevX := v[d+prevK]
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x--
+			y--
+			edits = append(edits, edit{kind: opEqual, line: a[x], a: x, b: y})
+		}
+		if x == prevX {
+			y--
+			edits = append(edits, edit{kind: opInsert, line: b[y], a: x, b: y})
+		} else {
+			x--
+			edits = append(edits, edit{kind: opDelete, line: a[x], a: x, b: y})
+		}
+	}
+	for x > 0 && y > 0 {
+		x--
+		y--
+		edits = append(edits, edit{kind: opEqual, line: a[x], a: x, b: y})
+	}
+	slices.Reverse(edits)
+	return edits
+}
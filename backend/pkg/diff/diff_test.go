@@ -0,0 +1,54 @@
+package diff
+
+import "testing"
+
+func TestUnified(t *testing.T) {
+	tests := []struct {
+		name    string
+		a, b    string
+		context int
+		want    string
+	}{
+		{name: "equal", a: "x\ny\n", b: "x\ny\n", context: 3, want: ""},
+		{name: "both empty", context: 3, want: ""},
+		{
+			name: "change", a: "a\nb\nc\n", b: "a\nB\nc\n", context: 1,
+			want: "--- a\n+++ b\n@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n",
+		},
+		{
+			name: "insert into empty", a: "", b: "x\n", context: 3,
+			want: "--- a\n+++ b\n@@ -0,0 +1 @@\n+x\n",
+		},
+		{
+			name: "delete all", a: "x\ny\n", b: "", context: 3,
+			want: "--- a\n+++ b\n@@ -1,2 +0,0 @@\n-x\n-y\n",
+		},
+		{
+			name: "separate hunks", a: "1\n2\n3\n4\n5\n6\n7\n8\n", b: "0\n2\n3\n4\n5\n6\n7\n9\n", context: 1,
+			want: "--- a\n+++ b\n@@ -1,2 +1,2 @@\n-1\n+0\n 2\n@@ -7,2 +7,2 @@\n 7\n-8\n+9\n",
+		},
+		{
+			name: "merged hunks", a: "1\n2\n3\n4\n", b: "0\n2\n3\n5\n", context: 1,
+			want: "--- a\n+++ b\n@@ -1,4 +1,4 @@\n-1\n+0\n 2\n 3\n-4\n+5\n",
+		},
+		{
+			name: "newline added", a: "x", b: "x\n", context: 3,
+			want: "--- a\n+++ b\n@@ -1 +1 @@\n-x\n\\ No newline at end of file\n+x\n",
+		},
+		{
+			name: "newline removed", a: "a\nx\n", b: "a\nx", context: 3,
+			want: "--- a\n+++ b\n@@ -1,2 +1,2 @@\n a\n-x\n+x\n\\ No newline at end of file\n",
+		},
+		{
+			name: "context without newline", a: "a\nx", b: "b\nx", context: 3,
+			want: "--- a\n+++ b\n@@ -1,2 +1,2 @@\n-a\n+b\n x\n\\ No newline at end of file\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Unified("a", "b", tt.a, tt.b, tt.context); got != tt.want {
+				t.Errorf("Unified(%q, %q) =\n%s\nwant\n%s", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package export
+
+import (
+	"bytes"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"path"
+	"strings"
+
+	"github.com/qingbingwei/latex_ppt_by_claude/backend/pkg/latex"
+)
+
+// Image 是可直接嵌入导出文件的位图
+type Image struct {
+	Name   string
+	Data   []byte
+	Format string // png, jpeg, gif
+	Width  int
+	Height int
+}
+
+// 未写扩展名时按 graphicx 的顺序尝试
+var imageExtensions = []string{"", ".png", ".jpg", ".jpeg", ".gif"}
+
+// FindImage 按 \includegraphics 的路径在资源中查找位图。
+// PDF、EPS 等矢量图无法直接嵌入，返回 false，由调用方退回为整页图片
+func FindImage(assets map[string][]byte, ref string) (*Image, bool) {
+	ref = strings.TrimPrefix(path.Clean(strings.TrimSpace(ref)), "./")
+	for _, ext := range imageExtensions {
+		name := ref + ext
+		data, ok := assets[name]
+		if !ok {
+			continue
+		}
+		if img, ok := DecodeImage(name, data); ok {
+			return img, true
+		}
+	}
+	return nil, false
+}
+
+// DecodeImage 读取位图的格式和尺寸
+func DecodeImage(name string, data []byte) (*Image, bool) {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || cfg.Width == 0 || cfg.Height == 0 {
+		return nil, false
+	}
+	return &Image{Name: name, Data: data, Format: format, Width: cfg.Width, Height: cfg.Height}, true
+}
+
+// needsImage 判断 frame 是否含有无法转换为原生元素的内容，需要退回为整页图片。
+// mathSupported 为 false 时行间公式也视为无法转换，tocSupported 为 true 时目录由章节生成
+func needsImage(frame latex.Frame, assets map[string][]byte, mathSupported, tocSupported bool) bool {
+	for _, name := range frame.Unsupported {
+		if name != "tableofcontents" || !tocSupported {
+			return true
+		}
+	}
+
+	var check func(blocks []latex.Block) bool
+	check = func(blocks []latex.Block) bool {
+		for _, b := range blocks {
+			switch b.Kind {
+			case latex.BlockMath:
+				if !mathSupported {
+					return true
+				}
+			case latex.BlockImage:
+				if _, found := FindImage(assets, b.Path); !found {
+					return true
+				}
+			case latex.BlockList:
+				for _, item := range b.Items {
+					if check(item.Children) {
+						return true
+					}
+				}
+			case latex.BlockColumns:
+				for _, col := range b.Columns {
+					if check(col.Blocks) {
+						return true
+					}
+				}
+			}
+			if check(b.Children) {
+				return true
+			}
+		}
+		return false
+	}
+	return check(frame.Blocks)
+}
@@ -0,0 +1,428 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"html"
+	"io/fs"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/qingbingwei/latex_ppt_by_claude/backend/pkg/latex"
+)
+
+// beamer aspectratio 选项对应的宽高比
+var aspectRatios = map[string]float64{
+	"":     4.0 / 3,
+	"43":   4.0 / 3,
+	"169":  16.0 / 9,
+	"1610": 16.0 / 10,
+	"149":  14.0 / 9,
+	"141":  1.41,
+	"54":   5.0 / 4,
+	"32":   3.0 / 2,
+}
+
+type HTMLOptions struct {
+	// Assets 是编译时可用的资源文件，用于查找 \includegraphics 引用的图片
+	Assets map[string][]byte
+	// Render 为 nil 或渲染失败时，无法转换的 frame 也尽力转换为 HTML
+	Render FrameRenderer
+	// KaTeX 是 KaTeX 发行包（katex.min.css、katex.min.js、contrib/、fonts/），打包到 zip 的 katex/ 下；
+	// 为 nil 时公式保留为 LaTeX 源码
+	KaTeX fs.FS
+}
+
+// katexFiles 是 index.html 引用的 KaTeX 文件，字体由 katex.min.css 按相对路径引用
+var katexFiles = []string{"katex.min.css", "katex.min.js", "contrib/auto-render.min.js"}
+
+const katexScripts = `<link rel="stylesheet" href="katex/katex.min.css">
+<script defer src="katex/katex.min.js"></script>
+<script defer src="katex/contrib/auto-render.min.js"
+  onload="renderMathInElement(document.body, {delimiters: [{left: '\\[', right: '\\]', display: true}, {left: '\\(', right: '\\)', display: false}], throwOnError: false})"></script>
+`
+
+// HTML 把解析后的 beamer 文档转换为浏览器中放映的幻灯片，打包为 zip：
+// index.html 内联样式和翻页脚本，图片放在 assets/ 下，公式由打包在 katex/ 下的 KaTeX 渲染，不依赖网络。
+// TikZ 等无法转换的 frame 通过 Render 退回为 slides/ 下的整页图片
+func HTML(deck *latex.Deck, opts HTMLOptions) ([]byte, error) {
+	ratio, ok := aspectRatios[deck.AspectRatio]
+	if !ok {
+		ratio = aspectRatios[""]
+	}
+	w := &htmlWriter{deck: deck, opts: opts, files: make(map[string][]byte)}
+
+	section := ""
+	for i, frame := range deck.Frames() {
+		// 章节变化时插入章节页
+		if frame.Section != "" && frame.Section != section && !frame.TitlePage {
+			w.sectionSlide(frame.Section)
+		}
+		section = frame.Section
+		w.frame(i, frame)
+	}
+
+	scripts := ""
+	if opts.KaTeX != nil {
+		if err := w.addKaTeX(opts.KaTeX); err != nil {
+			return nil, err
+		}
+		scripts = katexScripts
+	}
+
+	var page strings.Builder
+	fmt.Fprintf(&page, htmlHead, html.EscapeString(latex.PlainText(deck.Title)), scripts, ratio)
+	page.WriteString(w.slides.String())
+	page.WriteString(htmlTail)
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w.files["index.html"] = []byte(page.String())
+	names := make([]string, 0, len(w.files))
+	for name := range w.files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		f, err := zw.Create(name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := f.Write(w.files[name]); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+type htmlWriter struct {
+	deck   *latex.Deck
+	opts   HTMLOptions
+	slides strings.Builder
+	// files 是 index.html 之外的文件：zip 内路径 -> 内容
+	files map[string][]byte
+}
+
+// addKaTeX 把 KaTeX 的脚本、样式和字体复制到 katex/ 下
+func (w *htmlWriter) addKaTeX(fsys fs.FS) error {
+	fonts, err := fs.Glob(fsys, "fonts/*")
+	if err != nil {
+		return err
+	}
+	for _, name := range append(slices.Clone(katexFiles), fonts...) {
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return fmt.Errorf("read KaTeX file: %w", err)
+		}
+		w.files[path.Join("katex", name)] = data
+	}
+	return nil
+}
+
+func (w *htmlWriter) sectionSlide(section string) {
+	fmt.Fprintf(&w.slides, `<section class="slide section-slide" data-section="%s"><h1>%s</h1></section>`+"\n",
+		html.EscapeString(latex.PlainText(section)), inlineHTML(section))
+}
+
+func (w *htmlWriter) frame(index int, frame latex.Frame) {
+	section := html.EscapeString(latex.PlainText(frame.Section))
+
+	if frame.TitlePage {
+		d := w.deck
+		fmt.Fprintf(&w.slides, `<section class="slide title-slide"><h1>%s</h1>`, inlineHTML(d.Title))
+		for _, field := range []struct{ class, value string }{
+			{"subtitle", d.Subtitle}, {"author", d.Author}, {"institute", d.Institute}, {"date", d.Date},
+		} {
+			if field.value != "" {
+				fmt.Fprintf(&w.slides, `<p class="%s">%s</p>`, field.class, inlineHTML(field.value))
+			}
+		}
+		w.slides.WriteString("</section>\n")
+		return
+	}
+
+	if needsImage(frame, w.opts.Assets, true, true) && w.opts.Render != nil {
+		if data, err := w.opts.Render(index, frame); err == nil {
+			name := fmt.Sprintf("slides/frame-%d.png", index+1)
+			w.files[name] = data
+			fmt.Fprintf(&w.slides, `<section class="slide image-slide" data-section="%s"><img src="%s" alt="%s"></section>`+"\n",
+				section, name, html.EscapeString(latex.PlainText(frame.Title)))
+			return
+		}
+	}
+
+	fmt.Fprintf(&w.slides, `<section class="slide" data-section="%s">`, section)
+	if frame.Title != "" {
+		fmt.Fprintf(&w.slides, `<header><h2>%s</h2>`, inlineHTML(frame.Title))
+		if frame.Subtitle != "" {
+			fmt.Fprintf(&w.slides, `<h3>%s</h3>`, inlineHTML(frame.Subtitle))
+		}
+		w.slides.WriteString(`</header>`)
+	}
+	w.slides.WriteString(`<div class="content">`)
+	for _, name := range frame.Unsupported {
+		if name == "tableofcontents" {
+			w.tableOfContents(frame.Section)
+		}
+	}
+	w.blocks(frame.Blocks)
+	w.slides.WriteString("</div></section>\n")
+}
+
+// tableOfContents 由各 frame 的章节生成目录，current 为当前章节
+func (w *htmlWriter) tableOfContents(current string) {
+	w.slides.WriteString(`<ol class="toc">`)
+	section, subsection := "", ""
+	open := false
+	for _, f := range w.deck.Frames() {
+		if f.Section != "" && f.Section != section {
+			if open {
+				w.slides.WriteString(`</ul></li>`)
+			}
+			class := ""
+			if f.Section == current {
+				class = ` class="current"`
+			}
+			fmt.Fprintf(&w.slides, `<li%s>%s<ul>`, class, inlineHTML(f.Section))
+			section, subsection, open = f.Section, "", true
+		}
+		if open && f.Subsection != "" && f.Subsection != subsection {
+			fmt.Fprintf(&w.slides, `<li>%s</li>`, inlineHTML(f.Subsection))
+			subsection = f.Subsection
+		}
+	}
+	if open {
+		w.slides.WriteString(`</ul></li>`)
+	}
+	w.slides.WriteString(`</ol>`)
+}
+
+func (w *htmlWriter) blocks(blocks []latex.Block) {
+	for _, b := range blocks {
+		switch b.Kind {
+		case latex.BlockParagraph:
+			fmt.Fprintf(&w.slides, `<p>%s</p>`, inlineHTML(b.Text))
+		case latex.BlockList:
+			w.list(b)
+		case latex.BlockBox:
+			fmt.Fprintf(&w.slides, `<div class="block %s">`, b.Style)
+			if b.Text != "" {
+				fmt.Fprintf(&w.slides, `<div class="block-title">%s</div>`, inlineHTML(b.Text))
+			}
+			w.slides.WriteString(`<div class="block-body">`)
+			w.blocks(b.Children)
+			w.slides.WriteString(`</div></div>`)
+		case latex.BlockMath:
+			fmt.Fprintf(&w.slides, `<div class="math">\[%s\]</div>`, html.EscapeString(b.DisplayMath()))
+		case latex.BlockCode:
+			fmt.Fprintf(&w.slides, `<pre><code>%s</code></pre>`, html.EscapeString(b.Text))
+		case latex.BlockImage:
+			w.image(b)
+		case latex.BlockTable:
+			w.table(b.Rows)
+		case latex.BlockColumns:
+			w.slides.WriteString(`<div class="columns">`)
+			for _, col := range b.Columns {
+				w.slides.WriteString(`<div class="column">`)
+				w.blocks(col.Blocks)
+				w.slides.WriteString(`</div>`)
+			}
+			w.slides.WriteString(`</div>`)
+		}
+	}
+}
+
+func (w *htmlWriter) list(b latex.Block) {
+	tag := "ul"
+	switch {
+	case b.Ordered:
+		tag = "ol"
+	case len(b.Items) > 0 && b.Items[0].Label != "":
+		tag = "dl"
+	}
+
+	fmt.Fprintf(&w.slides, `<%s>`, tag)
+	for _, item := range b.Items {
+		if tag == "dl" {
+			fmt.Fprintf(&w.slides, `<dt>%s</dt><dd>%s`, inlineHTML(item.Label), inlineHTML(item.Text))
+			w.blocks(item.Children)
+			w.slides.WriteString(`</dd>`)
+			continue
+		}
+		fmt.Fprintf(&w.slides, `<li>%s`, inlineHTML(item.Text))
+		w.blocks(item.Children)
+		w.slides.WriteString(`</li>`)
+	}
+	fmt.Fprintf(&w.slides, `</%s>`, tag)
+}
+
+func (w *htmlWriter) image(b latex.Block) {
+	img, ok := FindImage(w.opts.Assets, b.Path)
+	if !ok {
+		return
+	}
+	name := "assets/" + path.Clean(img.Name)
+	w.files[name] = img.Data
+
+	fmt.Fprintf(&w.slides, `<figure><img src="%s" alt="%s">`, html.EscapeString(name), html.EscapeString(latex.PlainText(b.Caption)))
+	if b.Caption != "" {
+		fmt.Fprintf(&w.slides, `<figcaption>%s</figcaption>`, inlineHTML(b.Caption))
+	}
+	w.slides.WriteString(`</figure>`)
+}
+
+// table 输出表格，多于一行时第一行作为表头
+func (w *htmlWriter) table(rows [][]string) {
+	w.slides.WriteString(`<table>`)
+	for i, row := range rows {
+		cell := "td"
+		if i == 0 && len(rows) > 1 {
+			cell = "th"
+		}
+		w.slides.WriteString(`<tr>`)
+		for _, c := range row {
+			fmt.Fprintf(&w.slides, `<%s>%s</%s>`, cell, inlineHTML(c), cell)
+		}
+		w.slides.WriteString(`</tr>`)
+	}
+	w.slides.WriteString(`</table>`)
+}
+
+// inlineHTML 把行内 LaTeX 转换为 HTML，行内公式保留为 \(...\) 交给 KaTeX 渲染
+func inlineHTML(src string) string {
+	var b strings.Builder
+	for _, r := range latex.ParseInline(src) {
+		if r.Math {
+			fmt.Fprintf(&b, `\(%s\)`, html.EscapeString(r.Text))
+			continue
+		}
+
+		text := strings.ReplaceAll(html.EscapeString(r.Text), "\n", "<br>")
+		if r.Code {
+			text = "<code>" + text + "</code>"
+		}
+		if r.Italic {
+			text = "<em>" + text + "</em>"
+		}
+		if r.Bold {
+			text = "<strong>" + text + "</strong>"
+		}
+		if r.Link != "" && safeLink(r.Link) {
+			text = fmt.Sprintf(`<a href="%s" target="_blank" rel="noopener">%s</a>`, html.EscapeString(r.Link), text)
+		}
+		b.WriteString(text)
+	}
+	return b.String()
+}
+
+// safeLink 只保留 http(s) 和 mailto 链接，避免 javascript: 等链接在导出的页面中执行
+func safeLink(link string) bool {
+	lower := strings.ToLower(strings.TrimSpace(link))
+	return strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://") || strings.HasPrefix(lower, "mailto:")
+}
+
+// htmlHead 参数：标题、KaTeX 引用、宽高比
+const htmlHead = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>%s</title>
+%s<style>
+:root { --ratio: %.4f; --accent: #1f3864; }
+* { box-sizing: border-box; }
+html, body { margin: 0; height: 100%%; background: #222; font-family: "Helvetica Neue", Arial, "PingFang SC", "Microsoft YaHei", sans-serif; }
+.deck { height: 100%%; display: flex; align-items: center; justify-content: center; }
+.slide { display: none; width: 50rem; height: calc(50rem / var(--ratio)); background: #fff; color: #222; padding: 2rem 2.5rem; overflow: hidden; position: relative; font-size: 1.1rem; }
+.slide.active { display: flex; flex-direction: column; }
+.slide header { border-bottom: 0.15rem solid var(--accent); margin-bottom: 1rem; }
+.slide h2 { color: var(--accent); margin: 0 0 0.3rem; font-size: 1.8rem; }
+.slide h3 { color: #595959; margin: 0 0 0.3rem; font-size: 1.2rem; font-weight: normal; }
+.slide .content { flex: 1; }
+.slide::after { content: attr(data-section); position: absolute; left: 2.5rem; bottom: 0.6rem; font-size: 0.7rem; color: #888; }
+.title-slide, .section-slide { justify-content: center; align-items: center; text-align: center; }
+.title-slide h1, .section-slide h1 { color: var(--accent); font-size: 2.4rem; margin: 0 0 1rem; }
+.title-slide .subtitle { font-size: 1.4rem; color: #404040; margin: 0 0 1.5rem; }
+.title-slide p { margin: 0.3rem 0; color: #595959; }
+.image-slide { padding: 0; }
+.image-slide img { width: 100%%; height: 100%%; object-fit: contain; }
+li, dd { margin: 0.3rem 0; }
+dt { font-weight: bold; color: var(--accent); }
+.block { margin: 0.8rem 0; border-radius: 0.3rem; overflow: hidden; background: #eef1f7; }
+.block-title { background: var(--accent); color: #fff; padding: 0.3rem 0.8rem; font-weight: bold; }
+.alertblock { background: #fbecec; } .alertblock .block-title { background: #c00000; }
+.exampleblock { background: #edf5ea; } .exampleblock .block-title { background: #38761d; }
+.block-body { padding: 0.4rem 0.8rem; }
+.block-body p { margin: 0.3rem 0; }
+.columns { display: flex; gap: 1.5rem; align-items: flex-start; }
+.column { flex: 1; min-width: 0; }
+figure { margin: 0.8rem auto; text-align: center; }
+figure img { max-width: 80%%; max-height: 16rem; }
+figcaption { font-size: 0.9rem; color: #595959; }
+table { border-collapse: collapse; margin: 0.8rem auto; }
+th, td { border: 1px solid #bbb; padding: 0.3rem 0.8rem; }
+th { background: #eef1f7; }
+pre { background: #f5f5f5; padding: 0.6rem; font-size: 0.85rem; overflow: auto; }
+.toc .current { font-weight: bold; color: var(--accent); }
+.counter { position: fixed; right: 1rem; bottom: 0.6rem; color: #aaa; font-size: 0.8rem; }
+@media print {
+  html, body { background: none; height: auto; }
+  .deck { display: block; }
+  .slide { display: flex; flex-direction: column; page-break-after: always; }
+  .counter { display: none; }
+}
+</style>
+</head>
+<body>
+<div class="deck">
+`
+
+const htmlTail = `</div>
+<div class="counter"></div>
+<script>
+(function () {
+  var slides = document.querySelectorAll('.slide');
+  var counter = document.querySelector('.counter');
+  var ratio = parseFloat(getComputedStyle(document.documentElement).getPropertyValue('--ratio'));
+  var current = 0;
+
+  function fit() {
+    var width = Math.min(window.innerWidth, window.innerHeight * ratio);
+    document.documentElement.style.fontSize = (width / 50) + 'px';
+  }
+
+  function show(n) {
+    current = Math.max(0, Math.min(slides.length - 1, n));
+    slides.forEach(function (s, i) { s.classList.toggle('active', i === current); });
+    counter.textContent = (current + 1) + ' / ' + slides.length;
+    history.replaceState(null, '', '#' + (current + 1));
+  }
+
+  document.addEventListener('keydown', function (e) {
+    if (['ArrowRight', 'ArrowDown', 'PageDown', ' '].indexOf(e.key) >= 0) show(current + 1);
+    else if (['ArrowLeft', 'ArrowUp', 'PageUp', 'Backspace'].indexOf(e.key) >= 0) show(current - 1);
+    else if (e.key === 'Home') show(0);
+    else if (e.key === 'End') show(slides.length - 1);
+    else if (e.key === 'f' && document.documentElement.requestFullscreen) document.documentElement.requestFullscreen();
+    else return;
+    e.preventDefault();
+  });
+  document.addEventListener('click', function (e) {
+    if (e.target.closest('a')) return;
+    show(e.clientX < window.innerWidth / 3 ? current - 1 : current + 1);
+  });
+  window.addEventListener('resize', fit);
+
+  fit();
+  show(parseInt(location.hash.slice(1), 10) - 1 || 0);
+})();
+</script>
+</body>
+</html>
+`
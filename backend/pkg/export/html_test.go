@@ -0,0 +1,142 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"image"
+	"image/png"
+	"io"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/qingbingwei/latex_ppt_by_claude/backend/pkg/latex"
+)
+
+const htmlDeck = `\documentclass[aspectratio=169]{beamer}
+\title{Talk}
+\author{Someone}
+\begin{document}
+\maketitle
+\section{Intro}
+\begin{frame}{Basics}
+\begin{itemize}
+\item \textbf{Bold} <point>
+\end{itemize}
+\begin{description}
+\item[Term] Meaning
+\end{description}
+\[ x^2 \]
+\includegraphics{img/a.png}
+\href{javascript:alert(1)}{bad link}
+\end{frame}
+\begin{frame}{Drawing}
+\begin{tikzpicture}\draw (0,0) -- (1,1);\end{tikzpicture}
+\end{frame}
+\end{document}
+`
+
+var katexFS = fstest.MapFS{
+	"katex.min.css":                  {Data: []byte("css")},
+	"katex.min.js":                   {Data: []byte("js")},
+	"contrib/auto-render.min.js":     {Data: []byte("auto")},
+	"fonts/KaTeX_Main-Regular.woff2": {Data: []byte("font")},
+}
+
+func TestHTML(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    HTMLOptions
+		files   []string
+		want    []string
+		notWant []string
+	}{
+		{
+			name: "offline katex",
+			opts: HTMLOptions{
+				Assets: map[string][]byte{"img/a.png": testPNG(t)},
+				Render: func(int, latex.Frame) ([]byte, error) { return []byte("frame"), nil },
+				KaTeX:  katexFS,
+			},
+			files: []string{"index.html", "assets/img/a.png", "slides/frame-3.png", "katex/katex.min.css", "katex/katex.min.js",
+				"katex/contrib/auto-render.min.js", "katex/fonts/KaTeX_Main-Regular.woff2"},
+			want:    []string{`href="katex/katex.min.css"`, `src="katex/katex.min.js"`, `<h1>Talk</h1>`, `data-section="Intro"`, `<strong>Bold</strong> &lt;point&gt;`, `<dt>Term</dt>`, `\[x^2\]`, `src="slides/frame-3.png"`},
+			notWant: []string{"cdn.jsdelivr.net", "javascript:"},
+		},
+		{
+			name:    "without katex or renderer",
+			opts:    HTMLOptions{Render: func(int, latex.Frame) ([]byte, error) { return nil, errors.New("no tex") }},
+			files:   []string{"index.html"},
+			want:    []string{`\[x^2\]`, `<h2>Drawing</h2>`},
+			notWant: []string{"katex", "slides/"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := HTML(latex.ParseDeck(htmlDeck), tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			files := readZip(t, data)
+			for _, name := range tt.files {
+				if _, ok := files[name]; !ok {
+					t.Errorf("zip is missing %s", name)
+				}
+			}
+			if len(files) != len(tt.files) {
+				t.Errorf("got %d files, want %d", len(files), len(tt.files))
+			}
+			page := files["index.html"]
+			for _, want := range tt.want {
+				if !strings.Contains(page, want) {
+					t.Errorf("index.html is missing %q", want)
+				}
+			}
+			for _, unwanted := range tt.notWant {
+				if strings.Contains(page, unwanted) {
+					t.Errorf("index.html contains %q", unwanted)
+				}
+			}
+		})
+	}
+}
+
+func TestHTMLMissingKaTeXFile(t *testing.T) {
+	broken := fstest.MapFS{"katex.min.js": {Data: []byte("js")}}
+	if _, err := HTML(latex.ParseDeck(htmlDeck), HTMLOptions{KaTeX: broken}); err == nil {
+		t.Fatal("HTML succeeded without katex.min.css")
+	}
+}
+
+func testPNG(t *testing.T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 4, 3))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// readZip 返回 zip 中的文件：路径 -> 内容
+func readZip(t *testing.T, data []byte) map[string]string {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := make(map[string]string)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[f.Name] = string(content)
+	}
+	return files
+}
@@ -0,0 +1,652 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/qingbingwei/latex_ppt_by_claude/backend/pkg/latex"
+)
+
+const (
+	emuPerPt  = 12700
+	emuPerPx  = 9525 // 96 DPI
+	slideCY   = 6858000
+	bodySize  = 20.0
+	titleSize = 30.0
+)
+
+// beamer aspectratio 选项对应的幻灯片宽度（高度固定为 7.5 英寸）
+var slideWidths = map[string]int{
+	"":     9144000,
+	"43":   9144000,
+	"169":  12192000,
+	"1610": 10972800,
+	"149":  10668000,
+	"141":  9669780,
+	"54":   8572500,
+	"32":   10287000,
+}
+
+// FrameRenderer 把无法转换为原生元素的 frame 渲染为整页 PNG
+type FrameRenderer func(index int, frame latex.Frame) ([]byte, error)
+
+type PPTXOptions struct {
+	// Assets 是编译时可用的资源文件，用于查找 \includegraphics 引用的图片
+	Assets map[string][]byte
+	// Render 为 nil 或渲染失败时，无法转换的 frame 也尽力转换为原生元素
+	Render FrameRenderer
+}
+
+// PPTX 把解析后的 beamer 文档转换为 .pptx。
+// 标题、段落、列表、block、表格和位图转换为可编辑的原生元素；
+// 含有公式、TikZ 等无法转换内容的 frame 通过 Render 退回为一张整页图片
+func PPTX(deck *latex.Deck, opts PPTXOptions) ([]byte, error) {
+	width, ok := slideWidths[deck.AspectRatio]
+	if !ok {
+		width = slideWidths[""]
+	}
+	w := &pptxWriter{deck: deck, opts: opts, cx: width, cy: slideCY, media: make(map[string]string)}
+
+	for i, frame := range deck.Frames() {
+		slide := w.newSlide()
+		switch {
+		case frame.TitlePage:
+			slide.titlePage(deck)
+		// 行间公式在 PowerPoint 中无法保真，和 TikZ 等一样使用渲染结果
+		case needsImage(frame, opts.Assets, false, false) && w.renderFallback(slide, i, frame):
+		default:
+			slide.frame(frame)
+		}
+		w.slides = append(w.slides, slide)
+	}
+	return w.write()
+}
+
+type pptxWriter struct {
+	deck   *latex.Deck
+	opts   PPTXOptions
+	cx, cy int
+	slides []*slideBuilder
+	// media 记录已写入的图片：资源名 -> ppt/media 下的文件名
+	media     map[string]string
+	mediaData []mediaPart
+}
+
+type mediaPart struct {
+	name string
+	data []byte
+}
+
+type slideBuilder struct {
+	w      *pptxWriter
+	shapes bytes.Buffer
+	rels   []relationship
+	nextID int
+}
+
+type relationship struct {
+	id, typ, target string
+	external        bool
+}
+
+func (w *pptxWriter) newSlide() *slideBuilder {
+	s := &slideBuilder{w: w, nextID: 2}
+	s.addRel(relTypeLayout, "../slideLayouts/slideLayout1.xml", false)
+	return s
+}
+
+// renderFallback 把 frame 作为整页图片放入幻灯片，渲染失败时返回 false
+func (w *pptxWriter) renderFallback(s *slideBuilder, index int, frame latex.Frame) bool {
+	if w.opts.Render == nil {
+		return false
+	}
+	data, err := w.opts.Render(index, frame)
+	if err != nil {
+		return false
+	}
+	img, ok := DecodeImage(fmt.Sprintf("frame-%d.png", index+1), data)
+	if !ok {
+		return false
+	}
+
+	// 等比缩放并居中
+	scale := math.Min(float64(w.cx)/float64(img.Width), float64(w.cy)/float64(img.Height))
+	cx, cy := int(float64(img.Width)*scale), int(float64(img.Height)*scale)
+	s.picture(img, (w.cx-cx)/2, (w.cy-cy)/2, cx, cy)
+	return true
+}
+
+// addMedia 把图片写入 ppt/media，相同资源只写一次
+func (w *pptxWriter) addMedia(img *Image) string {
+	if name, ok := w.media[img.Name]; ok {
+		return name
+	}
+	ext := img.Format
+	name := fmt.Sprintf("image%d.%s", len(w.mediaData)+1, ext)
+	w.media[img.Name] = name
+	w.mediaData = append(w.mediaData, mediaPart{name: name, data: img.Data})
+	return name
+}
+
+func (s *slideBuilder) addRel(typ, target string, external bool) string {
+	id := fmt.Sprintf("rId%d", len(s.rels)+1)
+	s.rels = append(s.rels, relationship{id: id, typ: typ, target: target, external: external})
+	return id
+}
+
+func (s *slideBuilder) id() int {
+	s.nextID++
+	return s.nextID - 1
+}
+
+// textPara 是文本框中的一个段落
+type textPara struct {
+	runs   []latex.Run
+	level  int
+	bullet string // "", "char", "number"
+	size   float64
+	bold   bool
+	italic bool
+	font   string
+	color  string
+	align  string
+}
+
+// item 是按纵向顺序排列的内容：一组段落、一张图片或一个表格
+type item struct {
+	paras []textPara
+	image *Image
+	table [][]string
+}
+
+func (s *slideBuilder) titlePage(deck *latex.Deck) {
+	w := s.w
+	margin := w.cx / 12
+	width := w.cx - 2*margin
+
+	title := []textPara{{runs: latex.ParseInline(deck.Title), size: 40, bold: true, align: "ctr", color: "1F3864"}}
+	if deck.Subtitle != "" {
+		title = append(title, textPara{runs: latex.ParseInline(deck.Subtitle), size: 24, align: "ctr", color: "404040"})
+	}
+	s.textBox("Title", margin, w.cy/4, width, w.cy*3/10, "b", title)
+
+	var meta []textPara
+	for _, field := range []string{deck.Author, deck.Institute, deck.Date} {
+		if runs := latex.ParseInline(field); len(runs) > 0 {
+			meta = append(meta, textPara{runs: runs, size: 18, align: "ctr", color: "595959"})
+		}
+	}
+	if len(meta) > 0 {
+		s.textBox("Subtitle", margin, w.cy*3/5, width, w.cy/4, "t", meta)
+	}
+}
+
+func (s *slideBuilder) frame(frame latex.Frame) {
+	w := s.w
+	margin := w.cx / 20
+	width := w.cx - 2*margin
+
+	top := margin
+	if frame.Title != "" {
+		paras := []textPara{{runs: latex.ParseInline(frame.Title), size: titleSize, bold: true, color: "1F3864"}}
+		if frame.Subtitle != "" {
+			paras = append(paras, textPara{runs: latex.ParseInline(frame.Subtitle), size: 20, color: "595959"})
+		}
+		height := w.cy * 16 / 100
+		s.textBox("Title", margin, margin*2/3, width, height, "b", paras)
+		top = margin*2/3 + height + margin/3
+	}
+
+	items := blockItems(frame.Blocks, 0, w.opts.Assets)
+	available := w.cy - margin - top
+
+	// 内容超出时整体缩小字号，最小到 50%
+	scale := 1.0
+	for ; scale > 0.5; scale -= 0.05 {
+		if itemsHeight(items, width, scale) <= available {
+			break
+		}
+	}
+
+	y := top
+	for _, it := range items {
+		h := itemHeight(it, width, scale)
+		switch {
+		case it.image != nil:
+			cx := int(float64(h) * float64(it.image.Width) / float64(it.image.Height))
+			s.picture(it.image, margin+(width-cx)/2, y, cx, h)
+		case it.table != nil:
+			s.table(it.table, margin, y, width, h, scale)
+		default:
+			paras := make([]textPara, len(it.paras))
+			for i, p := range it.paras {
+				p.size *= scale
+				paras[i] = p
+			}
+			s.textBox("Content", margin, y, width, h, "t", paras)
+		}
+		y += h + gap(scale)
+	}
+}
+
+// blockItems 把内容块转换为纵向排列的内容，相邻的文本合并到同一个文本框
+func blockItems(blocks []latex.Block, level int, assets map[string][]byte) []item {
+	var items []item
+	addParas := func(paras ...textPara) {
+		if n := len(items); n > 0 && items[n-1].paras != nil {
+			items[n-1].paras = append(items[n-1].paras, paras...)
+			return
+		}
+		items = append(items, item{paras: paras})
+	}
+
+	for _, b := range blocks {
+		switch b.Kind {
+		case latex.BlockParagraph:
+			addParas(textPara{runs: latex.ParseInline(b.Text), level: level, size: bodySize})
+		case latex.BlockList:
+			for _, li := range b.Items {
+				runs := latex.ParseInline(li.Text)
+				if li.Label != "" {
+					label := latex.ParseInline(li.Label)
+					for i := range label {
+						label[i].Bold = true
+					}
+					runs = append(append(label, latex.Run{Text: " "}), runs...)
+				}
+				bullet := "char"
+				if b.Ordered {
+					bullet = "number"
+				}
+				addParas(textPara{runs: runs, level: level, bullet: bullet, size: listSize(level)})
+				items = appendItems(items, blockItems(li.Children, level+1, assets))
+			}
+		case latex.BlockBox:
+			color := "1F3864"
+			switch b.Style {
+			case "alertblock":
+				color = "C00000"
+			case "exampleblock":
+				color = "38761D"
+			}
+			addParas(textPara{runs: latex.ParseInline(b.Text), level: level, size: bodySize, bold: true, color: color})
+			items = appendItems(items, blockItems(b.Children, level+1, assets))
+		case latex.BlockMath:
+			addParas(textPara{runs: []latex.Run{{Text: b.DisplayMath()}}, level: level, size: bodySize, italic: true, font: "Cambria Math", align: "ctr"})
+		case latex.BlockCode:
+			addParas(textPara{runs: []latex.Run{{Text: b.Text}}, level: level, size: bodySize * 0.8, font: "Courier New"})
+		case latex.BlockImage:
+			if img, ok := FindImage(assets, b.Path); ok {
+				items = append(items, item{image: img})
+			}
+			if b.Caption != "" {
+				addParas(textPara{runs: latex.ParseInline(b.Caption), size: bodySize * 0.8, italic: true, align: "ctr"})
+			}
+		case latex.BlockTable:
+			items = append(items, item{table: b.Rows})
+		case latex.BlockColumns:
+			// 各栏依次纵向排列
+			for _, col := range b.Columns {
+				items = appendItems(items, blockItems(col.Blocks, level, assets))
+			}
+		}
+	}
+	return items
+}
+
+// appendItems 追加内容，首个文本内容与前面的文本框合并
+func appendItems(items, more []item) []item {
+	if len(more) > 0 && more[0].paras != nil {
+		if n := len(items); n > 0 && items[n-1].paras != nil {
+			items[n-1].paras = append(items[n-1].paras, more[0].paras...)
+			more = more[1:]
+		}
+	}
+	return append(items, more...)
+}
+
+func listSize(level int) float64 {
+	return math.Max(bodySize-2*float64(level), 14)
+}
+
+func gap(scale float64) int {
+	return int(8 * emuPerPt * scale)
+}
+
+func itemsHeight(items []item, width int, scale float64) int {
+	total := 0
+	for _, it := range items {
+		total += itemHeight(it, width, scale) + gap(scale)
+	}
+	return total
+}
+
+// itemHeight 估算内容高度：文本按字符宽度估算行数，图片按原始尺寸限制在可用宽度内
+func itemHeight(it item, width int, scale float64) int {
+	switch {
+	case it.image != nil:
+		maxW := float64(width) * 0.8
+		h := math.Min(float64(it.image.Height*emuPerPx), maxW*float64(it.image.Height)/float64(it.image.Width))
+		return int(math.Min(h, float64(slideCY)*0.55) * scale)
+	case it.table != nil:
+		return len(it.table) * int((bodySize*scale*1.2+10)*emuPerPt)
+	}
+
+	height := 0.0
+	for _, p := range it.paras {
+		size := p.size * scale
+		lineWidth := float64(width)/emuPerPt - float64(p.level+1)*27
+		lines := 0
+		for _, line := range strings.Split(plain(p.runs), "\n") {
+			lines += max(1, int(math.Ceil(textWidth(line, size)/lineWidth)))
+		}
+		height += float64(lines)*size*1.2 + 6*scale
+	}
+	return int(height*emuPerPt) + int(0.1*914400)
+}
+
+// textWidth 粗略估算文本宽度（pt），CJK 字符按全角计算
+func textWidth(s string, size float64) float64 {
+	w := 0.0
+	for _, r := range s {
+		if r >= 0x2E80 {
+			w += size
+		} else {
+			w += size * 0.55
+		}
+	}
+	return w
+}
+
+func plain(runs []latex.Run) string {
+	var b strings.Builder
+	for _, r := range runs {
+		b.WriteString(r.Text)
+	}
+	return b.String()
+}
+
+func (s *slideBuilder) textBox(name string, x, y, cx, cy int, anchor string, paras []textPara) {
+	id := s.id()
+	fmt.Fprintf(&s.shapes, `<p:sp><p:nvSpPr><p:cNvPr id="%d" name="%s %d"/><p:cNvSpPr txBox="1"/><p:nvPr/></p:nvSpPr>`, id, name, id)
+	fmt.Fprintf(&s.shapes, `<p:spPr><a:xfrm><a:off x="%d" y="%d"/><a:ext cx="%d" cy="%d"/></a:xfrm><a:prstGeom prst="rect"><a:avLst/></a:prstGeom><a:noFill/></p:spPr>`, x, y, cx, cy)
+	fmt.Fprintf(&s.shapes, `<p:txBody><a:bodyPr wrap="square" rtlCol="0" anchor="%s"><a:normAutofit/></a:bodyPr><a:lstStyle/>`, anchor)
+	for _, p := range paras {
+		s.paragraph(p)
+	}
+	s.shapes.WriteString(`</p:txBody></p:sp>`)
+}
+
+func (s *slideBuilder) paragraph(p textPara) {
+	indent := 342900
+	marL := p.level * indent
+	s.shapes.WriteString(`<a:p><a:pPr`)
+	switch p.bullet {
+	case "char", "number":
+		marL += indent
+		fmt.Fprintf(&s.shapes, ` marL="%d" lvl="%d" indent="%d"`, marL, min(p.level, 8), -indent)
+	default:
+		fmt.Fprintf(&s.shapes, ` marL="%d" indent="0"`, marL)
+	}
+	if p.align != "" {
+		fmt.Fprintf(&s.shapes, ` algn="%s"`, p.align)
+	}
+	s.shapes.WriteString(`><a:spcBef><a:spcPts val="600"/></a:spcBef>`)
+	switch p.bullet {
+	case "char":
+		chars := []string{"•", "–", "◦"}
+		fmt.Fprintf(&s.shapes, `<a:buFont typeface="Arial"/><a:buChar char="%s"/>`, chars[p.level%len(chars)])
+	case "number":
+		s.shapes.WriteString(`<a:buAutoNum type="arabicPeriod"/>`)
+	default:
+		s.shapes.WriteString(`<a:buNone/>`)
+	}
+	s.shapes.WriteString(`</a:pPr>`)
+
+	for _, r := range p.runs {
+		for i, line := range strings.Split(r.Text, "\n") {
+			if i > 0 {
+				s.shapes.WriteString(`<a:br/>`)
+			}
+			if line != "" {
+				s.run(p, r, line)
+			}
+		}
+	}
+	fmt.Fprintf(&s.shapes, `<a:endParaRPr lang="zh-CN" sz="%d"/></a:p>`, fontSize(p.size))
+}
+
+func (s *slideBuilder) run(p textPara, r latex.Run, text string) {
+	fmt.Fprintf(&s.shapes, `<a:r><a:rPr lang="zh-CN" altLang="en-US" sz="%d" dirty="0"`, fontSize(p.size))
+	if p.bold || r.Bold {
+		s.shapes.WriteString(` b="1"`)
+	}
+	if p.italic || r.Italic || r.Math {
+		s.shapes.WriteString(` i="1"`)
+	}
+	if r.Link != "" {
+		s.shapes.WriteString(` u="sng"`)
+	}
+	s.shapes.WriteString(`>`)
+	if p.color != "" {
+		fmt.Fprintf(&s.shapes, `<a:solidFill><a:srgbClr val="%s"/></a:solidFill>`, p.color)
+	}
+	font := p.font
+	switch {
+	case r.Code:
+		font = "Courier New"
+	case r.Math:
+		font = "Cambria Math"
+	}
+	if font != "" {
+		fmt.Fprintf(&s.shapes, `<a:latin typeface="%s"/>`, font)
+	}
+	if r.Link != "" {
+		id := s.addRel(relTypeHyperlink, r.Link, true)
+		fmt.Fprintf(&s.shapes, `<a:hlinkClick r:id="%s"/>`, id)
+	}
+	fmt.Fprintf(&s.shapes, `</a:rPr><a:t>%s</a:t></a:r>`, escape(text))
+}
+
+func (s *slideBuilder) picture(img *Image, x, y, cx, cy int) {
+	media := s.w.addMedia(img)
+	rel := s.addRel(relTypeImage, "../media/"+media, false)
+	id := s.id()
+	fmt.Fprintf(&s.shapes, `<p:pic><p:nvPicPr><p:cNvPr id="%d" name="Picture %d" descr="%s"/><p:cNvPicPr><a:picLocks noChangeAspect="1"/></p:cNvPicPr><p:nvPr/></p:nvPicPr>`, id, id, escape(img.Name))
+	fmt.Fprintf(&s.shapes, `<p:blipFill><a:blip r:embed="%s"/><a:stretch><a:fillRect/></a:stretch></p:blipFill>`, rel)
+	fmt.Fprintf(&s.shapes, `<p:spPr><a:xfrm><a:off x="%d" y="%d"/><a:ext cx="%d" cy="%d"/></a:xfrm><a:prstGeom prst="rect"><a:avLst/></a:prstGeom></p:spPr></p:pic>`, x, y, cx, cy)
+}
+
+func (s *slideBuilder) table(rows [][]string, x, y, cx, cy int, scale float64) {
+	cols := 0
+	for _, row := range rows {
+		cols = max(cols, len(row))
+	}
+	if cols == 0 {
+		return
+	}
+	colW := cx / cols
+	rowH := cy / len(rows)
+
+	id := s.id()
+	fmt.Fprintf(&s.shapes, `<p:graphicFrame><p:nvGraphicFramePr><p:cNvPr id="%d" name="Table %d"/><p:cNvGraphicFramePr><a:graphicFrameLocks noGrp="1"/></p:cNvGraphicFramePr><p:nvPr/></p:nvGraphicFramePr>`, id, id)
+	fmt.Fprintf(&s.shapes, `<p:xfrm><a:off x="%d" y="%d"/><a:ext cx="%d" cy="%d"/></p:xfrm>`, x, y, colW*cols, cy)
+	s.shapes.WriteString(`<a:graphic><a:graphicData uri="http://schemas.openxmlformats.org/drawingml/2006/table"><a:tbl>`)
+	s.shapes.WriteString(`<a:tblPr firstRow="1" bandRow="1"><a:tableStyleId>{5C22544A-7EE6-4342-B048-85BDC9FD1C3A}</a:tableStyleId></a:tblPr><a:tblGrid>`)
+	for i := 0; i < cols; i++ {
+		fmt.Fprintf(&s.shapes, `<a:gridCol w="%d"/>`, colW)
+	}
+	s.shapes.WriteString(`</a:tblGrid>`)
+	for _, row := range rows {
+		fmt.Fprintf(&s.shapes, `<a:tr h="%d">`, rowH)
+		for i := 0; i < cols; i++ {
+			var cell string
+			if i < len(row) {
+				cell = row[i]
+			}
+			s.shapes.WriteString(`<a:tc><a:txBody><a:bodyPr/><a:lstStyle/>`)
+			s.paragraph(textPara{runs: latex.ParseInline(cell), size: bodySize * scale * 0.9})
+			s.shapes.WriteString(`</a:txBody><a:tcPr/></a:tc>`)
+		}
+		s.shapes.WriteString(`</a:tr>`)
+	}
+	s.shapes.WriteString(`</a:tbl></a:graphicData></a:graphic></p:graphicFrame>`)
+}
+
+// fontSize 把 pt 转换为 OOXML 的百分之一磅
+func fontSize(pt float64) int {
+	return int(math.Round(pt)) * 100
+}
+
+func escape(s string) string {
+	var b bytes.Buffer
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+func (w *pptxWriter) write() ([]byte, error) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+
+	add := func(name, content string) error {
+		f, err := zw.Create(name)
+		if err != nil {
+			return err
+		}
+		_, err = f.Write([]byte(content))
+		return err
+	}
+
+	parts := []struct{ name, content string }{
+		{"[Content_Types].xml", w.contentTypes()},
+		{"_rels/.rels", rootRels},
+		{"docProps/core.xml", w.coreProps()},
+		{"docProps/app.xml", fmt.Sprintf(appProps, len(w.slides))},
+		{"ppt/presentation.xml", w.presentation()},
+		{"ppt/_rels/presentation.xml.rels", w.presentationRels()},
+		{"ppt/presProps.xml", presProps},
+		{"ppt/viewProps.xml", viewProps},
+		{"ppt/tableStyles.xml", tableStyles},
+		{"ppt/theme/theme1.xml", theme},
+		{"ppt/slideMasters/slideMaster1.xml", slideMaster},
+		{"ppt/slideMasters/_rels/slideMaster1.xml.rels", slideMasterRels},
+		{"ppt/slideLayouts/slideLayout1.xml", slideLayout},
+		{"ppt/slideLayouts/_rels/slideLayout1.xml.rels", slideLayoutRels},
+	}
+	for i, slide := range w.slides {
+		parts = append(parts,
+			struct{ name, content string }{fmt.Sprintf("ppt/slides/slide%d.xml", i+1), slide.xml()},
+			struct{ name, content string }{fmt.Sprintf("ppt/slides/_rels/slide%d.xml.rels", i+1), relsXML(slide.rels)},
+		)
+	}
+	for _, part := range parts {
+		if err := add(part.name, part.content); err != nil {
+			return nil, err
+		}
+	}
+	for _, m := range w.mediaData {
+		f, err := zw.Create("ppt/media/" + m.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := f.Write(m.data); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (w *pptxWriter) contentTypes() string {
+	var b strings.Builder
+	b.WriteString(xmlHeader + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">`)
+	b.WriteString(`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>`)
+	b.WriteString(`<Default Extension="xml" ContentType="application/xml"/>`)
+	b.WriteString(`<Default Extension="png" ContentType="image/png"/>`)
+	b.WriteString(`<Default Extension="jpeg" ContentType="image/jpeg"/>`)
+	b.WriteString(`<Default Extension="gif" ContentType="image/gif"/>`)
+	for _, o := range []struct{ part, typ string }{
+		{"/ppt/presentation.xml", "presentationml.presentation.main+xml"},
+		{"/ppt/presProps.xml", "presentationml.presProps+xml"},
+		{"/ppt/viewProps.xml", "presentationml.viewProps+xml"},
+		{"/ppt/tableStyles.xml", "presentationml.tableStyles+xml"},
+		{"/ppt/theme/theme1.xml", "theme+xml"},
+		{"/ppt/slideMasters/slideMaster1.xml", "presentationml.slideMaster+xml"},
+		{"/ppt/slideLayouts/slideLayout1.xml", "presentationml.slideLayout+xml"},
+		{"/docProps/app.xml", "extended-properties+xml"},
+	} {
+		fmt.Fprintf(&b, `<Override PartName="%s" ContentType="application/vnd.openxmlformats-officedocument.%s"/>`, o.part, o.typ)
+	}
+	b.WriteString(`<Override PartName="/docProps/core.xml" ContentType="application/vnd.openxmlformats-package.core-properties+xml"/>`)
+	for i := range w.slides {
+		fmt.Fprintf(&b, `<Override PartName="/ppt/slides/slide%d.xml" ContentType="application/vnd.openxmlformats-officedocument.presentationml.slide+xml"/>`, i+1)
+	}
+	b.WriteString(`</Types>`)
+	return b.String()
+}
+
+func (w *pptxWriter) coreProps() string {
+	return fmt.Sprintf(coreProps,
+		escape(latex.PlainText(w.deck.Title)),
+		escape(latex.PlainText(w.deck.Author)),
+		time.Now().UTC().Format(time.RFC3339))
+}
+
+func (w *pptxWriter) presentation() string {
+	var b strings.Builder
+	b.WriteString(xmlHeader + `<p:presentation ` + namespaces + ` saveSubsetFonts="1">`)
+	b.WriteString(`<p:sldMasterIdLst><p:sldMasterId id="2147483648" r:id="rId1"/></p:sldMasterIdLst><p:sldIdLst>`)
+	for i := range w.slides {
+		fmt.Fprintf(&b, `<p:sldId id="%d" r:id="rId%d"/>`, 256+i, presentationFixedRels+1+i)
+	}
+	fmt.Fprintf(&b, `</p:sldIdLst><p:sldSz cx="%d" cy="%d"/><p:notesSz cx="6858000" cy="9144000"/></p:presentation>`, w.cx, w.cy)
+	return b.String()
+}
+
+// presentation.xml.rels 中 slide 之前固定的关系数
+const presentationFixedRels = 5
+
+func (w *pptxWriter) presentationRels() string {
+	rels := []relationship{
+		{id: "rId1", typ: relTypeMaster, target: "slideMasters/slideMaster1.xml"},
+		{id: "rId2", typ: relTypePresProps, target: "presProps.xml"},
+		{id: "rId3", typ: relTypeViewProps, target: "viewProps.xml"},
+		{id: "rId4", typ: relTypeTheme, target: "theme/theme1.xml"},
+		{id: "rId5", typ: relTypeTableStyles, target: "tableStyles.xml"},
+	}
+	for i := range w.slides {
+		rels = append(rels, relationship{
+			id:     fmt.Sprintf("rId%d", presentationFixedRels+1+i),
+			typ:    relTypeSlide,
+			target: fmt.Sprintf("slides/slide%d.xml", i+1),
+		})
+	}
+	return relsXML(rels)
+}
+
+func (s *slideBuilder) xml() string {
+	return xmlHeader + `<p:sld ` + namespaces + `><p:cSld><p:spTree>` + emptyGroup +
+		s.shapes.String() + `</p:spTree></p:cSld><p:clrMapOvr><a:masterClrMapping/></p:clrMapOvr></p:sld>`
+}
+
+func relsXML(rels []relationship) string {
+	var b strings.Builder
+	b.WriteString(xmlHeader + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`)
+	for _, r := range rels {
+		mode := ""
+		if r.external {
+			mode = ` TargetMode="External"`
+		}
+		fmt.Fprintf(&b, `<Relationship Id="%s" Type="%s" Target="%s"%s/>`, r.id, r.typ, escape(r.target), mode)
+	}
+	b.WriteString(`</Relationships>`)
+	return b.String()
+}
@@ -0,0 +1,141 @@
+package export
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/qingbingwei/latex_ppt_by_claude/backend/pkg/latex"
+)
+
+func TestPPTX(t *testing.T) {
+	const body = `\begin{frame}{Basics}
+\begin{itemize}
+\item \textbf{Bold} & more
+\end{itemize}
+\begin{tabular}{ll}
+A & B \\
+1 & 2
+\end{tabular}
+\includegraphics{img/a.png}
+\includegraphics{img/a}
+\end{frame}
+\begin{frame}{Math}
+\begin{align}
+a &= b \label{eq:a}
+\end{align}
+\end{frame}
+`
+	tests := []struct {
+		name        string
+		aspectRatio string
+		render      FrameRenderer
+		slideWidth  string
+		// slides 是各页必须包含的片段
+		slides  map[int][]string
+		media   []string
+		notWant []string
+	}{
+		{
+			name:       "native elements",
+			slideWidth: `cx="9144000"`,
+			render:     func(int, latex.Frame) ([]byte, error) { return nil, errors.New("no tex") },
+			slides: map[int][]string{
+				1: {">Talk<", ">Someone<"},
+				2: {">Basics<", ">Bold<", "&amp; more", "<a:tbl>", ">B<", "<p:pic>"},
+				3: {">Math<", `\begin{aligned}`, "a &amp;= b"},
+			},
+			media:   []string{"ppt/media/image1.png"},
+			notWant: []string{`\label`},
+		},
+		{
+			name:        "rendered fallback",
+			aspectRatio: "169",
+			slideWidth:  `cx="12192000"`,
+			slides: map[int][]string{
+				3: {"<p:pic>"},
+			},
+			media: []string{"ppt/media/image1.png", "ppt/media/image2.png"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := "\\documentclass{beamer}\n\\title{Talk}\n\\author{Someone}\n\\begin{document}\n\\maketitle\n" + body + "\\end{document}\n"
+			if tt.aspectRatio != "" {
+				source = strings.Replace(source, `\documentclass{beamer}`, `\documentclass[aspectratio=`+tt.aspectRatio+`]{beamer}`, 1)
+			}
+			render := tt.render
+			if render == nil {
+				render = func(int, latex.Frame) ([]byte, error) { return testPNG(t), nil }
+			}
+
+			data, err := PPTX(latex.ParseDeck(source), PPTXOptions{Assets: map[string][]byte{"img/a.png": testPNG(t)}, Render: render})
+			if err != nil {
+				t.Fatal(err)
+			}
+			files := readZip(t, data)
+			for name, content := range files {
+				if strings.HasSuffix(name, ".xml") || strings.HasSuffix(name, ".rels") {
+					checkXML(t, name, content)
+				}
+			}
+
+			if !strings.Contains(files["ppt/presentation.xml"], tt.slideWidth) {
+				t.Errorf("presentation.xml does not have %s", tt.slideWidth)
+			}
+			if _, ok := files["ppt/slides/slide3.xml"]; !ok {
+				t.Fatal("deck should have 3 slides")
+			}
+			for n, wants := range tt.slides {
+				slide := files[fmt.Sprintf("ppt/slides/slide%d.xml", n)]
+				for _, want := range wants {
+					if !strings.Contains(slide, want) {
+						t.Errorf("slide %d is missing %q", n, want)
+					}
+				}
+				for _, unwanted := range tt.notWant {
+					if strings.Contains(slide, unwanted) {
+						t.Errorf("slide %d contains %q", n, unwanted)
+					}
+				}
+			}
+
+			var media []string
+			for name := range files {
+				if strings.HasPrefix(name, "ppt/media/") {
+					media = append(media, name)
+				}
+			}
+			if len(media) != len(tt.media) {
+				t.Errorf("got media %v, want %v", media, tt.media)
+			}
+			for _, name := range tt.media {
+				if _, ok := files[name]; !ok {
+					t.Errorf("missing %s", name)
+				}
+			}
+			if !strings.Contains(files["[Content_Types].xml"], `Extension="png"`) {
+				t.Error("[Content_Types].xml does not declare png")
+			}
+		})
+	}
+}
+
+// checkXML 检查 zip 中的 XML 部件是否格式正确
+func checkXML(t *testing.T, name, content string) {
+	t.Helper()
+	d := xml.NewDecoder(strings.NewReader(content))
+	for {
+		_, err := d.Token()
+		if err == io.EOF {
+			return
+		}
+		if err != nil {
+			t.Errorf("%s is not well-formed: %v", name, err)
+			return
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package latex
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	tempDirPrefix = "latex-"
+	// cacheVersion 在编译流程变化导致旧缓存失效时递增
+	cacheVersion = "2"
+)
+
+// cacheKey 对引擎和全部输入文件（模板已展开在 main.tex 中）计算内容哈希
+func cacheKey(engine string, files map[string][]byte) string {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	h := sha256.New()
+	io.WriteString(h, cacheVersion+"\x00"+engine+"\x00")
+	for _, name := range names {
+		// 写入长度避免不同文件边界产生相同的字节流
+		io.WriteString(h, name+"\x00"+strconv.Itoa(len(files[name]))+"\x00")
+		h.Write(files[name])
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// cacheMeta 与缓存的 PDF 一起保存的编译信息
+type cacheMeta struct {
+	Diagnostics []Diagnostic `json:"diagnostics"`
+	Passes      []Pass       `json:"passes"`
+}
+
+func (c *Compiler) cachePaths(key string) (pdf, meta string) {
+	return filepath.Join(c.cacheDir, key+".pdf"), filepath.Join(c.cacheDir, key+".json")
+}
+
+// lookupCache 命中时把缓存的 PDF 放到 finalPDF 并刷新缓存项的访问时间
+func (c *Compiler) lookupCache(key, finalPDF string) (*Result, bool) {
+	if c.cacheDir == "" {
+		return nil, false
+	}
+	pdfPath, metaPath := c.cachePaths(key)
+	if _, err := os.Stat(pdfPath); err != nil {
+		return nil, false
+	}
+
+	var meta cacheMeta
+	if data, err := os.ReadFile(metaPath); err == nil {
+		json.Unmarshal(data, &meta)
+	}
+
+	if err := linkOrCopy(pdfPath, finalPDF); err != nil {
+		log.Printf("Failed to reuse cached PDF %s: %v", key, err)
+		return nil, false
+	}
+	now := time.Now()
+	os.Chtimes(pdfPath, now, now)
+
+	return &Result{PDFPath: finalPDF, Diagnostics: meta.Diagnostics, Passes: meta.Passes, Cached: true}, true
+}
+
+// storeCache 把编译结果写入缓存，失败只记录日志
+func (c *Compiler) storeCache(key string, result *Result) {
+	if c.cacheDir == "" {
+		return
+	}
+	if err := os.MkdirAll(c.cacheDir, 0755); err != nil {
+		log.Printf("Failed to create compile cache: %v", err)
+		return
+	}
+
+	pdfPath, metaPath := c.cachePaths(key)
+	meta, _ := json.Marshal(cacheMeta{Diagnostics: result.Diagnostics, Passes: result.Passes})
+	if err := writeAtomic(metaPath, func(f *os.File) error {
+		_, err := f.Write(meta)
+		return err
+	}); err != nil {
+		log.Printf("Failed to cache compile metadata %s: %v", key, err)
+		return
+	}
+	if err := writeAtomic(pdfPath, func(f *os.File) error {
+		src, err := os.Open(result.PDFPath)
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+		_, err = io.Copy(f, src)
+		return err
+	}); err != nil {
+		log.Printf("Failed to cache PDF %s: %v", key, err)
+	}
+}
+
+// writeAtomic 先写临时文件再重命名，避免并发编译读到写了一半的缓存
+func writeAtomic(path string, write func(*os.File) error) error {
+	f, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return nil
+}
+
+// linkOrCopy 优先使用硬链接，跨文件系统时退回到复制
+func linkOrCopy(src, dst string) error {
+	os.Remove(dst)
+	if err := os.Link(src, dst); err == nil {
+		return nil
+	}
+	return writeAtomic(dst, func(f *os.File) error {
+		in, err := os.Open(src)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+		_, err = io.Copy(f, in)
+		return err
+	})
+}
+
+// SweepStats 统计一次清理删除的内容
+type SweepStats struct {
+	TempDirs     int `json:"temp_dirs"`
+	CacheEntries int `json:"cache_entries"`
+}
+
+// Sweep 删除超过 tempTTL 的编译临时目录（进程被杀死时遗留）和超过 cacheTTL 未被访问的缓存项。
+// TTL 为 0 时跳过对应的清理
+func (c *Compiler) Sweep(tempTTL, cacheTTL time.Duration) (SweepStats, error) {
+	var stats SweepStats
+	now := time.Now()
+
+	if tempTTL > 0 {
+		entries, err := os.ReadDir(c.outputDir)
+		if err != nil && !os.IsNotExist(err) {
+			return stats, err
+		}
+		for _, entry := range entries {
+			if !entry.IsDir() || !strings.HasPrefix(entry.Name(), tempDirPrefix) {
+				continue
+			}
+			if info, err := entry.Info(); err == nil && now.Sub(info.ModTime()) > tempTTL {
+				if os.RemoveAll(filepath.Join(c.outputDir, entry.Name())) == nil {
+					stats.TempDirs++
+				}
+			}
+		}
+	}
+
+	if c.cacheDir == "" {
+		return stats, nil
+	}
+	entries, err := os.ReadDir(c.cacheDir)
+	if err != nil && !os.IsNotExist(err) {
+		return stats, err
+	}
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || entry.IsDir() {
+			continue
+		}
+		name := entry.Name()
+		age := now.Sub(info.ModTime())
+		switch {
+		case strings.HasPrefix(name, ".tmp-"):
+			if tempTTL > 0 && age > tempTTL {
+				os.Remove(filepath.Join(c.cacheDir, name))
+			}
+		case strings.HasSuffix(name, ".pdf"):
+			if cacheTTL > 0 && age > cacheTTL {
+				pdfPath, metaPath := c.cachePaths(strings.TrimSuffix(name, ".pdf"))
+				if os.Remove(pdfPath) == nil {
+					os.Remove(metaPath)
+					stats.CacheEntries++
+				}
+			}
+		case strings.HasSuffix(name, ".json"):
+			// PDF 写入失败时遗留的元数据文件
+			pdfPath, _ := c.cachePaths(strings.TrimSuffix(name, ".json"))
+			if _, err := os.Stat(pdfPath); os.IsNotExist(err) && tempTTL > 0 && age > tempTTL {
+				os.Remove(filepath.Join(c.cacheDir, name))
+			}
+		}
+	}
+	return stats, nil
+}
@@ -1,61 +1,229 @@
 package latex
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"log"
 	"os"
-	"os/exec"
 	"path/filepath"
+	"time"
 )
 
 type Compiler struct {
-	outputDir string
+	outputDir     string
+	limits        Limits
+	cacheDir      string
+	defaultEngine string
+	// available 是 DetectEngines 检测到的引擎，nil 表示未检测
+	available map[string]bool
 }
 
-func NewCompiler(outputDir string) *Compiler {
-	return &Compiler{outputDir: outputDir}
+// NewCompiler 创建编译器，编译缓存默认开启，位于 outputDir/cache
+func NewCompiler(outputDir string, limits Limits) *Compiler {
+	return &Compiler{
+		outputDir:     outputDir,
+		limits:        limits,
+		cacheDir:      filepath.Join(outputDir, "cache"),
+		defaultEngine: DefaultEngine,
+	}
+}
+
+// DisableCache 关闭编译缓存，每次编译都会重新运行 TeX 引擎
+func (c *Compiler) DisableCache() {
+	c.cacheDir = ""
+}
+
+// ProgressFunc 在每一遍引擎运行前被调用，遍数由 rerun 检测动态决定，total 为 0 表示总数未知
+type ProgressFunc func(pass, total int)
+
+// Options 是单次编译的可选参数
+type Options struct {
+	// Engine 指定 TeX 引擎，为空时按 PreferredEngine（通常来自模板）或默认引擎选择
+	Engine          string
+	PreferredEngine string
+	// Assets 是与 main.tex 放在同一工作目录的资源文件（logo、.sty 等），键为相对路径
+	Assets   map[string][]byte
+	Progress ProgressFunc
+}
+
+// Result 是一次成功编译的结果
+type Result struct {
+	PDFPath string `json:"pdf_path"`
+	Engine  string `json:"engine"`
+	// Diagnostics 包含编译成功时仍然存在的警告和 bad box
+	Diagnostics []Diagnostic `json:"diagnostics"`
+	// Passes 是实际执行的编译步骤
+	Passes []Pass `json:"passes"`
+	// Cached 表示 PDF 直接取自编译缓存
+	Cached bool `json:"cached"`
+}
+
+func (c *Compiler) Compile(ctx context.Context, latexContent string, filename string) (*Result, error) {
+	return c.CompileWithOptions(ctx, latexContent, filename, Options{})
+}
+
+// CompileWithOptions 编译 LaTeX 源码为 PDF。编译失败时返回 *CompileError
+func (c *Compiler) CompileWithOptions(ctx context.Context, latexContent string, filename string, opts Options) (*Result, error) {
+	files := make(map[string][]byte, len(opts.Assets)+1)
+	for name, data := range opts.Assets {
+		files[name] = data
+	}
+	files["main.tex"] = []byte(latexContent)
+	return c.compile(ctx, files, filename, opts)
 }
 
-func (c *Compiler) Compile(latexContent string, filename string) (string, error) {
+// compile 编译 files（必须包含 main.tex，其余为图片等资源），PDF 保存为 outputDir/filename
+func (c *Compiler) compile(ctx context.Context, files map[string][]byte, filename string, opts Options) (*Result, error) {
+	engine, err := c.ResolveEngine(opts.Engine, opts.PreferredEngine)
+	if err != nil {
+		return nil, err
+	}
+	progress := opts.Progress
+
 	// Ensure output directory exists
 	if err := os.MkdirAll(c.outputDir, 0755); err != nil {
-		return "", err
+		return nil, err
+	}
+	finalPDF := filepath.Join(c.outputDir, filename)
+
+	key := cacheKey(engine.Name, files)
+	if result, ok := c.lookupCache(key, finalPDF); ok {
+		result.Engine = engine.Name
+		return result, nil
+	}
+
+	if c.limits.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.limits.Timeout)
+		defer cancel()
 	}
 
 	// Create temporary directory for compilation
-	tempDir, err := os.MkdirTemp(c.outputDir, "latex-*")
+	tempDir, err := os.MkdirTemp(c.outputDir, tempDirPrefix+"*")
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+	defer os.RemoveAll(tempDir)
+	// TEXMFOUTPUT 需要绝对路径
+	if tempDir, err = filepath.Abs(tempDir); err != nil {
+		return nil, err
 	}
 
-	// Write LaTeX content to file
-	texFile := filepath.Join(tempDir, "main.tex")
-	if err := os.WriteFile(texFile, []byte(latexContent), 0644); err != nil {
-		return "", err
+	// Write LaTeX content and assets
+	if err := writeFiles(tempDir, files); err != nil {
+		return nil, err
+	}
+
+	// 按 latexmk 的方式只运行必要的遍数：首遍之后按需运行 bibtex/biber，
+	// 直到辅助文件稳定且日志不再要求 rerun
+	var passes []Pass
+	compileError := func(err error, output []byte) error {
+		return &CompileError{
+			Engine:      engine.Name,
+			Err:         err,
+			Diagnostics: readDiagnostics(tempDir, output),
+			Output:      string(output),
+			Passes:      passes,
+		}
 	}
 
-	// Run xelatex twice for proper compilation
-	for i := 0; i < 2; i++ {
-		cmd := exec.Command("xelatex", "-interaction=nonstopmode", "-output-directory="+tempDir, texFile)
-		output, err := cmd.CombinedOutput()
+	reason := "initial"
+	bibDone := false
+	for run := 1; ; run++ {
+		if progress != nil {
+			progress(run, 0)
+		}
+		before := snapshotAux(tempDir)
+		output, err := c.runPass(ctx, tempDir, &passes, reason, engine.Binary, engine.Args...)
+		if errors.Is(err, ErrCompileTimeout) || errors.Is(err, context.Canceled) {
+			return nil, err
+		}
 		if err != nil {
-			return "", fmt.Errorf("xelatex compilation failed: %s, output: %s", err, string(output))
+			return nil, compileError(err, output)
+		}
+
+		// tectonic 等引擎自行完成多遍编译
+		if engine.SelfDriving {
+			break
+		}
+
+		if !bibDone {
+			bibDone = true
+			if program, bibReason := bibliographyStep(tempDir); program != "" {
+				// 参考文献工具失败不终止编译，未定义的引用会在最终日志中体现
+				_, err := c.runPass(ctx, tempDir, &passes, bibReason, program, "main")
+				if errors.Is(err, ErrCompileTimeout) || errors.Is(err, context.Canceled) {
+					return nil, err
+				}
+			}
+		}
+
+		if reason = rerunReason(tempDir, before); reason == "" {
+			break
+		}
+		if run >= maxEnginePasses {
+			log.Printf("LaTeX still requests rerun after %d passes: %s", run, reason)
+			break
 		}
 	}
 
 	// Check if PDF was generated
 	pdfFile := filepath.Join(tempDir, "main.pdf")
 	if _, err := os.Stat(pdfFile); os.IsNotExist(err) {
-		return "", fmt.Errorf("PDF file was not generated")
+		return nil, compileError(fmt.Errorf("PDF file was not generated"), nil)
 	}
 
 	// Move PDF to final location
-	finalPDF := filepath.Join(c.outputDir, filename)
 	if err := os.Rename(pdfFile, finalPDF); err != nil {
-		return "", err
+		return nil, err
 	}
 
-	// Clean up temp directory (optional, can keep for debugging)
-	// os.RemoveAll(tempDir)
+	result := &Result{
+		PDFPath:     finalPDF,
+		Engine:      engine.Name,
+		Diagnostics: readDiagnostics(tempDir, nil),
+		Passes:      passes,
+	}
+	c.storeCache(key, result)
+
+	return result, nil
+}
 
-	return finalPDF, nil
+// runPass 运行一个编译步骤并追加到 passes
+func (c *Compiler) runPass(ctx context.Context, dir string, passes *[]Pass, reason string, name string, args ...string) ([]byte, error) {
+	start := time.Now()
+	output, err := runSandboxed(ctx, dir, c.limits, name, args...)
+
+	pass := Pass{Program: name, Reason: reason, DurationMs: time.Since(start).Milliseconds()}
+	if err != nil {
+		pass.Error = err.Error()
+	}
+	*passes = append(*passes, pass)
+	return output, err
+}
+
+// writeFiles 把源码和资源写入 dir，拒绝指向 dir 之外的路径
+func writeFiles(dir string, files map[string][]byte) error {
+	for name, data := range files {
+		if !filepath.IsLocal(name) {
+			return fmt.Errorf("invalid file path %q", name)
+		}
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readDiagnostics 优先解析 main.log，日志不存在时退回到终端输出
+func readDiagnostics(dir string, output []byte) []Diagnostic {
+	if data, err := os.ReadFile(filepath.Join(dir, "main.log")); err == nil {
+		return ParseLog(string(data))
+	}
+	return ParseLog(string(output))
 }
@@ -0,0 +1,210 @@
+package latex
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Diagnostic 是从 TeX 日志中解析出的一条错误或警告
+type Diagnostic struct {
+	Severity string `json:"severity"` // error, warning, badbox
+	Kind     string `json:"kind,omitempty"`
+	Line     int    `json:"line,omitempty"`
+	EndLine  int    `json:"end_line,omitempty"`
+	Message  string `json:"message"`
+	Snippet  string `json:"snippet,omitempty"`
+}
+
+// CompileError 表示编译失败，携带解析后的诊断信息
+type CompileError struct {
+	Engine      string
+	Err         error
+	Diagnostics []Diagnostic
+	Output      string
+	Passes      []Pass
+}
+
+func (e *CompileError) Error() string {
+	for _, d := range e.Diagnostics {
+		if d.Severity == "error" {
+			if d.Line > 0 {
+				return fmt.Sprintf("%s compilation failed: line %d: %s", e.Engine, d.Line, d.Message)
+			}
+			return fmt.Sprintf("%s compilation failed: %s", e.Engine, d.Message)
+		}
+	}
+	return fmt.Sprintf("%s compilation failed: %v", e.Engine, e.Err)
+}
+
+func (e *CompileError) Unwrap() error {
+	return e.Err
+}
+
+// Errors 返回 severity 为 error 的诊断
+func (e *CompileError) Errors() []Diagnostic {
+	var errs []Diagnostic
+	for _, d := range e.Diagnostics {
+		if d.Severity == "error" {
+			errs = append(errs, d)
+		}
+	}
+	return errs
+}
+
+var (
+	fileLineErrorRe = regexp.MustCompile(`^\.?/?[^:\s]+\.tex:(\d+): (.+)$`)
+	errorContextRe  = regexp.MustCompile(`^l\.(\d+) ?(.*)$`)
+	inputLineRe     = regexp.MustCompile(`on input line (\d+)\.?`)
+	warningRe       = regexp.MustCompile(`^(LaTeX|Package ([\w@.-]+)|Class ([\w@.-]+)) Warning: (.*)$`)
+	continuationRe  = regexp.MustCompile(`^\(([\w@.-]+)\)\s+(.*)$`)
+	badboxRe        = regexp.MustCompile(`^(Overfull|Underfull) \\([hv]box) \((.+?)\) (?:in paragraph |in alignment |has occurred while \\output is active)?(?:at lines? (\d+)(?:--(\d+))?|detected at line (\d+))?`)
+	missingCharRe   = regexp.MustCompile(`^Missing character: There is no (.+)$`)
+)
+
+// ParseLog 解析 TeX 日志（main.log 或终端输出），返回按出现顺序排列的诊断信息
+func ParseLog(log string) []Diagnostic {
+	lines := strings.Split(strings.ReplaceAll(log, "\r\n", "\n"), "\n")
+	var diags []Diagnostic
+	seen := make(map[string]bool)
+
+	add := func(d Diagnostic) {
+		d.Message = strings.TrimSpace(d.Message)
+		key := fmt.Sprintf("%s|%d|%s", d.Severity, d.Line, d.Message)
+		if d.Message == "" || seen[key] {
+			return
+		}
+		seen[key] = true
+		diags = append(diags, d)
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimRight(lines[i], " ")
+
+		switch {
+		case strings.HasPrefix(line, "! "):
+			d := Diagnostic{Severity: "error", Kind: errorKind(line[2:]), Message: line[2:]}
+			i = scanErrorContext(lines, i, &d)
+			add(d)
+
+		case fileLineErrorRe.MatchString(line):
+			m := fileLineErrorRe.FindStringSubmatch(line)
+			n, _ := strconv.Atoi(m[1])
+			d := Diagnostic{Severity: "error", Kind: errorKind(m[2]), Line: n, Message: m[2]}
+			i = scanErrorContext(lines, i, &d)
+			add(d)
+
+		case warningRe.MatchString(line):
+			m := warningRe.FindStringSubmatch(line)
+			msg := m[4]
+			// 多行警告以 "(package)  " 开头续行
+			for i+1 < len(lines) {
+				c := continuationRe.FindStringSubmatch(strings.TrimRight(lines[i+1], " "))
+				if c == nil {
+					break
+				}
+				msg += " " + c[2]
+				i++
+			}
+			d := Diagnostic{Severity: "warning", Kind: warningKind(m[1], msg), Message: msg}
+			if lm := inputLineRe.FindStringSubmatch(msg); lm != nil {
+				d.Line, _ = strconv.Atoi(lm[1])
+			}
+			add(d)
+
+		case badboxRe.MatchString(line):
+			m := badboxRe.FindStringSubmatch(line)
+			d := Diagnostic{
+				Severity: "badbox",
+				Kind:     strings.ToLower(m[1]) + "_" + m[2],
+				Message:  line,
+			}
+			switch {
+			case m[4] != "":
+				d.Line, _ = strconv.Atoi(m[4])
+				if m[5] != "" {
+					d.EndLine, _ = strconv.Atoi(m[5])
+				}
+			case m[6] != "":
+				d.Line, _ = strconv.Atoi(m[6])
+			}
+			// 下一行通常是盒子内容的片段，以 "[]" 开头
+			if i+1 < len(lines) {
+				d.Snippet = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(lines[i+1]), "[]"))
+			}
+			add(d)
+
+		case missingCharRe.MatchString(line):
+			m := missingCharRe.FindStringSubmatch(line)
+			add(Diagnostic{Severity: "warning", Kind: "missing_character", Message: "Missing character: There is no " + m[1]})
+		}
+	}
+
+	return diags
+}
+
+// scanErrorContext 读取错误后的上下文，提取 "l.<行号> <代码>" 行，返回最后消费的行下标
+func scanErrorContext(lines []string, i int, d *Diagnostic) int {
+	for j := i + 1; j < len(lines) && j <= i+12; j++ {
+		line := strings.TrimRight(lines[j], " ")
+		if strings.HasPrefix(line, "! ") || fileLineErrorRe.MatchString(line) {
+			return j - 1
+		}
+		if m := errorContextRe.FindStringSubmatch(line); m != nil {
+			n, _ := strconv.Atoi(m[1])
+			if d.Line == 0 {
+				d.Line = n
+			}
+			d.Snippet = strings.TrimSpace(m[2])
+			// TeX 会把出错位置之后的内容折到下一行
+			if j+1 < len(lines) {
+				if rest := strings.TrimSpace(lines[j+1]); rest != "" && !strings.HasPrefix(rest, "!") {
+					d.Snippet = strings.TrimSpace(d.Snippet + " " + rest)
+				}
+			}
+			return j
+		}
+	}
+	return i
+}
+
+func errorKind(message string) string {
+	switch {
+	case strings.HasPrefix(message, "Undefined control sequence"):
+		return "undefined_control_sequence"
+	case strings.HasPrefix(message, "Missing $ inserted"):
+		return "missing_math_shift"
+	case strings.HasPrefix(message, "Missing } inserted"), strings.HasPrefix(message, "Missing { inserted"), strings.Contains(message, "Extra }"):
+		return "unbalanced_braces"
+	case strings.Contains(message, "not found"):
+		return "missing_file"
+	case strings.HasPrefix(message, "LaTeX Error: Environment") && strings.Contains(message, "undefined"):
+		return "undefined_environment"
+	case strings.Contains(message, "\\begin{") && strings.Contains(message, "ended by \\end{"):
+		return "mismatched_environment"
+	case strings.HasPrefix(message, "Emergency stop"), strings.HasPrefix(message, "==> Fatal error"):
+		return "fatal"
+	case strings.HasPrefix(message, "LaTeX Error"):
+		return "latex_error"
+	case strings.HasPrefix(message, "Package "):
+		return "package_error"
+	}
+	return ""
+}
+
+func warningKind(source, message string) string {
+	switch {
+	case strings.HasPrefix(message, "Reference") && strings.Contains(message, "undefined"):
+		return "undefined_reference"
+	case strings.HasPrefix(message, "Citation") && strings.Contains(message, "undefined"):
+		return "undefined_citation"
+	case needsRerunHint(message):
+		return "rerun"
+	case strings.HasPrefix(message, "Font shape"):
+		return "font"
+	case strings.HasPrefix(source, "Package"):
+		return "package_warning"
+	}
+	return ""
+}
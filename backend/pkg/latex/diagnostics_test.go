@@ -0,0 +1,80 @@
+package latex
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseLog(t *testing.T) {
+	tests := []struct {
+		name string
+		log  string
+		want []Diagnostic
+	}{
+		{
+			name: "undefined control sequence",
+			log:  "! Undefined control sequence.\nl.12 \\foo\n           bar\n",
+			want: []Diagnostic{{Severity: "error", Kind: "undefined_control_sequence", Line: 12, Message: "Undefined control sequence.", Snippet: `\foo bar`}},
+		},
+		{
+			name: "file line error",
+			log:  "./main.tex:7: LaTeX Error: Environment foo undefined.\n\nl.7 \\begin{foo}\n",
+			want: []Diagnostic{{Severity: "error", Kind: "undefined_environment", Line: 7, Message: "LaTeX Error: Environment foo undefined.", Snippet: `\begin{foo}`}},
+		},
+		{
+			name: "duplicate errors",
+			log:  "! Missing $ inserted.\nl.3 a_b\n! Missing $ inserted.\nl.3 a_b\n",
+			want: []Diagnostic{{Severity: "error", Kind: "missing_math_shift", Line: 3, Message: "Missing $ inserted.", Snippet: "a_b"}},
+		},
+		{
+			name: "multi-line warning",
+			log:  "Package hyperref Warning: Token not allowed in a PDF string\n(hyperref)                removing `math shift' on input line 42.\n",
+			want: []Diagnostic{{Severity: "warning", Kind: "package_warning", Line: 42, Message: "Token not allowed in a PDF string removing `math shift' on input line 42."}},
+		},
+		{
+			name: "undefined reference and rerun",
+			log:  "LaTeX Warning: Reference `fig:a' on page 2 undefined on input line 20.\nLaTeX Warning: Label(s) may have changed. Rerun to get cross-references right.\n",
+			want: []Diagnostic{
+				{Severity: "warning", Kind: "undefined_reference", Line: 20, Message: "Reference `fig:a' on page 2 undefined on input line 20."},
+				{Severity: "warning", Kind: "rerun", Message: "Label(s) may have changed. Rerun to get cross-references right."},
+			},
+		},
+		{
+			name: "badbox",
+			log:  "Overfull \\hbox (12.3pt too wide) in paragraph at lines 10--12\n[]\\T1/cmr/m/n/10 long text\n",
+			want: []Diagnostic{{Severity: "badbox", Kind: "overfull_hbox", Line: 10, EndLine: 12, Message: `Overfull \hbox (12.3pt too wide) in paragraph at lines 10--12`, Snippet: `\T1/cmr/m/n/10 long text`}},
+		},
+		{
+			name: "missing character",
+			log:  "Missing character: There is no 中 in font cmr10!\r\n",
+			want: []Diagnostic{{Severity: "warning", Kind: "missing_character", Message: "Missing character: There is no 中 in font cmr10!"}},
+		},
+		{
+			name: "no diagnostics",
+			log:  "This is XeTeX, Version 3.141592653\nOutput written on main.pdf (3 pages).\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseLog(tt.log); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseLog() =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompileErrorMessage(t *testing.T) {
+	err := &CompileError{
+		Engine: "xelatex",
+		Diagnostics: []Diagnostic{
+			{Severity: "warning", Message: "Font shape undefined"},
+			{Severity: "error", Line: 5, Message: "Undefined control sequence."},
+		},
+	}
+	if got, want := err.Error(), "xelatex compilation failed: line 5: Undefined control sequence."; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+	if got := err.Errors(); len(got) != 1 || got[0].Line != 5 {
+		t.Errorf("Errors() = %+v", got)
+	}
+}
@@ -0,0 +1,137 @@
+package latex
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"sort"
+)
+
+var (
+	ErrUnknownEngine     = errors.New("unknown TeX engine")
+	ErrEngineUnavailable = errors.New("TeX engine is not installed")
+)
+
+// Engine 描述一个 TeX 引擎如何编译工作目录中的 main.tex
+type Engine struct {
+	Name string
+	// Binary 是可执行文件名，启动时通过 PATH 检测是否安装
+	Binary string
+	Args   []string
+	// SelfDriving 为 true 时引擎自行处理多遍编译和参考文献，不需要 rerun 检测
+	SelfDriving bool
+}
+
+var engines = map[string]Engine{
+	"xelatex": {
+		Name:   "xelatex",
+		Binary: "xelatex",
+		Args:   []string{"-no-shell-escape", "-interaction=nonstopmode", "-halt-on-error", "main.tex"},
+	},
+	"lualatex": {
+		Name:   "lualatex",
+		Binary: "lualatex",
+		Args:   []string{"-no-shell-escape", "-interaction=nonstopmode", "-halt-on-error", "main.tex"},
+	},
+	"pdflatex": {
+		Name:   "pdflatex",
+		Binary: "pdflatex",
+		Args:   []string{"-no-shell-escape", "-interaction=nonstopmode", "-halt-on-error", "main.tex"},
+	},
+	"tectonic": {
+		Name:   "tectonic",
+		Binary: "tectonic",
+		// --untrusted 禁用 shell-escape 等不安全特性，--keep-logs 保留 main.log 供诊断解析
+		Args:        []string{"--untrusted", "--keep-logs", "--chatter", "minimal", "--outdir", ".", "main.tex"},
+		SelfDriving: true,
+	},
+}
+
+// DefaultEngine 是未配置时使用的引擎
+const DefaultEngine = "xelatex"
+
+// EngineInfo 描述引擎在本机的可用情况
+type EngineInfo struct {
+	Name      string `json:"name"`
+	Available bool   `json:"available"`
+	Default   bool   `json:"default"`
+}
+
+// LookupEngine 按名称查找引擎
+func LookupEngine(name string) (Engine, error) {
+	e, ok := engines[name]
+	if !ok {
+		return Engine{}, fmt.Errorf("%w: %s", ErrUnknownEngine, name)
+	}
+	return e, nil
+}
+
+// DetectEngines 检测 PATH 中已安装的引擎，返回可用引擎名称
+func (c *Compiler) DetectEngines() []string {
+	c.available = make(map[string]bool, len(engines))
+	var names []string
+	for name, e := range engines {
+		if _, err := exec.LookPath(e.Binary); err == nil {
+			c.available[name] = true
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SetDefaultEngine 设置未指定引擎时使用的引擎
+func (c *Compiler) SetDefaultEngine(name string) error {
+	if _, err := LookupEngine(name); err != nil {
+		return err
+	}
+	c.defaultEngine = name
+	return nil
+}
+
+// DefaultEngineName 返回默认引擎名称
+func (c *Compiler) DefaultEngineName() string {
+	return c.defaultEngine
+}
+
+// Engines 返回所有已知引擎及其可用情况
+func (c *Compiler) Engines() []EngineInfo {
+	infos := make([]EngineInfo, 0, len(engines))
+	for name := range engines {
+		infos = append(infos, EngineInfo{
+			Name:      name,
+			Available: c.isAvailable(name),
+			Default:   name == c.defaultEngine,
+		})
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos
+}
+
+// isAvailable 未执行过检测时假定所有引擎可用，由执行时报错
+func (c *Compiler) isAvailable(name string) bool {
+	if c.available == nil {
+		return true
+	}
+	return c.available[name]
+}
+
+// ResolveEngine 按 请求指定 > 模板偏好 > 默认 的顺序选择引擎。
+// 显式指定的引擎必须已安装；偏好的引擎未知或未安装时退回默认引擎
+func (c *Compiler) ResolveEngine(requested, preferred string) (Engine, error) {
+	if requested != "" {
+		e, err := LookupEngine(requested)
+		if err != nil {
+			return Engine{}, err
+		}
+		if !c.isAvailable(requested) {
+			return Engine{}, fmt.Errorf("%w: %s", ErrEngineUnavailable, requested)
+		}
+		return e, nil
+	}
+
+	if _, err := LookupEngine(preferred); err == nil && c.isAvailable(preferred) {
+		return LookupEngine(preferred)
+	}
+	return LookupEngine(c.defaultEngine)
+}
@@ -0,0 +1,774 @@
+package latex
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// BlockKind 是 frame 中内容块的类型
+type BlockKind string
+
+const (
+	BlockParagraph BlockKind = "paragraph"
+	BlockList      BlockKind = "list"
+	BlockImage     BlockKind = "image"
+	BlockTable     BlockKind = "table"
+	BlockMath      BlockKind = "math"
+	BlockCode      BlockKind = "code"
+	// BlockBox 对应 beamer 的 block / alertblock / exampleblock
+	BlockBox BlockKind = "block"
+	// BlockColumns 对应 columns 环境，每一栏的内容在 Columns 中
+	BlockColumns BlockKind = "columns"
+)
+
+// Block 是 frame 中的一个内容块，文本字段保留行内 LaTeX 源码，由导出方通过 ParseInline 解析
+type Block struct {
+	Kind BlockKind `json:"kind"`
+	// Text 是段落文本、公式源码、代码或 block 标题
+	Text string `json:"text,omitempty"`
+	// Style 是 block / alertblock / exampleblock，或公式、代码、表格所用的环境名；
+	// 公式的 Style 非空时 Text 是该环境的内容，导出时使用 DisplayMath
+	Style string `json:"style,omitempty"`
+	// List
+	Ordered bool       `json:"ordered,omitempty"`
+	Items   []ListItem `json:"items,omitempty"`
+	// Image
+	Path    string `json:"path,omitempty"`
+	Caption string `json:"caption,omitempty"`
+	// Options 是 \includegraphics 和 columns 的可选参数、tabular 的列格式，
+	// 或列表、代码环境名之后的参数原文，如 [<+->]、[language=Go]
+	Options string `json:"options,omitempty"`
+	// Table：Width 是 tabularx 和 tabular* 的宽度
+	Rows  [][]string `json:"rows,omitempty"`
+	Width string     `json:"width,omitempty"`
+	// Box
+	Children []Block `json:"children,omitempty"`
+	// Columns
+	Columns []Column `json:"columns,omitempty"`
+}
+
+// Column 是 columns 环境中的一栏，Width 为 LaTeX 长度，如 0.5\textwidth
+type Column struct {
+	Width  string  `json:"width,omitempty"`
+	Blocks []Block `json:"blocks,omitempty"`
+}
+
+// ListItem 是列表项，Overlay 是 \item<2-> 的覆盖规则，Children 保存嵌套列表等后续内容块
+type ListItem struct {
+	Text     string  `json:"text"`
+	Label    string  `json:"label,omitempty"`
+	Overlay  string  `json:"overlay,omitempty"`
+	Children []Block `json:"children,omitempty"`
+}
+
+// Frame 是解析后的一页 beamer frame
+type Frame struct {
+	// Section 由 Deck.Frames 根据所在章节填写，不单独存储
+	Section    string `json:"-"`
+	Subsection string `json:"subsection,omitempty"`
+	// Options 是 \begin{frame}[...] 的选项，如 fragile,allowframebreaks
+	Options  string `json:"options,omitempty"`
+	Title    string `json:"title,omitempty"`
+	Subtitle string `json:"subtitle,omitempty"`
+	// TitlePage 为 true 时该 frame 是 \titlepage 标题页
+	TitlePage bool    `json:"title_page,omitempty"`
+	Blocks    []Block `json:"blocks,omitempty"`
+	// Notes 是 \note 演讲者备注
+	Notes []string `json:"notes,omitempty"`
+	// Unsupported 记录无法结构化解析的内容（如 tikzpicture），导出时该页需要退回为图片，
+	// RenderDeck 对这类 frame 原样输出 Source
+	Unsupported []string `json:"unsupported,omitempty"`
+	// Source 是 frame 的原始 LaTeX 源码
+	Source string `json:"source"`
+}
+
+// Section 是一个 \section 及其下的 frame，第一个 \section 之前的 frame 位于标题为空的章节中
+type Section struct {
+	Title  string  `json:"title,omitempty"`
+	Frames []Frame `json:"frames"`
+}
+
+// Deck 是 beamer 文档的结构化表示：元信息、导言区和按章节组织的 frame。
+// 可以 JSON 形式存储，编辑后由 RenderDeck 重新生成 LaTeX
+type Deck struct {
+	Title     string `json:"title,omitempty"`
+	Subtitle  string `json:"subtitle,omitempty"`
+	Author    string `json:"author,omitempty"`
+	Institute string `json:"institute,omitempty"`
+	Date      string `json:"date,omitempty"`
+	// AspectRatio 是 \documentclass 的 aspectratio 选项，如 "169"；为空表示 beamer 默认的 4:3
+	AspectRatio string `json:"aspect_ratio,omitempty"`
+	// Preamble 是 \begin{document} 之前的内容，单独编译某个 frame 时复用
+	Preamble string    `json:"preamble,omitempty"`
+	Sections []Section `json:"sections"`
+}
+
+// Frames 按文档顺序返回所有 frame，并填写各自所在的章节
+func (d *Deck) Frames() []Frame {
+	var frames []Frame
+	for _, section := range d.Sections {
+		for _, frame := range section.Frames {
+			frame.Section = section.Title
+			frames = append(frames, frame)
+		}
+	}
+	return frames
+}
+
+// addFrame 把 frame 追加到最后一个章节
+func (d *Deck) addFrame(frame Frame) {
+	if len(d.Sections) == 0 {
+		d.Sections = append(d.Sections, Section{})
+	}
+	last := &d.Sections[len(d.Sections)-1]
+	last.Frames = append(last.Frames, frame)
+}
+
+// lastFrame 返回最后一个 frame，没有 frame 时返回 nil
+func (d *Deck) lastFrame() *Frame {
+	for i := len(d.Sections) - 1; i >= 0; i-- {
+		if frames := d.Sections[i].Frames; len(frames) > 0 {
+			return &frames[len(frames)-1]
+		}
+	}
+	return nil
+}
+
+var aspectRatioPattern = regexp.MustCompile(`\\documentclass\s*\[[^\]]*aspectratio\s*=\s*(\d+)`)
+
+// 内容按原样展开的容器环境
+var transparentEnvs = map[string]int{
+	"center": 0, "flushleft": 0, "flushright": 0, "figure": 0, "figure*": 0,
+	"table": 0, "table*": 0, "minipage": 1,
+	"onlyenv": 0, "visibleenv": 0, "uncoverenv": 0, "actionenv": 0,
+}
+
+var mathEnvs = map[string]string{
+	"equation": "", "equation*": "", "displaymath": "",
+	"align": "aligned", "align*": "aligned", "gather": "gathered", "gather*": "gathered",
+	"multline": "gathered", "multline*": "gathered", "eqnarray": "aligned", "eqnarray*": "aligned",
+}
+
+var tableEnvs = map[string]int{"tabular": 1, "tabular*": 2, "tabularx": 2, "longtable": 1}
+
+var ruleCommandPattern = regexp.MustCompile(`\\(hline|toprule|midrule|bottomrule)\b|\\(cline|cmidrule)(\([^)]*\))?\{[^}]*\}`)
+
+var labelPattern = regexp.MustCompile(`\\label\{[^}]*\}`)
+
+var blankLinePattern = regexp.MustCompile(`\n[ \t]*\n`)
+
+// ParseDeck 解析 beamer 文档的元信息、章节和 frame。
+// 解析是尽力而为的：无法识别的环境记录在 Frame.Unsupported 中，不会返回错误
+func ParseDeck(source string) *Deck {
+	deck, _ := parseDeck(source)
+	return deck
+}
+
+// ReplaceFrame 把文档中第 index 个 frame（从 0 开始，与 Deck.Frames 的顺序一致）替换为 frame，
+// 文档的其余部分保持原样
+func ReplaceFrame(source string, index int, frame string) (string, bool) {
+	_, spans := parseDeck(source)
+	if index < 0 || index >= len(spans) {
+		return source, false
+	}
+	span := spans[index]
+	return source[:span[0]] + strings.TrimSpace(frame) + source[span[1]:], true
+}
+
+// FirstFrame 返回 s 中第一个完整的 frame 环境
+func FirstFrame(s string) (string, bool) {
+	start := strings.Index(s, `\begin{frame}`)
+	if start < 0 {
+		return "", false
+	}
+	_, end := envBody(s, start+len(`\begin{frame}`), "frame")
+	if !strings.HasSuffix(s[:end], `\end{frame}`) {
+		return "", false
+	}
+	return s[start:end], true
+}
+
+// parseDeck 解析文档，并返回每个 frame 在 source 中的起止位置。
+// 注释替换为等长的空白后再解析，位置可以直接用于原文
+func parseDeck(original string) (*Deck, [][2]int) {
+	source := maskComments(original)
+	var spans [][2]int
+
+	deck := &Deck{
+		Title:     commandArg(source, "title"),
+		Subtitle:  commandArg(source, "subtitle"),
+		Author:    commandArg(source, "author"),
+		Institute: commandArg(source, "institute"),
+		Date:      commandArg(source, "date"),
+	}
+	if m := aspectRatioPattern.FindStringSubmatch(source); m != nil {
+		deck.AspectRatio = m[1]
+	}
+
+	body, base := source, 0
+	if i := strings.Index(source, `\begin{document}`); i >= 0 {
+		deck.Preamble = stripComments(original[:i])
+		base = i + len(`\begin{document}`)
+		body = source[base:]
+	}
+	if i := strings.Index(body, `\end{document}`); i >= 0 {
+		body = body[:i]
+	}
+
+	var subsection string
+	for i := 0; i < len(body); {
+		rest := body[i:]
+		switch {
+		case strings.HasPrefix(rest, `\begin{frame}`):
+			start := i
+			inner, next := envBody(body, i+len(`\begin{frame}`), "frame")
+			frame := parseFrame(inner)
+			frame.Subsection = subsection
+			frame.Source = original[base+start : base+next]
+			deck.addFrame(frame)
+			spans = append(spans, [2]int{base + start, base + next})
+			i = next
+		case hasCommand(rest, "section"):
+			title, next := sectionTitle(body, i+len(`\section`))
+			deck.Sections = append(deck.Sections, Section{Title: title})
+			subsection = ""
+			i = next
+		case hasCommand(rest, "subsection"):
+			subsection, i = sectionTitle(body, i+len(`\subsection`))
+		case hasCommand(rest, "maketitle"), hasCommand(rest, "titlepage"):
+			// frame 外的 \maketitle 也会生成标题页
+			deck.addFrame(Frame{TitlePage: true, Source: `\begin{frame}\titlepage\end{frame}`})
+			spans = append(spans, [2]int{base + i, base + i + len(`\maketitle`)})
+			i += len(`\maketitle`)
+		case hasCommand(rest, "note"):
+			// frame 之间的 \note 属于前一个 frame
+			note, next, ok := readGroup(body, skipBlank(body, skipOverlayAndOptions(body, i+len(`\note`))))
+			if frame := deck.lastFrame(); ok && frame != nil {
+				frame.Notes = append(frame.Notes, strings.TrimSpace(note))
+			}
+			i = max(next, i+len(`\note`))
+		default:
+			i++
+		}
+	}
+	return deck, spans
+}
+
+// parseFrame 解析 \begin{frame} 之后的参数和正文
+func parseFrame(inner string) Frame {
+	var frame Frame
+	var i int
+	frame.Options, i = readOptions(inner, 0)
+
+	// \begin{frame}{标题}{副标题}
+	if j := skipBlank(inner, i); j < len(inner) && inner[j] == '{' {
+		if title, next, ok := readGroup(inner, j); ok {
+			frame.Title, i = title, next
+			if j := skipBlank(inner, i); j < len(inner) && inner[j] == '{' {
+				if subtitle, next, ok := readGroup(inner, j); ok {
+					frame.Subtitle, i = subtitle, next
+				}
+			}
+		}
+	}
+	body := inner[i:]
+
+	if title, rest, ok := extractCommand(body, "frametitle"); ok {
+		frame.Title, body = title, rest
+	}
+	if subtitle, rest, ok := extractCommand(body, "framesubtitle"); ok {
+		frame.Subtitle, body = subtitle, rest
+	}
+	for {
+		note, rest, ok := extractCommand(body, "note")
+		if !ok {
+			break
+		}
+		frame.Notes, body = append(frame.Notes, strings.TrimSpace(note)), rest
+	}
+	if strings.Contains(body, `\titlepage`) || strings.Contains(body, `\maketitle`) {
+		frame.TitlePage = true
+		body = strings.NewReplacer(`\titlepage`, "", `\maketitle`, "").Replace(body)
+	}
+
+	frame.Blocks, frame.Unsupported = parseBlocks(body)
+	return frame
+}
+
+// parseBlocks 把 frame 正文解析为内容块，返回无法解析的环境或命令
+func parseBlocks(body string) ([]Block, []string) {
+	var blocks []Block
+	var unsupported []string
+	var text strings.Builder
+
+	flush := func() {
+		for _, para := range splitParagraphs(text.String()) {
+			blocks = append(blocks, Block{Kind: BlockParagraph, Text: para})
+		}
+		text.Reset()
+	}
+
+	for i := 0; i < len(body); {
+		rest := body[i:]
+		switch {
+		case strings.HasPrefix(rest, `\begin{`):
+			name, after, ok := readGroup(body, i+len(`\begin`))
+			if !ok {
+				text.WriteByte(body[i])
+				i++
+				continue
+			}
+			inner, next := envBody(body, after, name)
+			flush()
+			children, missing := parseEnvironment(name, inner)
+			blocks = append(blocks, children...)
+			unsupported = append(unsupported, missing...)
+			i = next
+		case strings.HasPrefix(rest, `\[`):
+			end := strings.Index(rest, `\]`)
+			if end < 0 {
+				end = len(rest)
+			}
+			flush()
+			blocks = append(blocks, mathBlock(rest[2:end]))
+			i += min(end+2, len(rest))
+		case strings.HasPrefix(rest, "$$"):
+			end := strings.Index(rest[2:], "$$")
+			if end < 0 {
+				end = len(rest) - 2
+			}
+			flush()
+			blocks = append(blocks, mathBlock(rest[2:2+end]))
+			i += min(end+4, len(rest))
+		case hasCommand(rest, "includegraphics"):
+			options, j := readOptions(body, i+len(`\includegraphics`))
+			path, next, ok := readGroup(body, skipBlank(body, j))
+			if !ok {
+				i = j
+				continue
+			}
+			flush()
+			blocks = append(blocks, Block{Kind: BlockImage, Path: strings.TrimSpace(path), Options: options})
+			i = next
+		case hasCommand(rest, "caption"):
+			j := skipOverlayAndOptions(body, i+len(`\caption`))
+			caption, next, ok := readGroup(body, skipBlank(body, j))
+			if !ok {
+				i = j
+				continue
+			}
+			flush()
+			if n := len(blocks); n > 0 && blocks[n-1].Kind == BlockImage && blocks[n-1].Caption == "" {
+				blocks[n-1].Caption = caption
+			} else {
+				blocks = append(blocks, Block{Kind: BlockParagraph, Text: caption})
+			}
+			i = next
+		case hasCommand(rest, "tableofcontents"):
+			unsupported = append(unsupported, "tableofcontents")
+			i += len(`\tableofcontents`)
+		case rest[0] == '\\' && len(rest) > 1:
+			// 转义字符整体写入，避免 \{ 被当作分组
+			text.WriteString(rest[:2])
+			i += 2
+		default:
+			text.WriteByte(body[i])
+			i++
+		}
+	}
+	flush()
+	return blocks, unsupported
+}
+
+// parseEnvironment 把一个环境转换为内容块
+func parseEnvironment(name, inner string) ([]Block, []string) {
+	switch {
+	case name == "itemize" || name == "enumerate" || name == "description":
+		return []Block{parseList(name, inner)}, nil
+	case name == "block" || name == "alertblock" || name == "exampleblock":
+		i := skipOverlayAndOptions(inner, 0)
+		title, next, ok := readGroup(inner, skipBlank(inner, i))
+		if ok {
+			i = next
+		}
+		children, unsupported := parseBlocks(inner[i:])
+		return []Block{{Kind: BlockBox, Style: name, Text: title, Children: children}}, unsupported
+	case name == "columns":
+		return parseColumns(inner)
+	case slices.Contains(verbatimEnvs, name):
+		return []Block{parseCode(name, inner)}, nil
+	}
+
+	if args, ok := transparentEnvs[name]; ok {
+		i := skipOverlayAndOptions(inner, 0)
+		for ; args > 0; args-- {
+			if _, next, ok := readGroup(inner, skipBlank(inner, i)); ok {
+				i = next
+			}
+		}
+		return parseBlocks(inner[i:])
+	}
+	if args, ok := tableEnvs[name]; ok {
+		return []Block{parseTable(name, inner, args)}, nil
+	}
+	if _, ok := mathEnvs[name]; ok {
+		// 保留环境名和 \label，重新生成时 \ref 仍然有效
+		return []Block{{Kind: BlockMath, Style: name, Text: indentLines(inner, "")}}, nil
+	}
+	return nil, []string{name}
+}
+
+// parseColumns 解析 columns 环境中的各个 column，栏外的内容被忽略
+func parseColumns(inner string) ([]Block, []string) {
+	columns := Block{Kind: BlockColumns}
+	columns.Options, _ = readOptions(inner, 0)
+	var unsupported []string
+	for i := 0; ; {
+		start := strings.Index(inner[i:], `\begin{column}`)
+		if start < 0 {
+			break
+		}
+		body, next := envBody(inner, i+start+len(`\begin{column}`), "column")
+		i = next
+
+		var column Column
+		j := skipOverlayAndOptions(body, 0)
+		if width, after, ok := readGroup(body, skipBlank(body, j)); ok {
+			column.Width, j = strings.TrimSpace(width), after
+		}
+		blocks, missing := parseBlocks(body[j:])
+		column.Blocks = blocks
+		columns.Columns = append(columns.Columns, column)
+		unsupported = append(unsupported, missing...)
+	}
+	return []Block{columns}, unsupported
+}
+
+// parseList 按顶层 \item 拆分列表项
+func parseList(name, inner string) Block {
+	list := Block{Kind: BlockList, Ordered: name == "enumerate"}
+	i := skipOverlayAndOptions(inner, 0)
+	list.Options, inner = strings.TrimSpace(inner[:i]), inner[i:]
+
+	for _, raw := range splitTopLevel(inner, `\item`) {
+		var item ListItem
+		i := skipBlank(raw, 0)
+		if overlay, next, ok := readBracket(raw, i, '<', '>'); ok {
+			item.Overlay, i = overlay, skipBlank(raw, next)
+		}
+		if label, next, ok := readBracket(raw, i, '[', ']'); ok {
+			item.Label, i = label, next
+		}
+		blocks, _ := parseBlocks(raw[i:])
+		if len(blocks) > 0 && blocks[0].Kind == BlockParagraph {
+			item.Text, blocks = blocks[0].Text, blocks[1:]
+		}
+		item.Children = blocks
+		list.Items = append(list.Items, item)
+	}
+	return list
+}
+
+// parseCode 解析代码环境，环境名之后同一行的参数原样保存在 Options 中
+func parseCode(name, inner string) Block {
+	header := inner
+	if end := strings.IndexByte(inner, '\n'); end >= 0 {
+		header = inner[:end]
+	}
+	i := skipOverlayAndOptions(header, 0)
+	if name == "minted" {
+		if _, next, ok := readGroup(header, skipBlank(header, i)); ok {
+			i = next
+		}
+	}
+	code := inner[i:]
+	if end := strings.IndexByte(code, '\n'); end >= 0 && strings.TrimSpace(code[:end]) == "" {
+		code = code[end+1:]
+	}
+	return Block{Kind: BlockCode, Style: name, Options: strings.TrimSpace(header[:i]), Text: strings.TrimRight(code, " \t\n")}
+}
+
+// parseTable 解析 tabular 的行和单元格，args 是宽度和列格式等必选参数的个数
+func parseTable(name, inner string, args int) Block {
+	table := Block{Kind: BlockTable, Style: name}
+	i := skipOverlayAndOptions(inner, 0)
+	for ; args > 0; args-- {
+		// 最后一个必选参数是列格式，之前的是宽度
+		if spec, next, ok := readGroup(inner, skipBlank(inner, i)); ok {
+			if args > 1 {
+				table.Width = strings.TrimSpace(spec)
+			} else {
+				table.Options = strings.TrimSpace(spec)
+			}
+			i = next
+		}
+	}
+	content := ruleCommandPattern.ReplaceAllString(inner[i:], "")
+
+	for _, row := range splitTopLevel(content, `\\`) {
+		if strings.TrimSpace(row) == "" {
+			continue
+		}
+		var cells []string
+		for _, cell := range splitTopLevel(row, "&") {
+			cells = append(cells, strings.TrimSpace(cell))
+		}
+		table.Rows = append(table.Rows, cells)
+	}
+	return table
+}
+
+func mathBlock(source string) Block {
+	return Block{Kind: BlockMath, Text: indentLines(labelPattern.ReplaceAllString(source, ""), "")}
+}
+
+// DisplayMath 返回可以放在 \[ \] 中的公式：去掉 \label，并把 align 等环境换成 aligned
+func (b Block) DisplayMath() string {
+	if b.Style == "" {
+		return b.Text
+	}
+	math := strings.TrimSpace(labelPattern.ReplaceAllString(b.Text, ""))
+	if wrapper := mathEnvs[b.Style]; wrapper != "" {
+		math = `\begin{` + wrapper + "}\n" + math + "\n" + `\end{` + wrapper + "}"
+	}
+	return math
+}
+
+// splitTopLevel 按不在分组或嵌套环境内的分隔符拆分，丢弃第一个分隔符之前的空白内容
+func splitTopLevel(s, sep string) []string {
+	var parts []string
+	depth, envDepth, start := 0, 0, 0
+	first := true
+	for i := 0; i < len(s); i++ {
+		rest := s[i:]
+		switch {
+		case strings.HasPrefix(rest, `\begin{`):
+			envDepth++
+		case strings.HasPrefix(rest, `\end{`):
+			envDepth--
+		case depth == 0 && envDepth == 0 && strings.HasPrefix(rest, sep) && (sep != `\item` || !startsWithLetter(rest[len(sep):])):
+			if part := s[start:i]; !first || strings.TrimSpace(part) != "" {
+				parts = append(parts, part)
+			}
+			first = false
+			start = i + len(sep)
+			i = start - 1
+			continue
+		case s[i] == '\\':
+			i++
+		case s[i] == '{':
+			depth++
+		case s[i] == '}':
+			depth--
+		}
+	}
+	if part := s[start:]; !first || strings.TrimSpace(part) != "" {
+		parts = append(parts, part)
+	}
+	return parts
+}
+
+// envBody 返回 \begin{name} 之后到匹配的 \end{name} 之间的内容和 \end{name} 之后的位置
+func envBody(s string, i int, name string) (string, int) {
+	begin, end := `\begin{`+name+`}`, `\end{`+name+`}`
+	depth := 1
+	for j := i; j < len(s); j++ {
+		switch {
+		case strings.HasPrefix(s[j:], begin):
+			depth++
+		case strings.HasPrefix(s[j:], end):
+			depth--
+			if depth == 0 {
+				return s[i:j], j + len(end)
+			}
+		}
+	}
+	return s[i:], len(s)
+}
+
+// extractCommand 取出 body 中第一个 \name{...} 的参数，并返回去掉该命令后的 body
+func extractCommand(body, name string) (string, string, bool) {
+	arg, start, end, ok := findCommand(body, name)
+	if !ok {
+		return "", body, false
+	}
+	return arg, body[:start] + body[end:], true
+}
+
+// findCommand 查找第一个 \name[...]{...}，返回参数和整个命令的起止位置
+func findCommand(body, name string) (string, int, int, bool) {
+	for i := strings.Index(body, `\`+name); i >= 0; {
+		if hasCommand(body[i:], name) {
+			j := skipOverlayAndOptions(body, i+len(name)+1)
+			if arg, next, ok := readGroup(body, skipBlank(body, j)); ok {
+				return arg, i, next, true
+			}
+		}
+		next := strings.Index(body[i+1:], `\`+name)
+		if next < 0 {
+			break
+		}
+		i += next + 1
+	}
+	return "", 0, 0, false
+}
+
+// commandArg 返回文档中第一个 \name[...]{...} 的参数
+func commandArg(source, name string) string {
+	arg, _, _ := extractCommand(source, name)
+	return strings.TrimSpace(arg)
+}
+
+// sectionTitle 读取 \section 之后的标题，跳过星号和短标题
+func sectionTitle(s string, i int) (string, int) {
+	if i < len(s) && s[i] == '*' {
+		i++
+	}
+	i = skipOverlayAndOptions(s, i)
+	title, next, ok := readGroup(s, skipBlank(s, i))
+	if !ok {
+		return "", i
+	}
+	return title, next
+}
+
+// hasCommand 判断 s 是否以完整的命令 \name 开头（\section 不匹配 \sectionpage）
+func hasCommand(s, name string) bool {
+	return strings.HasPrefix(s, `\`+name) && !startsWithLetter(s[len(name)+1:])
+}
+
+func startsWithLetter(s string) bool {
+	return s != "" && isLetter(s[0])
+}
+
+// skipOverlayAndOptions 跳过 <overlay> 和 [options]
+func skipOverlayAndOptions(s string, i int) int {
+	for {
+		j := skipBlank(s, i)
+		if _, next, ok := readBracket(s, j, '<', '>'); ok {
+			i = next
+			continue
+		}
+		if _, next, ok := readBracket(s, j, '[', ']'); ok {
+			i = next
+			continue
+		}
+		return i
+	}
+}
+
+// readOptions 跳过 <overlay> 和 [options]，返回最后一个 [options] 的内容
+func readOptions(s string, i int) (string, int) {
+	var options string
+	for {
+		j := skipBlank(s, i)
+		if _, next, ok := readBracket(s, j, '<', '>'); ok {
+			i = next
+			continue
+		}
+		if opts, next, ok := readBracket(s, j, '[', ']'); ok {
+			options, i = strings.TrimSpace(opts), next
+			continue
+		}
+		return options, i
+	}
+}
+
+// skipBlank 跳过空白，但不跨越空行
+func skipBlank(s string, i int) int {
+	newlines := 0
+	for i < len(s) && strings.ContainsRune(" \t\r\n", rune(s[i])) {
+		if s[i] == '\n' {
+			newlines++
+			if newlines > 1 {
+				break
+			}
+		}
+		i++
+	}
+	return i
+}
+
+// splitParagraphs 按空行拆分文本，丢弃只包含版式命令的段落
+func splitParagraphs(s string) []string {
+	var paras []string
+	for _, para := range blankLinePattern.Split(s, -1) {
+		para = strings.TrimSpace(para)
+		if para != "" && len(ParseInline(para)) > 0 {
+			paras = append(paras, para)
+		}
+	}
+	return paras
+}
+
+// verbatimEnvs 中的 % 不是注释
+var verbatimEnvs = []string{"verbatim", "verbatim*", "Verbatim", "lstlisting", "minted"}
+
+// commentRanges 返回 % 开始的注释的起止位置，跳过 \%、\verb 和 verbatim 类环境中的内容
+func commentRanges(s string) [][2]int {
+	var ranges [][2]int
+	for i := 0; i < len(s); i++ {
+		rest := s[i:]
+		switch {
+		case strings.HasPrefix(rest, `\begin{`):
+			for _, name := range verbatimEnvs {
+				if begin := `\begin{` + name + `}`; strings.HasPrefix(rest, begin) {
+					end := strings.Index(rest, `\end{`+name+`}`)
+					if end < 0 {
+						return ranges
+					}
+					i += end
+					break
+				}
+			}
+		case hasCommand(rest, "verb"):
+			// \verb|...| 以 \verb 之后的第一个字符为定界符
+			j := len(`\verb`)
+			if j < len(rest) && rest[j] == '*' {
+				j++
+			}
+			if j >= len(rest) {
+				return ranges
+			}
+			end := strings.IndexByte(rest[j+1:], rest[j])
+			if end < 0 {
+				return ranges
+			}
+			i += j + 1 + end
+		case s[i] == '\\':
+			i++
+		case s[i] == '%':
+			end := strings.IndexByte(rest, '\n')
+			if end < 0 {
+				end = len(rest)
+			}
+			ranges = append(ranges, [2]int{i, i + end})
+			i += end
+		}
+	}
+	return ranges
+}
+
+// maskComments 把 % 开始的注释替换为等长的空格，保留 \% 和换行
+func maskComments(s string) string {
+	b := []byte(s)
+	for _, r := range commentRanges(s) {
+		for j := r[0]; j < r[1]; j++ {
+			b[j] = ' '
+		}
+	}
+	return string(b)
+}
+
+// stripComments 删除 % 开始的注释，保留 \%
+func stripComments(s string) string {
+	var b strings.Builder
+	last := 0
+	for _, r := range commentRanges(s) {
+		b.WriteString(s[last:r[0]])
+		last = r[1]
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
@@ -0,0 +1,75 @@
+package latex
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStripComments(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+	}{
+		{"line comment", "a % note\nb", "a \nb"},
+		{"escaped percent", `50\% done % note`, `50\% done `},
+		{"verbatim", "\\begin{verbatim}\nprintf(\"%d\")\n\\end{verbatim} % x", "\\begin{verbatim}\nprintf(\"%d\")\n\\end{verbatim} "},
+		{"lstlisting", "\\begin{lstlisting}[language=Go]\nfmt.Println(\"%d\") // 100%\n\\end{lstlisting}", "\\begin{lstlisting}[language=Go]\nfmt.Println(\"%d\") // 100%\n\\end{lstlisting}"},
+		{"minted", "\\begin{minted}{python}\nprint('%s' % x)\n\\end{minted}%", "\\begin{minted}{python}\nprint('%s' % x)\n\\end{minted}"},
+		{"verb", `\verb|%d| and \verb*+%+ % gone`, `\verb|%d| and \verb*+%+ `},
+		{"unterminated verbatim", "\\begin{verbatim}\n% kept", "\\begin{verbatim}\n% kept"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := stripComments(tt.in); got != tt.want {
+				t.Errorf("stripComments(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+			if got := maskComments(tt.in); len(got) != len(tt.in) {
+				t.Errorf("maskComments(%q) changed the length: %q", tt.in, got)
+			}
+		})
+	}
+}
+
+func TestParseDeckKeepsPercentInCode(t *testing.T) {
+	source := `\documentclass{beamer}
+\begin{document}
+\begin{frame}[fragile]{Code} % comment
+\begin{lstlisting}
+fmt.Println("%d")
+\end{lstlisting}
+Done 100\% % trailing
+\end{frame}
+\end{document}
+`
+	frames := ParseDeck(source).Frames()
+	if len(frames) != 1 {
+		t.Fatalf("got %d frames, want 1", len(frames))
+	}
+	frame := frames[0]
+	if len(frame.Blocks) != 2 || frame.Blocks[0].Kind != BlockCode {
+		t.Fatalf("unexpected blocks: %+v", frame.Blocks)
+	}
+	if got, want := frame.Blocks[0].Text, `fmt.Println("%d")`; got != want {
+		t.Errorf("code = %q, want %q", got, want)
+	}
+	if got, want := frame.Blocks[1].Text, `Done 100\%`; got != want {
+		t.Errorf("paragraph = %q, want %q", got, want)
+	}
+	if !strings.HasPrefix(frame.Source, `\begin{frame}[fragile]{Code} % comment`) || !strings.HasSuffix(frame.Source, `\end{frame}`) {
+		t.Errorf("Source is not the original text: %q", frame.Source)
+	}
+}
+
+func TestReplaceFrame(t *testing.T) {
+	source := "\\begin{document}\n% \\begin{frame}\n\\begin{frame}{A}\nx\n\\end{frame}\n\\begin{frame}{B}\ny\n\\end{frame}\n\\end{document}"
+	got, ok := ReplaceFrame(source, 1, "\\begin{frame}{C}\nz\n\\end{frame}\n")
+	if !ok {
+		t.Fatal("ReplaceFrame failed")
+	}
+	want := "\\begin{document}\n% \\begin{frame}\n\\begin{frame}{A}\nx\n\\end{frame}\n\\begin{frame}{C}\nz\n\\end{frame}\n\\end{document}"
+	if got != want {
+		t.Errorf("ReplaceFrame = %q, want %q", got, want)
+	}
+	if _, ok := ReplaceFrame(source, 2, ""); ok {
+		t.Error("ReplaceFrame accepted an out-of-range index")
+	}
+}
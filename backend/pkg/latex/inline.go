@@ -0,0 +1,393 @@
+package latex
+
+import (
+	"strings"
+	"time"
+)
+
+// Run 是一段样式一致的行内文本，由 ParseInline 从 LaTeX 源码解析得到
+type Run struct {
+	Text   string `json:"text"`
+	Bold   bool   `json:"bold,omitempty"`
+	Italic bool   `json:"italic,omitempty"`
+	Code   bool   `json:"code,omitempty"`
+	// Math 为 true 时 Text 是行内公式的 LaTeX 源码（不含 $ 定界符）
+	Math bool   `json:"math,omitempty"`
+	Link string `json:"link,omitempty"`
+}
+
+// lineBreak 标记 \\ 产生的显式换行，避免在折叠源码空白时丢失
+const lineBreak = "\u2028"
+
+type runStyle struct {
+	bold, italic, code bool
+	link               string
+}
+
+// 参数内容保留、只改变样式的命令
+var styleCommands = map[string]func(*runStyle){
+	"textbf":    func(s *runStyle) { s.bold = true },
+	"alert":     func(s *runStyle) { s.bold = true },
+	"structure": func(s *runStyle) { s.bold = true },
+	"textit":    func(s *runStyle) { s.italic = true },
+	"textsl":    func(s *runStyle) { s.italic = true },
+	"emph":      func(s *runStyle) { s.italic = true },
+	"texttt":    func(s *runStyle) { s.code = true },
+}
+
+// 作用到分组结束的声明式命令，如 {\bfseries text}
+var declarations = map[string]func(*runStyle){
+	"bfseries": func(s *runStyle) { s.bold = true },
+	"bf":       func(s *runStyle) { s.bold = true },
+	"itshape":  func(s *runStyle) { s.italic = true },
+	"it":       func(s *runStyle) { s.italic = true },
+	"em":       func(s *runStyle) { s.italic = true },
+	"ttfamily": func(s *runStyle) { s.code = true },
+	"tt":       func(s *runStyle) { s.code = true },
+}
+
+// 直接替换为文本的无参命令
+var textCommands = map[string]string{
+	"ldots":           "…",
+	"dots":            "…",
+	"LaTeX":           "LaTeX",
+	"TeX":             "TeX",
+	"and":             ", ",
+	"newline":         lineBreak,
+	"quad":            " ",
+	"qquad":           " ",
+	"textbackslash":   "\\",
+	"textasciicircum": "^",
+	"textasciitilde":  "~",
+}
+
+// 连同参数一起丢弃的命令（版式、交叉引用等）
+var droppedCommands = map[string]int{
+	"vspace": 1, "hspace": 1, "label": 1, "ref": 1, "eqref": 1, "footnote": 1,
+	"inst": 1, "thanks": 1, "color": 1, "setlength": 2, "index": 1,
+	"pause": 0, "centering": 0, "medskip": 0, "bigskip": 0, "smallskip": 0,
+	"noindent": 0, "par": 0, "vfill": 0, "hfill": 0, "linebreak": 0,
+	"raggedright": 0, "raggedleft": 0, "small": 0, "footnotesize": 0,
+	"scriptsize": 0, "tiny": 0, "large": 0, "Large": 0, "LARGE": 0,
+	"huge": 0, "Huge": 0, "normalsize": 0, "item": 0,
+}
+
+// ParseInline 把行内 LaTeX 解析为带样式的文本段，未知命令保留参数内容
+func ParseInline(src string) []Run {
+	p := &inlineParser{src: src}
+	p.parse(runStyle{}, false)
+	return normalizeRuns(p.runs)
+}
+
+// PlainText 返回行内 LaTeX 的纯文本，公式保留源码
+func PlainText(src string) string {
+	var b strings.Builder
+	for _, r := range ParseInline(src) {
+		b.WriteString(r.Text)
+	}
+	return b.String()
+}
+
+type inlineParser struct {
+	src  string
+	pos  int
+	runs []Run
+}
+
+func (p *inlineParser) emit(text string, style runStyle) {
+	if text == "" {
+		return
+	}
+	p.runs = append(p.runs, Run{Text: text, Bold: style.bold, Italic: style.italic, Code: style.code, Link: style.link})
+}
+
+// parse 解析到输入结束；inGroup 为 true 时在匹配的 } 处返回
+func (p *inlineParser) parse(style runStyle, inGroup bool) {
+	for p.pos < len(p.src) {
+		ch := p.src[p.pos]
+		switch {
+		case ch == '}' && inGroup:
+			p.pos++
+			return
+		case ch == '{':
+			p.pos++
+			p.parse(style, true)
+		case ch == '}':
+			p.pos++
+		case ch == '$':
+			p.parseMath("$")
+		case ch == '\\':
+			p.parseCommand(&style)
+		case ch == '~':
+			p.emit(" ", style)
+			p.pos++
+		case strings.HasPrefix(p.src[p.pos:], "---"):
+			p.emit("—", style)
+			p.pos += 3
+		case strings.HasPrefix(p.src[p.pos:], "--"):
+			p.emit("–", style)
+			p.pos += 2
+		case strings.HasPrefix(p.src[p.pos:], "``"):
+			p.emit("“", style)
+			p.pos += 2
+		case strings.HasPrefix(p.src[p.pos:], "''"):
+			p.emit("”", style)
+			p.pos += 2
+		default:
+			start := p.pos
+			for p.pos < len(p.src) && !strings.ContainsRune("{}$\\~-`'", rune(p.src[p.pos])) {
+				p.pos++
+			}
+			if p.pos == start {
+				p.pos++
+			}
+			p.emit(p.src[start:p.pos], style)
+		}
+	}
+}
+
+// parseMath 读取 $...$ 或 \(...\) 中的公式源码
+func (p *inlineParser) parseMath(open string) {
+	closeDelim := "$"
+	if open == `\(` {
+		closeDelim = `\)`
+	}
+	p.pos += len(open)
+	start := p.pos
+	for p.pos < len(p.src) && !strings.HasPrefix(p.src[p.pos:], closeDelim) {
+		if p.src[p.pos] == '\\' {
+			p.pos++
+		}
+		p.pos++
+	}
+	end := min(p.pos, len(p.src))
+	p.pos = min(p.pos+len(closeDelim), len(p.src))
+	if math := strings.TrimSpace(p.src[start:end]); math != "" {
+		p.runs = append(p.runs, Run{Text: math, Math: true})
+	}
+}
+
+func (p *inlineParser) parseCommand(style *runStyle) {
+	if strings.HasPrefix(p.src[p.pos:], `\(`) {
+		p.parseMath(`\(`)
+		return
+	}
+	p.pos++
+	if p.pos >= len(p.src) {
+		return
+	}
+
+	// 转义字符和控制符号
+	if c := p.src[p.pos]; !isLetter(c) {
+		p.pos++
+		switch c {
+		case '\\':
+			p.skipOptional()
+			p.emit(lineBreak, *style)
+		case ',', ';', ' ', ':':
+			p.emit(" ", *style)
+		case '-', '/', '!':
+		default:
+			p.emit(string(c), *style)
+		}
+		return
+	}
+
+	start := p.pos
+	for p.pos < len(p.src) && isLetter(p.src[p.pos]) {
+		p.pos++
+	}
+	name := p.src[start:p.pos]
+	if p.pos < len(p.src) && p.src[p.pos] == '*' {
+		p.pos++
+	}
+	p.skipOverlay()
+
+	if apply, ok := styleCommands[name]; ok {
+		inner := *style
+		apply(&inner)
+		p.parseArg(inner)
+		return
+	}
+	if apply, ok := declarations[name]; ok {
+		apply(style)
+		return
+	}
+	if text, ok := textCommands[name]; ok {
+		p.emit(text, *style)
+		p.skipSpaces()
+		return
+	}
+	if n, ok := droppedCommands[name]; ok {
+		p.skipOptional()
+		for i := 0; i < n; i++ {
+			p.readArg()
+		}
+		if n == 0 {
+			p.skipSpaces()
+		}
+		return
+	}
+
+	switch name {
+	case "today":
+		p.emit(time.Now().Format("January 2, 2006"), *style)
+	case "href":
+		url := p.readArg()
+		inner := *style
+		inner.link = url
+		p.parseArg(inner)
+	case "url":
+		url := p.readArg()
+		inner := *style
+		inner.link = url
+		inner.code = true
+		p.emit(url, inner)
+	case "textcolor":
+		p.readArg()
+		p.parseArg(*style)
+	case "multicolumn":
+		p.readArg()
+		p.readArg()
+		p.parseArg(*style)
+	case "cite":
+		p.skipOptional()
+		p.emit("["+p.readArg()+"]", *style)
+	default:
+		// 未知命令：跳过可选参数，保留第一个必选参数的内容
+		p.skipOptional()
+		p.parseArg(*style)
+	}
+}
+
+// parseArg 以给定样式解析紧随其后的 {...} 参数，没有参数时什么也不做
+func (p *inlineParser) parseArg(style runStyle) {
+	p.skipSpaces()
+	if p.pos < len(p.src) && p.src[p.pos] == '{' {
+		p.pos++
+		p.parse(style, true)
+	}
+}
+
+// readArg 返回紧随其后的 {...} 参数的原始内容
+func (p *inlineParser) readArg() string {
+	p.skipSpaces()
+	arg, next, ok := readGroup(p.src, p.pos)
+	if !ok {
+		return ""
+	}
+	p.pos = next
+	return arg
+}
+
+func (p *inlineParser) skipOptional() {
+	if _, next, ok := readBracket(p.src, p.pos, '[', ']'); ok {
+		p.pos = next
+	}
+}
+
+func (p *inlineParser) skipOverlay() {
+	if _, next, ok := readBracket(p.src, p.pos, '<', '>'); ok {
+		p.pos = next
+	}
+}
+
+func (p *inlineParser) skipSpaces() {
+	for p.pos < len(p.src) && (p.src[p.pos] == ' ' || p.src[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+// normalizeRuns 折叠空白并合并样式相同的相邻文本段
+func normalizeRuns(runs []Run) []Run {
+	var out []Run
+	for _, r := range runs {
+		if !r.Math {
+			r.Text = collapseSpace(r.Text)
+		}
+		if n := len(out); n > 0 && !r.Math && !out[n-1].Math && sameStyle(out[n-1], r) {
+			out[n-1].Text = collapseSpace(out[n-1].Text + r.Text)
+			continue
+		}
+		out = append(out, r)
+	}
+
+	// 去掉首尾空白，删除由此变空的文本段
+	if n := len(out); n > 0 {
+		if !out[0].Math {
+			out[0].Text = strings.TrimLeft(out[0].Text, " "+lineBreak)
+		}
+		if !out[n-1].Math {
+			out[n-1].Text = strings.TrimRight(out[n-1].Text, " "+lineBreak)
+		}
+	}
+	kept := out[:0]
+	for _, r := range out {
+		if !r.Math {
+			r.Text = strings.ReplaceAll(r.Text, " "+lineBreak, lineBreak)
+			r.Text = strings.ReplaceAll(r.Text, lineBreak+" ", lineBreak)
+			r.Text = strings.ReplaceAll(r.Text, lineBreak, "\n")
+		}
+		if r.Text != "" {
+			kept = append(kept, r)
+		}
+	}
+	return kept
+}
+
+func sameStyle(a, b Run) bool {
+	return a.Bold == b.Bold && a.Italic == b.Italic && a.Code == b.Code && a.Link == b.Link
+}
+
+// collapseSpace 把源码中的换行和连续空白折叠为单个空格
+func collapseSpace(s string) string {
+	var b strings.Builder
+	space := false
+	for _, r := range s {
+		switch r {
+		case ' ', '\t', '\r', '\n':
+			space = true
+			continue
+		}
+		if space {
+			b.WriteByte(' ')
+			space = false
+		}
+		b.WriteRune(r)
+	}
+	if space {
+		b.WriteByte(' ')
+	}
+	return b.String()
+}
+
+func isLetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// readGroup 读取 s[i] 处的 {...}，返回内容和 } 之后的位置
+func readGroup(s string, i int) (string, int, bool) {
+	return readBracket(s, i, '{', '}')
+}
+
+// readBracket 读取 s[i] 处以 open 开始、匹配 close 结束的内容，处理嵌套和转义
+func readBracket(s string, i int, open, close byte) (string, int, bool) {
+	if i >= len(s) || s[i] != open {
+		return "", i, false
+	}
+	depth := 0
+	for j := i; j < len(s); j++ {
+		switch s[j] {
+		case '\\':
+			j++
+		case open:
+			depth++
+		case close:
+			depth--
+			if depth == 0 {
+				return s[i+1 : j], j + 1, true
+			}
+		}
+	}
+	return "", i, false
+}
@@ -0,0 +1,681 @@
+package latex
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Markdown 幻灯片格式（Marp / Pandoc 风格）：
+//
+//	---
+//	title: 标题
+//	author: 作者
+//	---
+//
+//	# 章节
+//
+//	## Frame 标题
+//	### Frame 副标题
+//
+//	- 列表项
+//
+//	---
+//
+// 幻灯片之间用单独一行的 --- 分隔，开头可选的 front matter 提供标题等元信息。
+// 文档中出现 ## 标题时 # 表示章节、## 表示 frame 标题；否则 # 表示 frame 标题。
+// ```latex 代码块中的内容按原样插入，用于保留无法用 Markdown 表达的 frame；
+// <!-- --> 注释作为演讲者备注（\note）
+
+// ToMarkdown 把解析后的 beamer 文档转换为 Markdown 幻灯片。
+// 标题页和只有目录的 frame 由模板提供，不会输出；无法转换的 frame 以 ```latex 代码块原样保留
+func ToMarkdown(deck *Deck) string {
+	var b strings.Builder
+
+	b.WriteString("---\n")
+	for _, field := range []struct{ key, value string }{
+		{"title", deck.Title}, {"subtitle", deck.Subtitle}, {"author", deck.Author}, {"institute", deck.Institute},
+	} {
+		if text := strings.ReplaceAll(PlainText(field.value), "\n", " "); text != "" {
+			fmt.Fprintf(&b, "%s: %s\n", field.key, text)
+		}
+	}
+	b.WriteString("---\n")
+
+	section := ""
+	for _, frame := range deck.Frames() {
+		if frame.TitlePage || isTOCFrame(frame) {
+			continue
+		}
+
+		b.WriteString("\n")
+		if frame.Section != "" && frame.Section != section {
+			fmt.Fprintf(&b, "# %s\n\n", inlineMarkdown(frame.Section, false))
+		}
+		section = frame.Section
+
+		if len(frame.Unsupported) > 0 {
+			fmt.Fprintf(&b, "```latex\n%s\n```\n", strings.TrimSpace(frame.Source))
+		} else {
+			if frame.Title != "" {
+				fmt.Fprintf(&b, "## %s\n", inlineMarkdown(frame.Title, false))
+				if frame.Subtitle != "" {
+					fmt.Fprintf(&b, "### %s\n", inlineMarkdown(frame.Subtitle, false))
+				}
+				b.WriteString("\n")
+			}
+			writeMarkdownBlocks(&b, frame.Blocks, "")
+			for _, note := range frame.Notes {
+				fmt.Fprintf(&b, "\n<!-- %s -->\n", strings.ReplaceAll(PlainText(note), "--", "- -"))
+			}
+		}
+		b.WriteString("\n---\n")
+	}
+	return strings.TrimRight(strings.TrimSuffix(b.String(), "\n---\n"), "\n") + "\n"
+}
+
+func isTOCFrame(frame Frame) bool {
+	return len(frame.Blocks) == 0 && len(frame.Unsupported) == 1 && frame.Unsupported[0] == "tableofcontents"
+}
+
+// writeMarkdownBlocks 输出内容块，prefix 用于 block 引用和列表缩进
+func writeMarkdownBlocks(b *strings.Builder, blocks []Block, prefix string) {
+	for i, block := range blocks {
+		if i > 0 {
+			b.WriteString(strings.TrimRight(prefix, " ") + "\n")
+		}
+		switch block.Kind {
+		case BlockParagraph:
+			for _, line := range strings.Split(inlineMarkdown(block.Text, false), "\n") {
+				b.WriteString(prefix + line + "\n")
+			}
+		case BlockList:
+			writeMarkdownList(b, block, prefix)
+		case BlockBox:
+			fmt.Fprintf(b, "%s> [!%s] %s\n", prefix, block.Style, inlineMarkdown(block.Text, true))
+			writeMarkdownBlocks(b, block.Children, prefix+"> ")
+		case BlockMath:
+			fmt.Fprintf(b, "%s$$\n", prefix)
+			for _, line := range strings.Split(block.DisplayMath(), "\n") {
+				b.WriteString(prefix + line + "\n")
+			}
+			fmt.Fprintf(b, "%s$$\n", prefix)
+		case BlockCode:
+			fmt.Fprintf(b, "%s```\n", prefix)
+			for _, line := range strings.Split(block.Text, "\n") {
+				b.WriteString(prefix + line + "\n")
+			}
+			fmt.Fprintf(b, "%s```\n", prefix)
+		case BlockImage:
+			fmt.Fprintf(b, "%s![%s](%s)\n", prefix, inlineMarkdown(block.Caption, true), block.Path)
+		case BlockTable:
+			writeMarkdownTable(b, block.Rows, prefix)
+		case BlockColumns:
+			// Markdown 没有分栏，各栏依次输出
+			var blocks []Block
+			for _, col := range block.Columns {
+				blocks = append(blocks, col.Blocks...)
+			}
+			writeMarkdownBlocks(b, blocks, prefix)
+		}
+	}
+}
+
+func writeMarkdownList(b *strings.Builder, list Block, prefix string) {
+	for i, item := range list.Items {
+		marker := "- "
+		if list.Ordered {
+			marker = fmt.Sprintf("%d. ", i+1)
+		}
+		text := inlineMarkdown(item.Text, true)
+		if item.Label != "" {
+			text = "**" + inlineMarkdown(item.Label, true) + "** " + text
+		}
+		b.WriteString(prefix + marker + text + "\n")
+
+		indent := prefix + strings.Repeat(" ", len(marker))
+		for _, child := range item.Children {
+			if child.Kind == BlockList {
+				writeMarkdownList(b, child, indent)
+			} else {
+				writeMarkdownBlocks(b, []Block{child}, indent)
+			}
+		}
+	}
+}
+
+func writeMarkdownTable(b *strings.Builder, rows [][]string, prefix string) {
+	cols := 0
+	for _, row := range rows {
+		cols = max(cols, len(row))
+	}
+	for i, row := range rows {
+		cells := make([]string, cols)
+		for j := range cells {
+			if j < len(row) {
+				cells[j] = strings.ReplaceAll(inlineMarkdown(row[j], true), "|", `\|`)
+			}
+		}
+		b.WriteString(prefix + "| " + strings.Join(cells, " | ") + " |\n")
+		if i == 0 {
+			b.WriteString(prefix + "|" + strings.Repeat(" --- |", cols) + "\n")
+		}
+	}
+}
+
+var markdownEscaper = strings.NewReplacer(`\`, `\\`, `*`, `\*`, `_`, `\_`, "`", "\\`", `[`, `\[`, `]`, `\]`, `$`, `\$`)
+
+// inlineMarkdown 把行内 LaTeX 转换为 Markdown，singleLine 为 true 时换行替换为空格
+func inlineMarkdown(src string, singleLine bool) string {
+	var b strings.Builder
+	for _, r := range ParseInline(src) {
+		if r.Math {
+			b.WriteString("$" + r.Text + "$")
+			continue
+		}
+		text := r.Text
+		if r.Code {
+			text = "`" + text + "`"
+		} else {
+			text = markdownEscaper.Replace(text)
+		}
+		if r.Italic {
+			text = "*" + text + "*"
+		}
+		if r.Bold {
+			text = "**" + text + "**"
+		}
+		if r.Link != "" {
+			text = "[" + text + "](" + r.Link + ")"
+		}
+		b.WriteString(text)
+	}
+	if singleLine {
+		return strings.ReplaceAll(b.String(), "\n", " ")
+	}
+	return strings.ReplaceAll(b.String(), "\n", "  \n")
+}
+
+var (
+	frontMatterLine = regexp.MustCompile(`^([A-Za-z_]+)\s*:\s*(.*)$`)
+	headingLine     = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+	listItemLine    = regexp.MustCompile(`^(\s*)([-*+]|\d+[.)])\s+(.*)$`)
+	imageLine       = regexp.MustCompile(`^!\[([^\]]*)\]\(([^)\s]+)(?:\s+"[^"]*")?\)$`)
+	tableSeparator  = regexp.MustCompile(`^\|?\s*:?-+:?\s*(\|\s*:?-+:?\s*)*\|?$`)
+	calloutLine     = regexp.MustCompile(`^\[!(\w+)\]\s*(.*)$`)
+	boldTitleLine   = regexp.MustCompile(`^\*\*(.+)\*\*$`)
+	htmlComment     = regexp.MustCompile(`(?s)<!--(.*?)-->`)
+)
+
+// ParseMarkdown 把 Markdown 幻灯片转换为模板数据：front matter 作为元信息，幻灯片转换为 frame 正文
+func ParseMarkdown(md string) TemplateData {
+	md = strings.ReplaceAll(md, "\r\n", "\n")
+	lines := strings.Split(md, "\n")
+
+	var data TemplateData
+	if len(lines) > 0 && strings.TrimSpace(lines[0]) == "---" {
+		for i := 1; i < len(lines); i++ {
+			if strings.TrimSpace(lines[i]) == "---" {
+				for _, line := range lines[1:i] {
+					if m := frontMatterLine.FindStringSubmatch(strings.TrimSpace(line)); m != nil {
+						setFrontMatter(&data, strings.ToLower(m[1]), strings.Trim(m[2], `"' `))
+					}
+				}
+				lines = lines[i+1:]
+				break
+			}
+		}
+	}
+
+	slides := splitSlides(lines)
+	sectionLevel := 0
+	for _, slide := range slides {
+		for _, line := range slide {
+			if strings.HasPrefix(line, "## ") {
+				sectionLevel = 1
+			}
+		}
+	}
+
+	var frames []string
+	for _, slide := range slides {
+		if frame := markdownSlide(slide, sectionLevel); frame != "" {
+			frames = append(frames, frame)
+		}
+	}
+	data.Content = strings.Join(frames, "\n\n")
+	return data
+}
+
+func setFrontMatter(data *TemplateData, key, value string) {
+	switch key {
+	case "title":
+		data.Title = value
+	case "subtitle":
+		data.Subtitle = value
+	case "author":
+		data.Author = value
+	case "institute", "institution":
+		data.Institute = value
+	}
+}
+
+// splitSlides 按 --- 拆分幻灯片，忽略代码块中的 ---
+func splitSlides(lines []string) [][]string {
+	var slides [][]string
+	var current []string
+	fenced := false
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") {
+			fenced = !fenced
+		}
+		if !fenced && trimmed == "---" {
+			slides = append(slides, current)
+			current = nil
+			continue
+		}
+		current = append(current, line)
+	}
+	return append(slides, current)
+}
+
+// markdownSlide 把一张幻灯片转换为 \section 和 frame；sectionLevel 为 1 时 # 表示章节
+func markdownSlide(lines []string, sectionLevel int) string {
+	content := strings.Join(lines, "\n")
+	var notes []string
+	for _, m := range htmlComment.FindAllStringSubmatch(content, -1) {
+		if note := strings.TrimSpace(m[1]); note != "" {
+			notes = append(notes, note)
+		}
+	}
+	content = htmlComment.ReplaceAllString(content, "")
+	lines = strings.Split(content, "\n")
+
+	var section, title, subtitle string
+	var body []string
+	for _, line := range lines {
+		m := headingLine.FindStringSubmatch(line)
+		if m == nil || len(body) > 0 && strings.TrimSpace(strings.Join(body, "")) != "" {
+			body = append(body, line)
+			continue
+		}
+		level := len(m[1]) - sectionLevel
+		switch {
+		case level == 0:
+			section = m[2]
+		case level == 1 && title == "":
+			title = m[2]
+		case level == 2 && subtitle == "" && title != "":
+			subtitle = m[2]
+		default:
+			body = append(body, line)
+		}
+	}
+
+	var b strings.Builder
+	if section != "" {
+		fmt.Fprintf(&b, "\\section{%s}\n\n", markdownInline(section))
+	}
+
+	// 只有 ```latex 代码块且其中是完整 frame 时原样插入
+	if raw, ok := rawFrame(body); ok {
+		b.WriteString(raw)
+		return strings.TrimSpace(b.String())
+	}
+
+	fragile := false
+	inner := markdownBlocks(body, &fragile)
+	for _, note := range notes {
+		inner += fmt.Sprintf("  \\note{%s}\n", markdownInline(note))
+	}
+	if title == "" && strings.TrimSpace(inner) == "" {
+		return strings.TrimSpace(b.String())
+	}
+
+	b.WriteString(`\begin{frame}`)
+	if fragile {
+		b.WriteString("[fragile]")
+	}
+	if title != "" {
+		fmt.Fprintf(&b, "{%s}", markdownInline(title))
+		if subtitle != "" {
+			fmt.Fprintf(&b, "{%s}", markdownInline(subtitle))
+		}
+	}
+	b.WriteString("\n" + inner + `\end{frame}`)
+	return strings.TrimSpace(b.String())
+}
+
+func rawFrame(body []string) (string, bool) {
+	text := strings.TrimSpace(strings.Join(body, "\n"))
+	for _, fence := range []string{"```latex\n", "```tex\n"} {
+		if strings.HasPrefix(text, fence) && strings.HasSuffix(text, "```") {
+			raw := strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(text, fence), "```"))
+			if strings.HasPrefix(raw, `\begin{frame}`) && !strings.Contains(raw, "\n```") {
+				return raw, true
+			}
+		}
+	}
+	return "", false
+}
+
+// markdownBlocks 把幻灯片正文转换为 LaTeX，使用 verbatim 时把 fragile 置为 true
+func markdownBlocks(lines []string, fragile *bool) string {
+	var b strings.Builder
+	var para []string
+	flush := func() {
+		if len(para) > 0 {
+			b.WriteString("  " + markdownInline(strings.Join(para, "\n")) + "\n\n")
+			para = nil
+		}
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "":
+			flush()
+
+		case strings.HasPrefix(trimmed, "```"):
+			flush()
+			lang := strings.TrimSpace(strings.TrimPrefix(trimmed, "```"))
+			var code []string
+			for i++; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), "```"); i++ {
+				code = append(code, lines[i])
+			}
+			if lang == "latex" || lang == "tex" {
+				b.WriteString(strings.Join(code, "\n") + "\n")
+			} else {
+				*fragile = true
+				b.WriteString("\\begin{verbatim}\n" + strings.Join(code, "\n") + "\n\\end{verbatim}\n")
+			}
+
+		case strings.HasPrefix(trimmed, "$$"):
+			flush()
+			math := strings.TrimPrefix(trimmed, "$$")
+			if strings.HasSuffix(math, "$$") && math != "" {
+				math = strings.TrimSuffix(math, "$$")
+			} else {
+				var parts []string
+				if math != "" {
+					parts = append(parts, math)
+				}
+				for i++; i < len(lines); i++ {
+					t := strings.TrimSpace(lines[i])
+					if strings.HasSuffix(t, "$$") {
+						if t = strings.TrimSuffix(t, "$$"); t != "" {
+							parts = append(parts, t)
+						}
+						break
+					}
+					parts = append(parts, lines[i])
+				}
+				math = strings.Join(parts, "\n")
+			}
+			b.WriteString("  \\[\n" + strings.TrimSpace(math) + "\n  \\]\n")
+
+		case listItemLine.MatchString(line):
+			flush()
+			start := i
+			for i+1 < len(lines) && (listItemLine.MatchString(lines[i+1]) ||
+				strings.TrimSpace(lines[i+1]) != "" && strings.HasPrefix(lines[i+1], " ")) {
+				i++
+			}
+			b.WriteString(markdownList(lines[start:i+1], fragile))
+
+		case strings.HasPrefix(trimmed, "|"):
+			flush()
+			start := i
+			for i+1 < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i+1]), "|") {
+				i++
+			}
+			b.WriteString(markdownTable(lines[start : i+1]))
+
+		case strings.HasPrefix(trimmed, ">"):
+			flush()
+			var quote []string
+			for ; i < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i]), ">"); i++ {
+				q := strings.TrimPrefix(strings.TrimSpace(lines[i]), ">")
+				quote = append(quote, strings.TrimPrefix(q, " "))
+			}
+			i--
+			b.WriteString(markdownBlock(quote, fragile))
+
+		case imageLine.MatchString(trimmed):
+			flush()
+			m := imageLine.FindStringSubmatch(trimmed)
+			b.WriteString("  \\begin{figure}\n    \\centering\n")
+			fmt.Fprintf(&b, "    \\includegraphics[width=0.8\\textwidth,height=0.6\\textheight,keepaspectratio]{%s}\n", m[2])
+			if m[1] != "" {
+				fmt.Fprintf(&b, "    \\caption{%s}\n", markdownInline(m[1]))
+			}
+			b.WriteString("  \\end{figure}\n")
+
+		case headingLine.MatchString(line):
+			// 正文中的其他标题按加粗段落处理
+			flush()
+			m := headingLine.FindStringSubmatch(line)
+			fmt.Fprintf(&b, "  \\textbf{%s}\n\n", markdownInline(m[2]))
+
+		default:
+			// 保留行尾两个空格表示的换行
+			para = append(para, strings.TrimLeft(line, " \t"))
+		}
+	}
+	flush()
+	return b.String()
+}
+
+type markdownItem struct {
+	indent  int
+	ordered bool
+	text    string
+}
+
+// markdownList 按缩进把列表行转换为嵌套的 itemize / enumerate
+func markdownList(lines []string, fragile *bool) string {
+	var items []markdownItem
+	for _, line := range lines {
+		if m := listItemLine.FindStringSubmatch(line); m != nil {
+			indent := len(strings.ReplaceAll(m[1], "\t", "    "))
+			ordered := m[2] != "-" && m[2] != "*" && m[2] != "+"
+			items = append(items, markdownItem{indent: indent, ordered: ordered, text: m[3]})
+		} else if n := len(items); n > 0 {
+			// 缩进的续行
+			items[n-1].text += " " + strings.TrimSpace(line)
+		}
+	}
+
+	var b strings.Builder
+	writeMarkdownItems(&b, items, "  ")
+	return b.String()
+}
+
+func writeMarkdownItems(b *strings.Builder, items []markdownItem, indent string) {
+	if len(items) == 0 {
+		return
+	}
+	env := "itemize"
+	if items[0].ordered {
+		env = "enumerate"
+	}
+	level := items[0].indent
+
+	fmt.Fprintf(b, "%s\\begin{%s}\n", indent, env)
+	for i := 0; i < len(items); {
+		fmt.Fprintf(b, "%s  \\item %s\n", indent, markdownInline(items[i].text))
+		j := i + 1
+		for j < len(items) && items[j].indent > level {
+			j++
+		}
+		writeMarkdownItems(b, items[i+1:j], indent+"  ")
+		i = j
+	}
+	fmt.Fprintf(b, "%s\\end{%s}\n", indent, env)
+}
+
+// markdownTable 把管道表格转换为 tabular，对齐方式取自分隔行
+func markdownTable(lines []string) string {
+	var rows [][]string
+	var aligns []string
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if tableSeparator.MatchString(trimmed) {
+			for _, cell := range splitTableRow(trimmed) {
+				cell = strings.TrimSpace(cell)
+				switch {
+				case strings.HasPrefix(cell, ":") && strings.HasSuffix(cell, ":"):
+					aligns = append(aligns, "c")
+				case strings.HasSuffix(cell, ":"):
+					aligns = append(aligns, "r")
+				default:
+					aligns = append(aligns, "l")
+				}
+			}
+			continue
+		}
+		rows = append(rows, splitTableRow(trimmed))
+	}
+
+	cols := len(aligns)
+	for _, row := range rows {
+		cols = max(cols, len(row))
+	}
+	for len(aligns) < cols {
+		aligns = append(aligns, "l")
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "  \\begin{center}\n  \\begin{tabular}{%s}\n    \\hline\n", strings.Join(aligns, ""))
+	for i, row := range rows {
+		cells := make([]string, cols)
+		for j := range cells {
+			if j < len(row) {
+				cells[j] = markdownInline(strings.TrimSpace(row[j]))
+			}
+		}
+		fmt.Fprintf(&b, "    %s \\\\\n", strings.Join(cells, " & "))
+		if i == 0 {
+			b.WriteString("    \\hline\n")
+		}
+	}
+	b.WriteString("    \\hline\n  \\end{tabular}\n  \\end{center}\n")
+	return b.String()
+}
+
+// splitTableRow 按未转义的 | 拆分表格行
+func splitTableRow(row string) []string {
+	row = strings.TrimSuffix(strings.TrimPrefix(row, "|"), "|")
+	var cells []string
+	var cell strings.Builder
+	for i := 0; i < len(row); i++ {
+		switch {
+		case row[i] == '\\' && i+1 < len(row) && row[i+1] == '|':
+			cell.WriteByte('|')
+			i++
+		case row[i] == '|':
+			cells = append(cells, cell.String())
+			cell.Reset()
+		default:
+			cell.WriteByte(row[i])
+		}
+	}
+	return append(cells, cell.String())
+}
+
+// markdownBlock 把引用块转换为 beamer block，首行 [!alert] / [!example] 选择样式，
+// [!type] 之后或单独加粗的首行作为标题
+func markdownBlock(lines []string, fragile *bool) string {
+	env, title := "block", ""
+	if len(lines) > 0 {
+		first := strings.TrimSpace(lines[0])
+		if m := calloutLine.FindStringSubmatch(first); m != nil {
+			switch strings.ToLower(m[1]) {
+			case "alert", "alertblock", "warning", "caution", "important":
+				env = "alertblock"
+			case "example", "exampleblock", "tip":
+				env = "exampleblock"
+			}
+			title, lines = m[2], lines[1:]
+		} else if m := boldTitleLine.FindStringSubmatch(first); m != nil {
+			title, lines = m[1], lines[1:]
+		}
+	}
+	return fmt.Sprintf("  \\begin{%s}{%s}\n%s  \\end{%s}\n", env, markdownInline(title), strings.TrimRight(markdownBlocks(lines, fragile), "\n")+"\n", env)
+}
+
+// markdownInline 把行内 Markdown 转换为 LaTeX：加粗、斜体、行内代码、链接和 $公式$，其余文本转义
+func markdownInline(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		rest := s[i:]
+		switch {
+		case rest[0] == '\\' && len(rest) > 1 && strings.ContainsRune("\\`*_{}[]()#+-.!|$<>", rune(rest[1])):
+			b.WriteString(EscapeLaTeX(rest[1:2]))
+			i += 2
+		case rest[0] == '`':
+			if end := strings.IndexByte(rest[1:], '`'); end >= 0 {
+				fmt.Fprintf(&b, `\texttt{%s}`, EscapeLaTeX(rest[1:1+end]))
+				i += end + 2
+				continue
+			}
+			b.WriteString(EscapeLaTeX("`"))
+			i++
+		case rest[0] == '$':
+			if end := strings.IndexByte(rest[1:], '$'); end > 0 {
+				b.WriteString(rest[:end+2])
+				i += end + 2
+				continue
+			}
+			b.WriteString(`\$`)
+			i++
+		case strings.HasPrefix(rest, "**") || strings.HasPrefix(rest, "__"):
+			delim := rest[:2]
+			if end := strings.Index(rest[2:], delim); end > 0 {
+				fmt.Fprintf(&b, `\textbf{%s}`, markdownInline(rest[2:2+end]))
+				i += end + 4
+				continue
+			}
+			b.WriteString(EscapeLaTeX(delim))
+			i += 2
+		case rest[0] == '*' || rest[0] == '_' && (i == 0 || !isWordByte(s[i-1])):
+			delim := rest[:1]
+			if end := strings.Index(rest[1:], delim); end > 0 && (delim == "*" || 1+end+1 >= len(rest) || !isWordByte(rest[1+end+1])) {
+				fmt.Fprintf(&b, `\emph{%s}`, markdownInline(rest[1:1+end]))
+				i += end + 2
+				continue
+			}
+			b.WriteString(EscapeLaTeX(delim))
+			i++
+		case rest[0] == '[':
+			if m := markdownLink.FindStringSubmatch(rest); m != nil {
+				fmt.Fprintf(&b, `\href{%s}{%s}`, urlEscaper.Replace(m[2]), markdownInline(m[1]))
+				i += len(m[0])
+				continue
+			}
+			b.WriteString("[")
+			i++
+		case strings.HasPrefix(rest, "<br>") || strings.HasPrefix(rest, "<br/>"):
+			b.WriteString(`\\ `)
+			i += strings.IndexByte(rest, '>') + 1
+		case strings.HasPrefix(rest, "  \n"):
+			b.WriteString("\\\\\n")
+			i += 3
+		default:
+			b.WriteString(EscapeLaTeX(rest[:1]))
+			i++
+		}
+	}
+	return b.String()
+}
+
+var markdownLink = regexp.MustCompile(`^\[([^\]]*)\]\(([^)\s]+)\)`)
+
+// \href 的 URL 参数中 % 和 # 需要转义
+var urlEscaper = strings.NewReplacer(`%`, `\%`, `#`, `\#`)
+
+func isWordByte(c byte) bool {
+	return isLetter(c) || c >= '0' && c <= '9'
+}
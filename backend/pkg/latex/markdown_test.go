@@ -0,0 +1,156 @@
+package latex
+
+import (
+	"strings"
+	"testing"
+)
+
+const markdownDeck = `\documentclass{beamer}
+\title{Tips \& Tricks}
+\author{Someone}
+\begin{document}
+\maketitle
+\begin{frame}{Outline}\tableofcontents\end{frame}
+\section{Intro}
+\begin{frame}{Basics}{Sub}
+Some \textbf{bold} and \emph{it} with $x_1$ and 5\%.
+\begin{itemize}
+\item One
+\begin{enumerate}
+\item Nested
+\end{enumerate}
+\item Two
+\end{itemize}
+\begin{alertblock}{Careful}
+Text
+\end{alertblock}
+\begin{align}
+a &= b \label{eq:a}
+\end{align}
+\note{Say hi}
+\end{frame}
+\begin{frame}[fragile]{Code}
+\begin{lstlisting}[language=Go]
+fmt.Println("%d")
+\end{lstlisting}
+\begin{tabular}{ll}
+A & B \\
+1 & 2
+\end{tabular}
+\includegraphics{fig.png}
+\end{frame}
+\begin{frame}{Draw}
+\begin{tikzpicture}\end{tikzpicture}
+\end{frame}
+\end{document}
+`
+
+const markdownExpected = "---\ntitle: Tips & Tricks\nauthor: Someone\n---\n\n" +
+	"# Intro\n\n## Basics\n### Sub\n\n" +
+	"Some **bold** and *it* with $x_1$ and 5%.\n\n" +
+	"- One\n  1. Nested\n- Two\n\n" +
+	"> [!alertblock] Careful\n> Text\n\n" +
+	"$$\n\\begin{aligned}\na &= b\n\\end{aligned}\n$$\n\n" +
+	"<!-- Say hi -->\n\n---\n\n" +
+	"## Code\n\n```\nfmt.Println(\"%d\")\n```\n\n| A | B |\n| --- | --- |\n| 1 | 2 |\n\n![](fig.png)\n\n---\n\n" +
+	"```latex\n\\begin{frame}{Draw}\n\\begin{tikzpicture}\\end{tikzpicture}\n\\end{frame}\n```\n"
+
+func TestToMarkdown(t *testing.T) {
+	if got := ToMarkdown(ParseDeck(markdownDeck)); got != markdownExpected {
+		t.Errorf("ToMarkdown() =\n%s\nwant\n%s", got, markdownExpected)
+	}
+}
+
+func TestParseMarkdown(t *testing.T) {
+	tests := []struct {
+		name    string
+		md      string
+		title   string
+		want    []string
+		notWant []string
+	}{
+		{
+			name:  "front matter and headings",
+			md:    "---\ntitle: \"Deck\"\ninstitution: Lab\n---\n\n# Part\n\n## Slide\n### Detail\n\nText with **bold**, `code` and 50% off.",
+			title: "Deck",
+			want:  []string{`\section{Part}`, `\begin{frame}{Slide}{Detail}`, `Text with \textbf{bold}, \texttt{code} and 50\% off.`},
+		},
+		{
+			name:    "single heading level",
+			md:      "# One\n\nA\n\n---\n\n# Two\n\nB",
+			want:    []string{`\begin{frame}{One}`, `\begin{frame}{Two}`},
+			notWant: []string{`\section`},
+		},
+		{
+			name: "code keeps separators",
+			md:   "# Code\n\n```go\na := 1\n---\n```",
+			want: []string{`\begin{frame}[fragile]{Code}`, "\\begin{verbatim}\na := 1\n---\n\\end{verbatim}"},
+		},
+		{
+			name: "raw latex frame",
+			md:   "```latex\n\\begin{frame}{Raw}\n\\begin{tikzpicture}\\end{tikzpicture}\n\\end{frame}\n```",
+			want: []string{"\\begin{frame}{Raw}\n\\begin{tikzpicture}\\end{tikzpicture}\n\\end{frame}"},
+		},
+		{
+			name: "lists tables and callouts",
+			md:   "# S\n\n1. First\n   - Sub\n2. Second\n\n| a | b |\n|:-:|--:|\n| 1 | 2 |\n\n> [!warning] Watch\n> Out",
+			want: []string{`\begin{enumerate}`, `\item Sub`, `\begin{tabular}{cr}`, `1 & 2 \\`, `\begin{alertblock}{Watch}`},
+		},
+		{
+			name: "images math and notes",
+			md:   "# S\n\n![Cap](img/a.png)\n\n$$\nx^2\n$$\n\n<!-- remember -->",
+			want: []string{`\includegraphics[width=0.8\textwidth,height=0.6\textheight,keepaspectratio]{img/a.png}`, `\caption{Cap}`, "\\[\nx^2\n  \\]", `\note{remember}`},
+		},
+		{
+			name:    "empty slides are dropped",
+			md:      "# A\n\nx\n\n---\n\n---\n",
+			want:    []string{`\begin{frame}{A}`},
+			notWant: []string{`\begin{frame}` + "\n"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := ParseMarkdown(tt.md)
+			if data.Title != tt.title {
+				t.Errorf("Title = %q, want %q", data.Title, tt.title)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(data.Content, want) {
+					t.Errorf("Content is missing %q:\n%s", want, data.Content)
+				}
+			}
+			for _, unwanted := range tt.notWant {
+				if strings.Contains(data.Content, unwanted) {
+					t.Errorf("Content contains %q:\n%s", unwanted, data.Content)
+				}
+			}
+		})
+	}
+}
+
+func TestMarkdownRoundTrip(t *testing.T) {
+	deck := ParseDeck(markdownDeck)
+	data := ParseMarkdown(ToMarkdown(deck))
+	again := ParseDeck("\\begin{document}\n" + data.Content + "\n\\end{document}")
+
+	var want, got []string
+	for _, frame := range deck.Frames() {
+		if !frame.TitlePage && !isTOCFrame(frame) {
+			want = append(want, frame.Section+"/"+frame.Title+"/"+blockKinds(frame.Blocks))
+		}
+	}
+	for _, frame := range again.Frames() {
+		got = append(got, frame.Section+"/"+frame.Title+"/"+blockKinds(frame.Blocks))
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("round trip changed the frames:\ngot  %q\nwant %q", got, want)
+	}
+}
+
+func blockKinds(blocks []Block) string {
+	var kinds []string
+	for _, block := range blocks {
+		kinds = append(kinds, string(block.Kind))
+	}
+	return strings.Join(kinds, ",")
+}
@@ -0,0 +1,72 @@
+package latex
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// maxEnginePasses 限制 TeX 引擎的运行次数，防止交叉引用不收敛时无限重跑
+const maxEnginePasses = 5
+
+// Pass 记录编译流程中的一步（TeX 引擎、bibtex 或 biber）
+type Pass struct {
+	Program    string `json:"program"`
+	Reason     string `json:"reason"`
+	DurationMs int64  `json:"duration_ms"`
+	Error      string `json:"error,omitempty"`
+}
+
+// auxFiles 是引擎下一遍会读取的辅助文件，内容变化意味着需要重新编译
+var auxFiles = []string{"main.aux", "main.toc", "main.nav", "main.snm", "main.out", "main.lof", "main.lot", "main.bbl"}
+
+// auxSnapshot 记录辅助文件的内容哈希，不存在的文件视为空
+type auxSnapshot map[string][sha256.Size]byte
+
+func snapshotAux(dir string) auxSnapshot {
+	snap := make(auxSnapshot, len(auxFiles))
+	for _, name := range auxFiles {
+		data, _ := os.ReadFile(filepath.Join(dir, name))
+		snap[name] = sha256.Sum256(data)
+	}
+	return snap
+}
+
+// rerunReason 按 latexmk 的规则判断是否需要再运行一遍引擎，返回原因，无需重跑时返回空串：
+// 日志中出现 rerun 提示，或本遍运行改变了下一遍会读取的辅助文件
+func rerunReason(dir string, before auxSnapshot) string {
+	for _, d := range readDiagnostics(dir, nil) {
+		if d.Kind == "rerun" {
+			return "rerun requested: " + d.Message
+		}
+	}
+
+	after := snapshotAux(dir)
+	for _, name := range auxFiles {
+		if before[name] != after[name] {
+			return name + " changed"
+		}
+	}
+	return ""
+}
+
+// bibliographyStep 返回需要运行的参考文献工具及原因，文档不使用参考文献时返回空串。
+// biblatex 生成 main.bcf 并使用 biber，传统 \bibliography 在 main.aux 中写入 \bibdata 并使用 bibtex
+func bibliographyStep(dir string) (program, reason string) {
+	if _, err := os.Stat(filepath.Join(dir, "main.bcf")); err == nil {
+		return "biber", "biblatex control file main.bcf"
+	}
+	aux, err := os.ReadFile(filepath.Join(dir, "main.aux"))
+	if err == nil && bytes.Contains(aux, []byte(`\bibdata{`)) {
+		return "bibtex", `\bibdata in main.aux`
+	}
+	return "", ""
+}
+
+// needsRerunHint 判断警告信息是否要求重新编译
+func needsRerunHint(message string) bool {
+	lower := strings.ToLower(message)
+	return strings.Contains(lower, "rerun") || strings.Contains(lower, "may have changed")
+}
@@ -0,0 +1,86 @@
+package latex
+
+import "testing"
+
+func TestRerunReason(t *testing.T) {
+	tests := []struct {
+		name   string
+		before map[string]string
+		after  map[string]string
+		want   string
+	}{
+		{
+			name:   "nothing changed",
+			before: map[string]string{"main.aux": `\relax`, "main.log": "Output written on main.pdf"},
+			want:   "",
+		},
+		{
+			name:   "aux changed",
+			before: map[string]string{"main.aux": `\relax`},
+			after:  map[string]string{"main.aux": `\relax \newlabel{a}{{1}{1}}`},
+			want:   "main.aux changed",
+		},
+		{
+			name:  "toc created",
+			after: map[string]string{"main.toc": `\beamer@sectionintoc {1}{Intro}{2}{0}{1}`},
+			want:  "main.toc changed",
+		},
+		{
+			name:   "rerun warning",
+			before: map[string]string{"main.log": "LaTeX Warning: Label(s) may have changed. Rerun to get cross-references right.\n"},
+			want:   "rerun requested: Label(s) may have changed. Rerun to get cross-references right.",
+		},
+		{
+			name:  "unrelated file",
+			after: map[string]string{"main.pdf": "%PDF"},
+			want:  "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeTestFiles(t, dir, tt.before)
+			snap := snapshotAux(dir)
+			writeTestFiles(t, dir, tt.after)
+			if got := rerunReason(dir, snap); got != tt.want {
+				t.Errorf("rerunReason() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBibliographyStep(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   map[string]string
+		program string
+	}{
+		{"none", map[string]string{"main.aux": `\relax`}, ""},
+		{"bibtex", map[string]string{"main.aux": `\bibstyle{plain} \bibdata{refs}`}, "bibtex"},
+		{"biber", map[string]string{"main.aux": `\bibdata{refs}`, "main.bcf": "<bcf/>"}, "biber"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeTestFiles(t, dir, tt.files)
+			program, reason := bibliographyStep(dir)
+			if program != tt.program {
+				t.Errorf("bibliographyStep() = %q, want %q", program, tt.program)
+			}
+			if (reason == "") != (program == "") {
+				t.Errorf("reason %q does not match program %q", reason, program)
+			}
+		})
+	}
+}
+
+func writeTestFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	data := make(map[string][]byte, len(files))
+	for name, content := range files {
+		data[name] = []byte(content)
+	}
+	if err := writeFiles(dir, data); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,150 @@
+package latex
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/png"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var ErrNoRasterizer = errors.New("no PDF rasterizer installed (pdftoppm or mutool)")
+
+// Rasterizer 使用 poppler 的 pdftoppm 或 MuPDF 的 mutool 把 PDF 页面渲染为 PNG
+type Rasterizer struct {
+	tool   string
+	limits Limits
+}
+
+// NewRasterizer 检测 PATH 中可用的工具，优先使用 pdftoppm
+func NewRasterizer(limits Limits) *Rasterizer {
+	r := &Rasterizer{limits: limits}
+	for _, tool := range []string{"pdftoppm", "mutool"} {
+		if _, err := exec.LookPath(tool); err == nil {
+			r.tool = tool
+			break
+		}
+	}
+	return r
+}
+
+// Tool 返回使用的工具名称，没有可用工具时返回空串
+func (r *Rasterizer) Tool() string {
+	return r.tool
+}
+
+// RenderPages 把 PDF 的全部页面渲染为 dir 下的 page-<n>.png，返回按页码排序的文件路径
+func (r *Rasterizer) RenderPages(ctx context.Context, pdfPath, dir string, dpi int) ([]string, error) {
+	if r.tool == "" {
+		return nil, ErrNoRasterizer
+	}
+	if r.limits.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.limits.Timeout)
+		defer cancel()
+	}
+
+	absPDF, err := filepath.Abs(pdfPath)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	if dir, err = filepath.Abs(dir); err != nil {
+		return nil, err
+	}
+
+	var args []string
+	switch r.tool {
+	case "pdftoppm":
+		// 输出 page-1.png 或 page-01.png，位数取决于总页数
+		args = []string{"-png", "-r", strconv.Itoa(dpi), absPDF, "page"}
+	case "mutool":
+		args = []string{"draw", "-q", "-r", strconv.Itoa(dpi), "-o", "page-%d.png", absPDF}
+	}
+	if output, err := runSandboxed(ctx, dir, r.limits, r.tool, args...); err != nil {
+		return nil, fmt.Errorf("%s failed: %w: %s", r.tool, err, strings.TrimSpace(string(output)))
+	}
+
+	return RenderedPages(dir)
+}
+
+// RenderedPages 收集 dir 下的 page-<n>.png 并按页码排序
+func RenderedPages(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	type page struct {
+		n    int
+		path string
+	}
+	var pages []page
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasPrefix(name, "page-") || !strings.HasSuffix(name, ".png") {
+			continue
+		}
+		n, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(name, "page-"), ".png"))
+		if err != nil {
+			continue
+		}
+		pages = append(pages, page{n: n, path: filepath.Join(dir, name)})
+	}
+	if len(pages) == 0 {
+		return nil, fmt.Errorf("no pages rendered")
+	}
+	sort.Slice(pages, func(i, j int) bool { return pages[i].n < pages[j].n })
+
+	paths := make([]string, len(pages))
+	for i, p := range pages {
+		paths[i] = p.path
+	}
+	return paths, nil
+}
+
+// ComposePNG 把多张 PNG 横向拼接为一张，保存到 out
+func ComposePNG(paths []string, out string) error {
+	var images []image.Image
+	width, height := 0, 0
+	for _, path := range paths {
+		img, err := decodePNG(path)
+		if err != nil {
+			return err
+		}
+		images = append(images, img)
+		width += img.Bounds().Dx()
+		height = max(height, img.Bounds().Dy())
+	}
+
+	canvas := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(canvas, canvas.Bounds(), image.White, image.Point{}, draw.Src)
+	x := 0
+	for _, img := range images {
+		b := img.Bounds()
+		draw.Draw(canvas, image.Rect(x, 0, x+b.Dx(), b.Dy()), img, b.Min, draw.Src)
+		x += b.Dx()
+	}
+
+	return writeAtomic(out, func(f *os.File) error {
+		return png.Encode(f, canvas)
+	})
+}
+
+func decodePNG(path string) (image.Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return png.Decode(f)
+}
@@ -0,0 +1,276 @@
+package latex
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// RenderDeck 把结构化文档重新生成为完整的 beamer 源码。
+// 导言区沿用 Deck.Preamble，其中的 \title 等命令按 Deck 的元信息更新；
+// 含有无法解析内容或内容没有改动的 frame 原样输出 Source，其余 frame 由内容块生成
+func RenderDeck(deck *Deck) string {
+	var b strings.Builder
+	b.WriteString(renderPreamble(deck))
+	b.WriteString("\\begin{document}\n")
+
+	for _, section := range deck.Sections {
+		if section.Title != "" {
+			fmt.Fprintf(&b, "\n\\section{%s}\n", section.Title)
+		}
+		subsection := ""
+		for _, frame := range section.Frames {
+			if frame.Subsection != "" && frame.Subsection != subsection {
+				fmt.Fprintf(&b, "\n\\subsection{%s}\n", frame.Subsection)
+			}
+			subsection = frame.Subsection
+			b.WriteString("\n" + RenderFrame(frame) + "\n")
+		}
+	}
+
+	b.WriteString("\n\\end{document}\n")
+	return b.String()
+}
+
+// RenderFrame 生成一个 frame 的源码
+func RenderFrame(frame Frame) string {
+	if source := strings.TrimSpace(frame.Source); source != "" && (len(frame.Unsupported) > 0 || unchanged(frame)) {
+		return source
+	}
+
+	var b strings.Builder
+	b.WriteString(`\begin{frame}`)
+	options := frame.Options
+	if hasCode(frame.Blocks) && !strings.Contains(options, "fragile") {
+		options = strings.TrimPrefix(options+",fragile", ",")
+	}
+	if options != "" {
+		fmt.Fprintf(&b, "[%s]", options)
+	}
+	if frame.Title != "" || frame.Subtitle != "" {
+		fmt.Fprintf(&b, "{%s}", frame.Title)
+		if frame.Subtitle != "" {
+			fmt.Fprintf(&b, "{%s}", frame.Subtitle)
+		}
+	}
+	b.WriteString("\n")
+
+	if frame.TitlePage {
+		b.WriteString("  \\titlepage\n")
+	}
+	renderBlocks(&b, frame.Blocks, "  ")
+	for _, note := range frame.Notes {
+		fmt.Fprintf(&b, "  \\note{%s}\n", note)
+	}
+	b.WriteString(`\end{frame}`)
+	return b.String()
+}
+
+// unchanged 判断 frame 与解析 Source 得到的结果是否相同，
+// 相同时原样输出 Source，保留注释和内容块无法表示的细节
+func unchanged(frame Frame) bool {
+	source := maskComments(strings.TrimSpace(frame.Source))
+	if !strings.HasPrefix(source, `\begin{frame}`) {
+		return false
+	}
+	inner, _ := envBody(source, len(`\begin{frame}`), "frame")
+	parsed := parseFrame(inner)
+
+	frame.Section, frame.Subsection, frame.Source = "", "", ""
+	want, err := json.Marshal(frame)
+	if err != nil {
+		return false
+	}
+	got, err := json.Marshal(parsed)
+	return err == nil && string(got) == string(want)
+}
+
+// renderPreamble 返回导言区，没有导言区时生成最小的 beamer 导言区
+func renderPreamble(deck *Deck) string {
+	preamble := deck.Preamble
+	if strings.TrimSpace(preamble) == "" {
+		class := `\documentclass{beamer}`
+		if deck.AspectRatio != "" {
+			class = fmt.Sprintf(`\documentclass[aspectratio=%s]{beamer}`, deck.AspectRatio)
+		}
+		preamble = class + "\n\\usepackage{graphicx}\n"
+	}
+
+	for _, field := range []struct{ name, value string }{
+		{"title", deck.Title}, {"subtitle", deck.Subtitle}, {"author", deck.Author},
+		{"institute", deck.Institute}, {"date", deck.Date},
+	} {
+		preamble = setCommand(preamble, field.name, field.value)
+	}
+	return strings.TrimRight(preamble, "\n") + "\n\n"
+}
+
+// setCommand 把导言区中的 \name{...} 替换为 value；命令不存在且 value 非空时追加到末尾
+func setCommand(preamble, name, value string) string {
+	command := fmt.Sprintf(`\%s{%s}`, name, value)
+	if _, start, end, ok := findCommand(preamble, name); ok {
+		return preamble[:start] + command + preamble[end:]
+	}
+	if value == "" {
+		return preamble
+	}
+	return strings.TrimRight(preamble, "\n") + "\n" + command + "\n"
+}
+
+func renderBlocks(b *strings.Builder, blocks []Block, indent string) {
+	for _, block := range blocks {
+		switch block.Kind {
+		case BlockParagraph:
+			b.WriteString(indentLines(block.Text, indent) + "\n\n")
+		case BlockList:
+			renderList(b, block, indent)
+		case BlockBox:
+			style := block.Style
+			if style == "" {
+				style = "block"
+			}
+			fmt.Fprintf(b, "%s\\begin{%s}{%s}\n", indent, style, block.Text)
+			renderBlocks(b, block.Children, indent+"  ")
+			fmt.Fprintf(b, "%s\\end{%s}\n", indent, style)
+		case BlockMath:
+			if block.Style != "" {
+				fmt.Fprintf(b, "%s\\begin{%s}\n%s\n%s\\end{%s}\n", indent, block.Style, indentLines(block.Text, indent+"  "), indent, block.Style)
+			} else {
+				fmt.Fprintf(b, "%s\\[\n%s\n%s\\]\n", indent, indentLines(block.Text, indent+"  "), indent)
+			}
+		case BlockCode:
+			env := block.Style
+			if env == "" {
+				env = "verbatim"
+			}
+			// verbatim 的内容不能缩进
+			fmt.Fprintf(b, "%s\\begin{%s}%s\n%s\n\\end{%s}\n", indent, env, block.Options, block.Text, env)
+		case BlockImage:
+			renderImage(b, block, indent)
+		case BlockTable:
+			renderTable(b, block, indent)
+		case BlockColumns:
+			fmt.Fprintf(b, "%s\\begin{columns}", indent)
+			if block.Options != "" {
+				fmt.Fprintf(b, "[%s]", block.Options)
+			}
+			b.WriteString("\n")
+			for _, col := range block.Columns {
+				width := col.Width
+				if width == "" {
+					width = fmt.Sprintf(`%.2f\textwidth`, 0.95/float64(len(block.Columns)))
+				}
+				fmt.Fprintf(b, "%s  \\begin{column}{%s}\n", indent, width)
+				renderBlocks(b, col.Blocks, indent+"    ")
+				fmt.Fprintf(b, "%s  \\end{column}\n", indent)
+			}
+			fmt.Fprintf(b, "%s\\end{columns}\n", indent)
+		}
+	}
+}
+
+func renderList(b *strings.Builder, list Block, indent string) {
+	env := "itemize"
+	switch {
+	case list.Ordered:
+		env = "enumerate"
+	case slices.ContainsFunc(list.Items, func(item ListItem) bool { return item.Label != "" }):
+		env = "description"
+	}
+
+	fmt.Fprintf(b, "%s\\begin{%s}%s\n", indent, env, list.Options)
+	for _, item := range list.Items {
+		b.WriteString(indent + `  \item`)
+		if item.Overlay != "" {
+			fmt.Fprintf(b, "<%s>", item.Overlay)
+		}
+		if item.Label != "" {
+			fmt.Fprintf(b, "[%s]", item.Label)
+		}
+		if item.Text != "" {
+			b.WriteString(" " + strings.TrimSpace(indentLines(item.Text, indent+"    ")))
+		}
+		b.WriteString("\n")
+		renderBlocks(b, item.Children, indent+"    ")
+	}
+	fmt.Fprintf(b, "%s\\end{%s}\n", indent, env)
+}
+
+func renderImage(b *strings.Builder, image Block, indent string) {
+	graphic := `\includegraphics`
+	if image.Options != "" {
+		graphic += "[" + image.Options + "]"
+	}
+	graphic += "{" + image.Path + "}"
+
+	if image.Caption == "" {
+		fmt.Fprintf(b, "%s\\begin{center}\n%s  %s\n%s\\end{center}\n", indent, indent, graphic, indent)
+		return
+	}
+	fmt.Fprintf(b, "%s\\begin{figure}\n%s  \\centering\n%s  %s\n%s  \\caption{%s}\n%s\\end{figure}\n",
+		indent, indent, indent, graphic, indent, image.Caption, indent)
+}
+
+func renderTable(b *strings.Builder, table Block, indent string) {
+	cols := 0
+	for _, row := range table.Rows {
+		cols = max(cols, len(row))
+	}
+	spec := table.Options
+	if spec == "" {
+		spec = strings.Repeat("l", max(cols, 1))
+	}
+
+	env, args := table.Style, ""
+	if _, ok := tableEnvs[env]; !ok {
+		env = "tabular"
+	}
+	if tableEnvs[env] == 2 {
+		width := table.Width
+		if width == "" {
+			width = `\textwidth`
+		}
+		args = "{" + width + "}"
+	}
+
+	fmt.Fprintf(b, "%s\\begin{center}\n%s\\begin{%s}%s{%s}\n%s  \\hline\n", indent, indent, env, args, spec, indent)
+	for i, row := range table.Rows {
+		cells := make([]string, cols)
+		copy(cells, row)
+		fmt.Fprintf(b, "%s  %s \\\\\n", indent, strings.Join(cells, " & "))
+		if i == 0 && len(table.Rows) > 1 {
+			fmt.Fprintf(b, "%s  \\hline\n", indent)
+		}
+	}
+	fmt.Fprintf(b, "%s  \\hline\n%s\\end{%s}\n%s\\end{center}\n", indent, indent, env, indent)
+}
+
+// hasCode 判断内容块中是否有需要 fragile 的 verbatim
+func hasCode(blocks []Block) bool {
+	for _, block := range blocks {
+		if block.Kind == BlockCode || hasCode(block.Children) {
+			return true
+		}
+		for _, item := range block.Items {
+			if hasCode(item.Children) {
+				return true
+			}
+		}
+		for _, col := range block.Columns {
+			if hasCode(col.Blocks) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// indentLines 给多行文本的每一行加上缩进
+func indentLines(text, indent string) string {
+	lines := strings.Split(strings.TrimSpace(text), "\n")
+	for i, line := range lines {
+		lines[i] = indent + strings.TrimSpace(line)
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,158 @@
+package latex
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+const roundTripDeck = `\documentclass[aspectratio=169]{beamer}
+\title{Deck}
+\author{Someone}
+\begin{document}
+\maketitle
+\section{Intro}
+\begin{frame}{Plain} % keep this comment
+Hello \textbf{world}.
+\end{frame}
+\begin{frame}[fragile]{Code}
+\begin{lstlisting}[language=Go]
+fmt.Println("%d")
+\end{lstlisting}
+\end{frame}
+\end{document}
+`
+
+func TestRenderDeckKeepsUnchangedFrames(t *testing.T) {
+	deck := ParseDeck(roundTripDeck)
+	out := RenderDeck(deck)
+	for _, frame := range deck.Frames() {
+		if !strings.Contains(out, frame.Source) {
+			t.Errorf("frame %q was not written verbatim:\n%s", frame.Title, out)
+		}
+	}
+
+	deck.Sections[1].Frames[0].Title = "Changed"
+	out = RenderDeck(deck)
+	if strings.Contains(out, "keep this comment") {
+		t.Errorf("edited frame was written from its old source:\n%s", out)
+	}
+	if !strings.Contains(out, `\begin{frame}{Changed}`) {
+		t.Errorf("edited frame is missing:\n%s", out)
+	}
+
+	again := ParseDeck(out)
+	if again.Title != "Deck" || again.Author != "Someone" || again.AspectRatio != "169" {
+		t.Errorf("metadata changed: %+v", again)
+	}
+	if got := len(again.Frames()); got != 3 {
+		t.Errorf("got %d frames after rendering, want 3", got)
+	}
+}
+
+func TestRenderFrameRoundTrip(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		// want 是重新生成的源码中必须出现的片段
+		want []string
+	}{
+		{
+			name:   "overlays",
+			source: "\\begin{frame}{Steps}\n\\begin{itemize}[<+->]\n\\item<2-> First\n\\item<3> Second\n\\end{itemize}\n\\end{frame}",
+			want:   []string{`\begin{itemize}[<+->]`, `\item<2-> First`, `\item<3> Second`},
+		},
+		{
+			name:   "lstlisting",
+			source: "\\begin{frame}[fragile]{Code}\n\\begin{lstlisting}[language=Go]\nfunc main() {\n\tfmt.Println(\"%d\")\n}\n\\end{lstlisting}\n\\end{frame}",
+			want:   []string{"\\begin{lstlisting}[language=Go]\nfunc main() {\n\tfmt.Println(\"%d\")\n}\n\\end{lstlisting}"},
+		},
+		{
+			name:   "minted",
+			source: "\\begin{frame}[fragile]{Code}\n\\begin{minted}[linenos]{python}\nprint('%s' % x)\n\\end{minted}\n\\end{frame}",
+			want:   []string{"\\begin{minted}[linenos]{python}\nprint('%s' % x)\n\\end{minted}"},
+		},
+		{
+			name:   "tabularx",
+			source: "\\begin{frame}{Table}\n\\begin{tabularx}{\\textwidth}{XX}\n\\toprule\nA & B \\\\\n\\midrule\n1 & 2 \\\\\n\\bottomrule\n\\end{tabularx}\n\\end{frame}",
+			want:   []string{`\begin{tabularx}{\textwidth}{XX}`, `\end{tabularx}`},
+		},
+		{
+			name:   "align labels",
+			source: "\\begin{frame}{Math}\n\\begin{align}\na &= b \\label{eq:a} \\\\\nc &= d \\label{eq:c}\n\\end{align}\nSee \\eqref{eq:a}.\n\\end{frame}",
+			want:   []string{`\begin{align}`, `\label{eq:a}`, `\label{eq:c}`, `\end{align}`},
+		},
+		{
+			name:   "columns",
+			source: "\\begin{frame}{Columns}\n\\begin{columns}[T]\n\\begin{column}{0.4\\textwidth}\nLeft\n\\end{column}\n\\begin{column}{0.6\\textwidth}\n\\begin{block}{Box}\nRight\n\\end{block}\n\\end{column}\n\\end{columns}\n\\end{frame}",
+			want:   []string{`\begin{columns}[T]`, `\begin{column}{0.4\textwidth}`, `\begin{block}{Box}`},
+		},
+		{
+			name:   "nested lists",
+			source: "\\begin{frame}{Lists}\n\\begin{enumerate}\n\\item One\n\\begin{description}\n\\item[Term] Meaning\n\\end{description}\n\\item Two\n\\end{enumerate}\n\\end{frame}",
+			want:   []string{`\begin{enumerate}`, `\item[Term] Meaning`},
+		},
+		{
+			name:   "figure",
+			source: "\\begin{frame}{Figure}\n\\begin{figure}\n\\centering\n\\includegraphics[width=0.5\\textwidth]{img/a.png}\n\\caption{A picture}\n\\end{figure}\n\\[ x^2 \\]\n\\end{frame}",
+			want:   []string{`\includegraphics[width=0.5\textwidth]{img/a.png}`, `\caption{A picture}`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			frame := parseTestFrame(t, tt.source)
+			if got := RenderFrame(frame); got != tt.source {
+				t.Errorf("unchanged frame was re-rendered:\n%s", got)
+			}
+
+			// 改动标题后 frame 由内容块生成，重新解析应得到相同的内容块
+			frame.Title += " (edited)"
+			out := RenderFrame(frame)
+			for _, want := range tt.want {
+				if !strings.Contains(out, want) {
+					t.Errorf("rendered frame is missing %q:\n%s", want, out)
+				}
+			}
+			again := parseTestFrame(t, out)
+			if got, want := frameJSON(t, again), frameJSON(t, frame); got != want {
+				t.Errorf("round trip changed the frame:\ngot  %s\nwant %s\nrendered:\n%s", got, want, out)
+			}
+		})
+	}
+}
+
+func TestDisplayMath(t *testing.T) {
+	tests := []struct {
+		block Block
+		want  string
+	}{
+		{Block{Kind: BlockMath, Text: "x^2"}, "x^2"},
+		{Block{Kind: BlockMath, Style: "equation", Text: `E = mc^2 \label{eq:e}`}, "E = mc^2"},
+		{Block{Kind: BlockMath, Style: "align*", Text: `a &= b \\ c &= d`}, "\\begin{aligned}\na &= b \\\\ c &= d\n\\end{aligned}"},
+	}
+	for _, tt := range tests {
+		if got := tt.block.DisplayMath(); got != tt.want {
+			t.Errorf("DisplayMath(%+v) = %q, want %q", tt.block, got, tt.want)
+		}
+	}
+}
+
+func parseTestFrame(t *testing.T, source string) Frame {
+	t.Helper()
+	frames := ParseDeck("\\begin{document}\n" + source + "\n\\end{document}").Frames()
+	if len(frames) != 1 {
+		t.Fatalf("got %d frames, want 1", len(frames))
+	}
+	return frames[0]
+}
+
+// frameJSON 返回去掉 Source 后的 frame，用于比较内容
+func frameJSON(t *testing.T, frame Frame) string {
+	t.Helper()
+	frame.Source = ""
+	data, err := json.Marshal(frame)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
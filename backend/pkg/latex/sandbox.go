@@ -0,0 +1,115 @@
+package latex
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"time"
+)
+
+var ErrCompileTimeout = errors.New("latex compilation timed out")
+
+// Limits 限制单次 TeX 进程可使用的资源，零值表示不限制
+type Limits struct {
+	// Timeout 单次编译（全部 pass）的墙钟时间上限
+	Timeout time.Duration
+	// CPUSeconds 单个进程的 CPU 时间上限
+	CPUSeconds int
+	// MemoryMB 单个进程的虚拟内存上限
+	MemoryMB int
+	// MaxFileMB 单个输出文件（PDF、log 等）的大小上限
+	MaxFileMB int
+	// MaxOutputBytes 保留的终端输出字节数，超出部分被丢弃
+	MaxOutputBytes int
+}
+
+// sandboxEnv 禁用 shell-escape，并限制 \input/\openout 只能访问工作目录
+func sandboxEnv(workDir string) []string {
+	overrides := map[string]string{
+		"shell_escape":   "f",
+		"openin_any":     "p",
+		"openout_any":    "p",
+		"TEXMFOUTPUT":    workDir,
+		"max_print_line": "10000",
+	}
+
+	env := make([]string, 0, len(os.Environ())+len(overrides))
+	for _, kv := range os.Environ() {
+		key, _, _ := strings.Cut(kv, "=")
+		if _, ok := overrides[key]; ok {
+			continue
+		}
+		env = append(env, kv)
+	}
+	for k, v := range overrides {
+		env = append(env, k+"="+v)
+	}
+	return env
+}
+
+// runSandboxed 在 workDir 中以受限资源运行命令，ctx 取消时终止整个进程组
+func runSandboxed(ctx context.Context, workDir string, limits Limits, name string, args ...string) ([]byte, error) {
+	cmd := sandboxCommand(limits, name, args...)
+	cmd.Dir = workDir
+	cmd.Env = sandboxEnv(workDir)
+
+	output := &limitedBuffer{max: limits.MaxOutputBytes}
+	cmd.Stdout = output
+	cmd.Stderr = output
+	cmd.Stdin = nil
+	setProcessGroup(cmd)
+
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+
+	select {
+	case err := <-done:
+		return output.Bytes(), err
+	case <-ctx.Done():
+		killProcessGroup(cmd)
+		<-done
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return output.Bytes(), ErrCompileTimeout
+		}
+		return output.Bytes(), ctx.Err()
+	}
+}
+
+// limitedBuffer 只保留前 max 个字节，之后的写入被丢弃但仍报告成功，避免子进程阻塞
+type limitedBuffer struct {
+	buf       bytes.Buffer
+	max       int
+	truncated bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if b.max <= 0 {
+		return b.buf.Write(p)
+	}
+	if remaining := b.max - b.buf.Len(); remaining > 0 {
+		if len(p) > remaining {
+			b.buf.Write(p[:remaining])
+			b.truncated = true
+		} else {
+			b.buf.Write(p)
+		}
+	} else if len(p) > 0 {
+		b.truncated = true
+	}
+	return len(p), nil
+}
+
+func (b *limitedBuffer) Bytes() []byte {
+	if b.truncated {
+		return append(b.buf.Bytes(), []byte("\n[output truncated]")...)
+	}
+	return b.buf.Bytes()
+}
@@ -0,0 +1,46 @@
+//go:build !windows
+
+package latex
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+	"syscall"
+)
+
+// sandboxCommand 通过 sh 设置 rlimit 后 exec 目标程序
+func sandboxCommand(limits Limits, name string, args ...string) *exec.Cmd {
+	shArgs := append([]string{"-c", ulimitScript(limits), "sh", name}, args...)
+	return exec.Command("sh", shArgs...)
+}
+
+// setProcessGroup 让子进程成为新进程组的组长，便于一次性终止其派生的所有进程
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+func killProcessGroup(cmd *exec.Cmd) {
+	if cmd.Process == nil {
+		return
+	}
+	// 负 pid 表示整个进程组
+	syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+}
+
+// ulimitScript 生成在 exec 目标程序前设置资源限制的 shell 片段
+func ulimitScript(limits Limits) string {
+	var parts []string
+	if limits.CPUSeconds > 0 {
+		parts = append(parts, fmt.Sprintf("ulimit -t %d", limits.CPUSeconds))
+	}
+	if limits.MemoryMB > 0 {
+		parts = append(parts, fmt.Sprintf("ulimit -v %d", limits.MemoryMB*1024))
+	}
+	if limits.MaxFileMB > 0 {
+		// ulimit -f 以 512 字节块为单位
+		parts = append(parts, fmt.Sprintf("ulimit -f %d", limits.MaxFileMB*2048))
+	}
+	parts = append(parts, `exec "$@"`)
+	return strings.Join(parts, " && ")
+}
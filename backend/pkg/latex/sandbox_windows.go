@@ -0,0 +1,20 @@
+//go:build windows
+
+package latex
+
+import (
+	"os/exec"
+)
+
+// sandboxCommand 在 Windows 上无法设置 rlimit，仅依赖超时终止进程
+func sandboxCommand(limits Limits, name string, args ...string) *exec.Cmd {
+	return exec.Command(name, args...)
+}
+
+func setProcessGroup(cmd *exec.Cmd) {}
+
+func killProcessGroup(cmd *exec.Cmd) {
+	if cmd.Process != nil {
+		cmd.Process.Kill()
+	}
+}
@@ -1,5 +1,7 @@
 package latex
 
+import "strings"
+
 // GetTemplate returns a LaTeX Beamer template by name
 func GetTemplate(name string) string {
 	templates := map[string]string{
@@ -14,6 +16,17 @@ func GetTemplate(name string) string {
 	return defaultTemplate
 }
 
+// templateEngines 记录模板偏好的 TeX 引擎，未列出的模板使用编译器默认引擎
+var templateEngines = map[string]string{
+	// metropolis 主题通过 fontspec 使用 Fira 字体，ctex 在 xelatex 下使用系统中文字体
+	"modern": "xelatex",
+}
+
+// TemplateEngine 返回模板偏好的引擎名称，没有偏好时返回空串
+func TemplateEngine(name string) string {
+	return templateEngines[name]
+}
+
 const defaultTemplate = `\documentclass[aspectratio=169,11pt]{beamer}
 
 % 中文支持
@@ -113,3 +126,76 @@ const modernTemplate = `\documentclass[aspectratio=169,11pt]{beamer}
 func ListTemplates() []string {
 	return []string{"default", "madrid", "modern"}
 }
+
+// SampleContent 是用于校验和预览模板的示例正文，只使用 Beamer 自带的命令，
+// 以免对模板加载的宏包做任何假设
+const SampleContent = `\section{Sample Section}
+
+\begin{frame}{Sample Content Slide}
+  \begin{itemize}
+    \item First point with \textbf{bold} and \emph{emphasis}
+    \item Second point with inline math $E = mc^2$
+    \item Third point
+  \end{itemize}
+  \begin{block}{Block Title}
+    Block content
+  \end{block}
+\end{frame}`
+
+// TemplateData 是渲染模板所需的数据。元信息会被转义，Content 按 LaTeX 原样插入
+type TemplateData struct {
+	Title     string
+	Subtitle  string
+	Author    string
+	Institute string
+	Content   string
+}
+
+// RenderTemplate 把 data 填入模板的 {{TITLE}} 等占位符
+func RenderTemplate(template string, data TemplateData) string {
+	return strings.NewReplacer(
+		"{{TITLE}}", EscapeLaTeX(data.Title),
+		"{{SUBTITLE}}", EscapeLaTeX(data.Subtitle),
+		"{{AUTHOR}}", EscapeLaTeX(data.Author),
+		"{{INSTITUTE}}", EscapeLaTeX(data.Institute),
+		"{{CONTENT}}", data.Content,
+	).Replace(template)
+}
+
+var latexEscaper = strings.NewReplacer(
+	`\`, `\textbackslash{}`,
+	`{`, `\{`,
+	`}`, `\}`,
+	`$`, `\$`,
+	`&`, `\&`,
+	`#`, `\#`,
+	`%`, `\%`,
+	`_`, `\_`,
+	`^`, `\textasciicircum{}`,
+	`~`, `\textasciitilde{}`,
+	"\r\n", " ",
+	"\n", " ",
+)
+
+// EscapeLaTeX 转义 LaTeX 特殊字符，使任意文本可以安全地作为普通文本排版
+func EscapeLaTeX(s string) string {
+	return latexEscaper.Replace(s)
+}
+
+// FrameContent 从模型输出中取出正文：若模型仍然输出了完整文档，
+// 只保留 \begin{document} 与 \end{document} 之间的内容，并去掉模板已经提供的标题页，
+// 包括 frame 外的 \maketitle 和只含 \titlepage 的 frame
+func FrameContent(source string) string {
+	if _, body, ok := strings.Cut(source, `\begin{document}`); ok {
+		body, _, _ = strings.Cut(body, `\end{document}`)
+		source = body
+	}
+	deck, spans := parseDeck(source)
+	frames := deck.Frames()
+	for i := len(spans) - 1; i >= 0; i-- {
+		if frame := frames[i]; frame.TitlePage && len(frame.Blocks) == 0 && len(frame.Unsupported) == 0 {
+			source = source[:spans[i][0]] + source[spans[i][1]:]
+		}
+	}
+	return strings.TrimSpace(source)
+}
@@ -0,0 +1,49 @@
+package latex
+
+import "testing"
+
+func TestFrameContent(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{
+			name:   "frames only",
+			source: "\\begin{frame}{A}\nx\n\\end{frame}\n",
+			want:   "\\begin{frame}{A}\nx\n\\end{frame}",
+		},
+		{
+			name:   "full document",
+			source: "\\documentclass{beamer}\n\\begin{document}\n\\maketitle\n\\begin{frame}{A}\nx\n\\end{frame}\n\\end{document}\n",
+			want:   "\\begin{frame}{A}\nx\n\\end{frame}",
+		},
+		{
+			name:   "title page frame",
+			source: "\\begin{frame}\n  \\titlepage\n\\end{frame}\n\\begin{frame}{A}\nx\n\\end{frame}",
+			want:   "\\begin{frame}{A}\nx\n\\end{frame}",
+		},
+		{
+			name:   "title page frame with options",
+			source: "\\begin{frame}[plain]\\maketitle\\end{frame}\n\\begin{frame}{A}\nx\n\\end{frame}",
+			want:   "\\begin{frame}{A}\nx\n\\end{frame}",
+		},
+		{
+			name:   "frame with more than the title page",
+			source: "\\begin{frame}\n\\titlepage\nWelcome\n\\end{frame}",
+			want:   "\\begin{frame}\n\\titlepage\nWelcome\n\\end{frame}",
+		},
+		{
+			name:   "commented title page",
+			source: "% \\begin{frame}\\titlepage\\end{frame}\n\\begin{frame}{A}\n50\\% % \\titlepage\n\\end{frame}",
+			want:   "% \\begin{frame}\\titlepage\\end{frame}\n\\begin{frame}{A}\n50\\% % \\titlepage\n\\end{frame}",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FrameContent(tt.source); got != tt.want {
+				t.Errorf("FrameContent() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
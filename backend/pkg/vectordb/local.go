@@ -0,0 +1,235 @@
+package vectordb
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"sync"
+)
+
+var errLocalStoreClosed = errors.New("local vector store is not initialized")
+
+// LocalStore 是进程内的向量存储：向量保存在内存中并以 JSON Lines 追加写入 path，
+// 检索时逐个计算距离。适合文档量不大、不想部署 Milvus 的环境
+type LocalStore struct {
+	path string
+
+	mu      sync.RWMutex
+	file    *os.File
+	records []localRecord
+}
+
+type localRecord struct {
+	ChunkID    int64     `json:"chunk_id"`
+	DocumentID int64     `json:"document_id"`
+	UserID     int64     `json:"user_id"`
+	Content    string    `json:"content"`
+	Embedding  []float32 `json:"embedding"`
+}
+
+func NewLocalStore(path string) *LocalStore {
+	return &LocalStore{path: path}
+}
+
+// Init 加载已保存的向量并打开文件用于追加，无法解析的记录（如写入中断留下的半行）会被跳过
+func (s *LocalStore) Init(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file != nil {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(s.path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+
+	records, torn, err := readRecords(file)
+	if err != nil {
+		file.Close()
+		return err
+	}
+	// 补上换行，避免新记录接在半行后面
+	if torn {
+		if _, err := file.Write([]byte("\n")); err != nil {
+			file.Close()
+			return err
+		}
+	}
+
+	s.file = file
+	s.records = records
+	log.Printf("Loaded %d vectors from %s", len(records), s.path)
+	return nil
+}
+
+// readRecords 逐行读取记录，torn 表示文件最后一行没有换行
+func readRecords(r io.Reader) (records []localRecord, torn bool, err error) {
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			var record localRecord
+			if jsonErr := json.Unmarshal(line, &record); jsonErr != nil {
+				log.Printf("Skipping malformed vector record: %v", jsonErr)
+			} else {
+				records = append(records, record)
+			}
+		}
+		if err == io.EOF {
+			return records, len(line) > 0, nil
+		}
+		if err != nil {
+			return nil, false, err
+		}
+	}
+}
+
+func (s *LocalStore) Insert(ctx context.Context, chunkID, documentID, userID int64, content string, embedding []float32) (string, error) {
+	record := localRecord{
+		ChunkID:    chunkID,
+		DocumentID: documentID,
+		UserID:     userID,
+		Content:    content,
+		Embedding:  embedding,
+	}
+	line, err := json.Marshal(record)
+	if err != nil {
+		return "", err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return "", errLocalStoreClosed
+	}
+	if _, err := s.file.Write(append(line, '\n')); err != nil {
+		return "", err
+	}
+	s.records = append(s.records, record)
+
+	return strconv.FormatInt(chunkID, 10), nil
+}
+
+// Search 按 L2 距离的平方（与 Milvus 的 L2 度量一致）从小到大返回 topK 个分块
+func (s *LocalStore) Search(ctx context.Context, embedding []float32, topK int, filter Filter) ([]SearchResult, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.file == nil {
+		return nil, errLocalStoreClosed
+	}
+
+	var results []SearchResult
+	for _, record := range s.records {
+		if len(record.Embedding) != len(embedding) || !filter.match(record.UserID, record.DocumentID) {
+			continue
+		}
+		results = append(results, SearchResult{
+			ChunkID:    record.ChunkID,
+			DocumentID: record.DocumentID,
+			Content:    record.Content,
+			Score:      squaredL2(record.Embedding, embedding),
+		})
+	}
+
+	slices.SortFunc(results, func(a, b SearchResult) int {
+		return cmp.Compare(a.Score, b.Score)
+	})
+	if len(results) > topK {
+		results = results[:topK]
+	}
+	return results, nil
+}
+
+// Delete 从内存和文件中删除文档的全部分块。文件按剩余记录重写到临时文件后替换原文件，
+// 同时去掉之前写入中断留下的半行
+func (s *LocalStore) Delete(ctx context.Context, documentID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return errLocalStoreClosed
+	}
+
+	kept := make([]localRecord, 0, len(s.records))
+	for _, record := range s.records {
+		if record.DocumentID != documentID {
+			kept = append(kept, record)
+		}
+	}
+	if len(kept) == len(s.records) {
+		return nil
+	}
+
+	if err := s.rewrite(kept); err != nil {
+		return err
+	}
+	s.records = kept
+	return nil
+}
+
+// rewrite 把 records 写入临时文件并替换 s.path，成功后 s.file 指向新文件；
+// 新文件无法打开时 s.file 为 nil，之后的操作返回 errLocalStoreClosed
+func (s *LocalStore) rewrite(records []localRecord) error {
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	writer := bufio.NewWriter(tmp)
+	encoder := json.NewEncoder(writer)
+	for _, record := range records {
+		if err := encoder.Encode(record); err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return err
+	}
+
+	s.file.Close()
+	s.file, err = os.OpenFile(s.path, os.O_RDWR|os.O_APPEND, 0644)
+	return err
+}
+
+func (s *LocalStore) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file != nil {
+		s.file.Close()
+		s.file = nil
+	}
+}
+
+func squaredL2(a, b []float32) float32 {
+	var sum float32
+	for i := range a {
+		d := a[i] - b[i]
+		sum += d * d
+	}
+	return sum
+}
@@ -0,0 +1,180 @@
+package vectordb
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func newTestStore(t *testing.T) (*LocalStore, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "data", "vectors.jsonl")
+	store := NewLocalStore(path)
+	if err := store.Init(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(store.Close)
+	return store, path
+}
+
+func insert(t *testing.T, store *LocalStore, chunkID, documentID, userID int64, embedding ...float32) {
+	t.Helper()
+	if _, err := store.Insert(context.Background(), chunkID, documentID, userID, "chunk", embedding); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func chunkIDs(results []SearchResult) []int64 {
+	ids := make([]int64, len(results))
+	for i, r := range results {
+		ids[i] = r.ChunkID
+	}
+	return ids
+}
+
+func TestLocalStoreSearch(t *testing.T) {
+	store, _ := newTestStore(t)
+	insert(t, store, 1, 10, 1, 0, 0)
+	insert(t, store, 2, 10, 1, 1, 0)
+	insert(t, store, 3, 20, 1, 3, 0)
+	insert(t, store, 4, 30, 2, 0, 0)
+	insert(t, store, 5, 10, 1, 0, 0, 0) // 维度不同
+
+	tests := []struct {
+		name   string
+		query  []float32
+		topK   int
+		filter Filter
+		want   []int64
+	}{
+		{"nearest first", []float32{0.9, 0}, 10, Filter{}, []int64{2, 1, 4, 3}},
+		{"top k", []float32{3, 0}, 2, Filter{}, []int64{3, 2}},
+		{"user", []float32{0, 0}, 10, Filter{UserID: 2}, []int64{4}},
+		{"documents", []float32{0, 0}, 10, Filter{UserID: 1, DocumentIDs: []int64{20}}, []int64{3}},
+		{"no match", []float32{0, 0}, 10, Filter{UserID: 3}, []int64{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := store.Search(context.Background(), tt.query, tt.topK, tt.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := chunkIDs(results); !slices.Equal(got, tt.want) {
+				t.Errorf("Search() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLocalStorePersistence(t *testing.T) {
+	tests := []struct {
+		name string
+		// prepare 在重新打开之前修改文件
+		prepare func(t *testing.T, path string)
+		want    []int64
+	}{
+		{name: "reload", want: []int64{1, 2}},
+		{
+			name: "torn last line",
+			prepare: func(t *testing.T, path string) {
+				f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer f.Close()
+				if _, err := f.WriteString(`{"chunk_id": 9, "embed`); err != nil {
+					t.Fatal(err)
+				}
+			},
+			want: []int64{1, 2, 3},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, path := newTestStore(t)
+			insert(t, store, 1, 10, 1, 0)
+			insert(t, store, 2, 10, 1, 1)
+			store.Close()
+			if tt.prepare != nil {
+				tt.prepare(t, path)
+			}
+
+			reopened := NewLocalStore(path)
+			if err := reopened.Init(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			defer reopened.Close()
+			if tt.prepare != nil {
+				// 半行之后追加的记录必须能再次读出
+				insert(t, reopened, 3, 10, 1, 2)
+				reopened.Close()
+				if err := reopened.Init(context.Background()); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			results, err := reopened.Search(context.Background(), []float32{0}, 10, Filter{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := chunkIDs(results); !slices.Equal(got, tt.want) {
+				t.Errorf("after reopening got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLocalStoreDelete(t *testing.T) {
+	store, path := newTestStore(t)
+	insert(t, store, 1, 10, 1, 0)
+	insert(t, store, 2, 20, 1, 1)
+	insert(t, store, 3, 10, 1, 2)
+
+	ctx := context.Background()
+	if err := store.Delete(ctx, 10); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Delete(ctx, 99); err != nil {
+		t.Fatalf("deleting a document without chunks: %v", err)
+	}
+	insert(t, store, 4, 30, 1, 3)
+
+	store.Close()
+	reopened := NewLocalStore(path)
+	if err := reopened.Init(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+	results, err := reopened.Search(ctx, []float32{0}, 10, Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := chunkIDs(results), []int64{2, 4}; !slices.Equal(got, want) {
+		t.Errorf("after delete got %v, want %v", got, want)
+	}
+
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("temporary files left behind: %v", entries)
+	}
+}
+
+func TestLocalStoreClosed(t *testing.T) {
+	store := NewLocalStore(filepath.Join(t.TempDir(), "vectors.jsonl"))
+	ctx := context.Background()
+	if _, err := store.Insert(ctx, 1, 1, 1, "x", []float32{0}); !errors.Is(err, errLocalStoreClosed) {
+		t.Errorf("Insert before Init: %v", err)
+	}
+	if _, err := store.Search(ctx, []float32{0}, 1, Filter{}); !errors.Is(err, errLocalStoreClosed) {
+		t.Errorf("Search before Init: %v", err)
+	}
+	if err := store.Delete(ctx, 1); !errors.Is(err, errLocalStoreClosed) {
+		t.Errorf("Delete before Init: %v", err)
+	}
+}
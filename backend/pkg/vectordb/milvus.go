@@ -4,6 +4,10 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/milvus-io/milvus-sdk-go/v2/client"
 	"github.com/milvus-io/milvus-sdk-go/v2/entity"
@@ -12,10 +16,19 @@ import (
 type MilvusClient struct {
 	client         client.Client
 	collectionName string
+
+	mu        sync.Mutex
+	userField *bool // 集合是否有 user_id 字段，首次使用时检查
 }
 
+// 连接 Milvus 的超时时间，SDK 默认阻塞等待连接建立，不设超时在 Milvus 不可达时会一直等待
+const milvusDialTimeout = 10 * time.Second
+
 func NewMilvusClient(host, port string) (*MilvusClient, error) {
-	c, err := client.NewClient(context.Background(), client.Config{
+	ctx, cancel := context.WithTimeout(context.Background(), milvusDialTimeout)
+	defer cancel()
+
+	c, err := client.NewClient(ctx, client.Config{
 		Address: fmt.Sprintf("%s:%s", host, port),
 	})
 	if err != nil {
@@ -28,7 +41,8 @@ func NewMilvusClient(host, port string) (*MilvusClient, error) {
 	}, nil
 }
 
-func (m *MilvusClient) CreateCollection(ctx context.Context) error {
+// Init 创建集合和向量索引，集合已存在时直接返回
+func (m *MilvusClient) Init(ctx context.Context) error {
 	// Check if collection exists
 	has, err := m.client.HasCollection(ctx, m.collectionName)
 	if err != nil {
@@ -59,6 +73,10 @@ func (m *MilvusClient) CreateCollection(ctx context.Context) error {
 				Name:     "document_id",
 				DataType: entity.FieldTypeInt64,
 			},
+			{
+				Name:     "user_id",
+				DataType: entity.FieldTypeInt64,
+			},
 			{
 				Name:     "content",
 				DataType: entity.FieldTypeVarChar,
@@ -90,19 +108,28 @@ func (m *MilvusClient) CreateCollection(ctx context.Context) error {
 	return m.client.CreateIndex(ctx, m.collectionName, "embedding", idx, false)
 }
 
-func (m *MilvusClient) Insert(ctx context.Context, chunkID, documentID int64, content string, embedding []float32) (string, error) {
+func (m *MilvusClient) Insert(ctx context.Context, chunkID, documentID, userID int64, content string, embedding []float32) (string, error) {
 	// Load collection
 	err := m.client.LoadCollection(ctx, m.collectionName, false)
 	if err != nil {
 		return "", err
 	}
+	userField, err := m.userFieldEnabled(ctx)
+	if err != nil {
+		return "", err
+	}
 
-	chunkIDColumn := entity.NewColumnInt64("chunk_id", []int64{chunkID})
-	documentIDColumn := entity.NewColumnInt64("document_id", []int64{documentID})
-	contentColumn := entity.NewColumnVarChar("content", []string{content})
-	embeddingColumn := entity.NewColumnFloatVector("embedding", 1536, [][]float32{embedding})
+	columns := []entity.Column{
+		entity.NewColumnInt64("chunk_id", []int64{chunkID}),
+		entity.NewColumnInt64("document_id", []int64{documentID}),
+		entity.NewColumnVarChar("content", []string{content}),
+		entity.NewColumnFloatVector("embedding", 1536, [][]float32{embedding}),
+	}
+	if userField {
+		columns = append(columns, entity.NewColumnInt64("user_id", []int64{userID}))
+	}
 
-	_, err = m.client.Insert(ctx, m.collectionName, "", chunkIDColumn, documentIDColumn, contentColumn, embeddingColumn)
+	_, err = m.client.Insert(ctx, m.collectionName, "", columns...)
 	if err != nil {
 		return "", err
 	}
@@ -110,19 +137,24 @@ func (m *MilvusClient) Insert(ctx context.Context, chunkID, documentID int64, co
 	return fmt.Sprintf("%d", chunkID), nil
 }
 
-func (m *MilvusClient) Search(ctx context.Context, embedding []float32, topK int) ([]SearchResult, error) {
+// Search 返回与 embedding 最相近的 topK 个分块，只在 filter 限定的范围内检索
+func (m *MilvusClient) Search(ctx context.Context, embedding []float32, topK int, filter Filter) ([]SearchResult, error) {
 	err := m.client.LoadCollection(ctx, m.collectionName, false)
 	if err != nil {
 		return nil, err
 	}
+	userField, err := m.userFieldEnabled(ctx)
+	if err != nil {
+		return nil, err
+	}
 
 	sp, _ := entity.NewIndexAUTOINDEXSearchParam(1)
-	
+
 	results, err := m.client.Search(
 		ctx,
 		m.collectionName,
 		nil,
-		"",
+		filter.expr(userField),
 		[]string{"chunk_id", "document_id", "content"},
 		[]entity.Vector{entity.FloatVector(embedding)},
 		"embedding",
@@ -154,15 +186,56 @@ func (m *MilvusClient) Search(ctx context.Context, embedding []float32, topK int
 	return searchResults, nil
 }
 
+// Delete 按 document_id 删除文档的全部分块
+func (m *MilvusClient) Delete(ctx context.Context, documentID int64) error {
+	expr := Filter{DocumentIDs: []int64{documentID}}.expr(false)
+	return m.client.Delete(ctx, m.collectionName, "", expr)
+}
+
+// userFieldEnabled 检查集合是否有 user_id 字段，旧版本创建的集合没有该字段，
+// 此时写入不带 user_id，检索只按 document_id 过滤
+func (m *MilvusClient) userFieldEnabled(ctx context.Context) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.userField != nil {
+		return *m.userField, nil
+	}
+
+	coll, err := m.client.DescribeCollection(ctx, m.collectionName)
+	if err != nil {
+		return false, err
+	}
+	has := false
+	for _, field := range coll.Schema.Fields {
+		if field.Name == "user_id" {
+			has = true
+		}
+	}
+	if !has {
+		log.Printf("Warning: collection %s has no user_id field, searches are filtered by document_id only; recreate it to store user IDs", m.collectionName)
+	}
+	m.userField = &has
+	return has, nil
+}
+
 func (m *MilvusClient) Close() {
 	if m.client != nil {
 		m.client.Close()
 	}
 }
 
-type SearchResult struct {
-	ChunkID    int64
-	DocumentID int64
-	Content    string
-	Score      float32
+// expr 生成 Milvus 布尔表达式，userField 为 false 时不按 user_id 过滤
+func (f Filter) expr(userField bool) string {
+	var conds []string
+	if userField && f.UserID > 0 {
+		conds = append(conds, fmt.Sprintf("user_id == %d", f.UserID))
+	}
+	if len(f.DocumentIDs) > 0 {
+		ids := make([]string, len(f.DocumentIDs))
+		for i, id := range f.DocumentIDs {
+			ids[i] = strconv.FormatInt(id, 10)
+		}
+		conds = append(conds, fmt.Sprintf("document_id in [%s]", strings.Join(ids, ", ")))
+	}
+	return strings.Join(conds, " && ")
 }
@@ -0,0 +1,72 @@
+package vectordb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+var ErrUnavailable = errors.New("vector store unavailable")
+
+// VectorStore 保存文档分块的向量并按相似度检索
+type VectorStore interface {
+	// Init 准备存储（创建集合或加载已保存的向量），启动时调用一次
+	Init(ctx context.Context) error
+	// Insert 保存一个分块，返回其在存储中的 ID
+	Insert(ctx context.Context, chunkID, documentID, userID int64, content string, embedding []float32) (string, error)
+	// Search 返回 filter 范围内与 embedding 最相近的 topK 个分块，Score 为 L2 距离，越小越相近
+	Search(ctx context.Context, embedding []float32, topK int, filter Filter) ([]SearchResult, error)
+	// Delete 删除文档的全部分块，文档没有分块时不报错
+	Delete(ctx context.Context, documentID int64) error
+	Close()
+}
+
+// Filter 限定检索范围
+type Filter struct {
+	UserID      int64   // 大于 0 时只检索该用户的分块
+	DocumentIDs []int64 // 非空时只检索这些文档的分块
+}
+
+// match 判断分块是否在检索范围内
+func (f Filter) match(userID, documentID int64) bool {
+	if f.UserID > 0 && userID != f.UserID {
+		return false
+	}
+	return len(f.DocumentIDs) == 0 || slices.Contains(f.DocumentIDs, documentID)
+}
+
+type SearchResult struct {
+	ChunkID    int64
+	DocumentID int64
+	Content    string
+	Score      float32
+}
+
+// Unavailable 返回一个所有操作都失败的 VectorStore。
+// 向量库无法连接时用它代替，服务照常启动，只是文档不会建立向量、检索返回 ErrUnavailable
+func Unavailable(cause error) VectorStore {
+	return unavailableStore{err: fmt.Errorf("%w: %v", ErrUnavailable, cause)}
+}
+
+type unavailableStore struct {
+	err error
+}
+
+func (s unavailableStore) Init(ctx context.Context) error {
+	return s.err
+}
+
+func (s unavailableStore) Insert(ctx context.Context, chunkID, documentID, userID int64, content string, embedding []float32) (string, error) {
+	return "", s.err
+}
+
+func (s unavailableStore) Search(ctx context.Context, embedding []float32, topK int, filter Filter) ([]SearchResult, error) {
+	return nil, s.err
+}
+
+func (s unavailableStore) Delete(ctx context.Context, documentID int64) error {
+	return s.err
+}
+
+func (s unavailableStore) Close() {}
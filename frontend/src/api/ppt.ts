@@ -1,17 +1,79 @@
 import request from '@/utils/request'
-import type { PPTRecord, GeneratePPTRequest } from '@/types'
+import type { PPTRecord, GeneratePPTRequest, StreamEvent } from '@/types'
 import { getToken } from '@/utils/storage'
 
 export function generatePPT(data: GeneratePPTRequest): Promise<PPTRecord> {
   return request.post('/ppt/generate', data)
 }
 
+// 流式生成 PPT，逐个回调 SSE 事件
+export async function streamGeneratePPT(
+  data: GeneratePPTRequest,
+  onEvent: (event: StreamEvent) => void
+): Promise<void> {
+  const token = getToken()
+  const response = await fetch('/api/v1/ppt/generate', {
+    method: 'POST',
+    headers: {
+      'Authorization': `Bearer ${token}`,
+      'Content-Type': 'application/json',
+      'Accept': 'text/event-stream'
+    },
+    body: JSON.stringify(data)
+  })
+
+  if (!response.ok || !response.body) {
+    throw new Error('Failed to start generation')
+  }
+
+  const reader = response.body.getReader()
+  const decoder = new TextDecoder()
+  let buffer = ''
+
+  while (true) {
+    const { done, value } = await reader.read()
+    if (done) break
+
+    buffer += decoder.decode(value, { stream: true })
+    const messages = buffer.split('\n\n')
+    buffer = messages.pop() || ''
+
+    for (const message of messages) {
+      const dataLine = message.split('\n').find(line => line.startsWith('data: '))
+      if (dataLine) {
+        onEvent(JSON.parse(dataLine.slice(6)))
+      }
+    }
+  }
+}
+
 export function getTemplates(): Promise<{ templates: string[] }> {
   return request.get('/ppt/templates')
 }
 
-export function compileLaTeX(latexContent: string): Promise<PPTRecord> {
-  return request.post('/ppt/compile', { latex_content: latexContent })
+// 重新编译已有 PPT 编辑后的源码
+export function compileLaTeX(id: number, latexContent: string): Promise<PPTRecord> {
+  return request.post(`/ppt/${id}/compile`, { latex_content: latexContent })
+}
+
+// 编译任意源码，不创建 PPT 记录，返回 PDF Blob URL
+export async function compileScratch(latexContent: string): Promise<string> {
+  const token = getToken()
+  const response = await fetch('/api/v1/latex/compile', {
+    method: 'POST',
+    headers: {
+      'Authorization': `Bearer ${token}`,
+      'Content-Type': 'application/json'
+    },
+    body: JSON.stringify({ latex_content: latexContent })
+  })
+
+  if (!response.ok) {
+    throw new Error('Compilation failed')
+  }
+
+  const blob = await response.blob()
+  return window.URL.createObjectURL(blob)
 }
 
 export function getPPTHistory(): Promise<PPTRecord[]> {
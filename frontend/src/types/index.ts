@@ -26,13 +26,123 @@ export interface PPTRecord {
   prompt: string
   latex_content: string
   pdf_path: string
+  revision?: number
   template: string
-  status: 'pending' | 'generating' | 'completed' | 'failed'
+  engine?: string
+  mode?: 'full' | 'template' | 'outline' | 'markdown'
+  status: 'outline' | 'pending' | 'generating' | 'completed' | 'failed'
   error_message?: string
+  diagnostics?: Diagnostic[]
+  passes?: CompilePass[]
+  outline?: Outline
   created_at: string
   updated_at: string
 }
 
+export interface PPTRevision {
+  id: number
+  ppt_id: number
+  number: number
+  source?: string
+  pdf_path?: string
+  engine?: string
+  author_id: number
+  origin: 'generated' | 'manual' | 'auto_fix' | 'restore'
+  restored_from?: number
+  created_at: string
+}
+
+export interface Outline {
+  sections: OutlineSection[]
+}
+
+export interface OutlineSection {
+  title: string
+  frames: OutlineFrame[]
+}
+
+export interface OutlineFrame {
+  title: string
+  points?: string[]
+}
+
+export interface Diagnostic {
+  severity: 'error' | 'warning' | 'badbox'
+  kind?: string
+  line?: number
+  end_line?: number
+  message: string
+  snippet?: string
+}
+
+export interface CompilePass {
+  program: string
+  reason: string
+  duration_ms: number
+  error?: string
+}
+
+export interface SlideInfo {
+  page: number
+  url: string
+  thumbnail_url: string
+}
+
+export type BlockKind = 'paragraph' | 'list' | 'image' | 'table' | 'math' | 'code' | 'block' | 'columns'
+
+export interface DeckBlock {
+  kind: BlockKind
+  text?: string
+  ordered?: boolean
+  items?: DeckListItem[]
+  path?: string
+  caption?: string
+  options?: string
+  rows?: string[][]
+  style?: string
+  children?: DeckBlock[]
+  columns?: DeckColumn[]
+}
+
+export interface DeckListItem {
+  text: string
+  label?: string
+  children?: DeckBlock[]
+}
+
+export interface DeckColumn {
+  width?: string
+  blocks?: DeckBlock[]
+}
+
+export interface DeckFrame {
+  subsection?: string
+  options?: string
+  title?: string
+  subtitle?: string
+  title_page?: boolean
+  blocks?: DeckBlock[]
+  notes?: string[]
+  unsupported?: string[]
+  source: string
+}
+
+export interface DeckSection {
+  title?: string
+  frames: DeckFrame[]
+}
+
+export interface Deck {
+  title?: string
+  subtitle?: string
+  author?: string
+  institute?: string
+  date?: string
+  aspect_ratio?: string
+  preamble?: string
+  sections: DeckSection[]
+}
+
 export interface LoginRequest {
   username: string
   password: string
@@ -53,10 +163,30 @@ export interface GeneratePPTRequest {
   title: string
   prompt: string
   template?: string
+  mode?: 'full' | 'template'
+  subtitle?: string
+  author?: string
+  institute?: string
+  engine?: string
   document_ids?: number[]
+  provider?: string
+  model?: string
+  auto_fix?: boolean
+  max_fix_attempts?: number
   use_openai?: boolean
 }
 
+export interface StreamEvent {
+  type: 'started' | 'delta' | 'compiling' | 'repairing' | 'completed' | 'failed'
+  ppt_id?: number
+  content?: string
+  pass?: number
+  total_passes?: number
+  attempt?: number
+  pdf_path?: string
+  error?: string
+}
+
 export interface SearchResult {
   ChunkID: number
   DocumentID: number